@@ -0,0 +1,54 @@
+package algebra
+
+import "math/big"
+
+// Accumulator is a running sum of field elements with constant-time
+// conditional additions (e.g., of the keys selected by the DPF bits of an
+// audit). With the Montgomery backend, the sum is kept in fixed-width limbs
+// between additions rather than converted to and from a big.Int each time;
+// only the addends are converted (in time that depends on their size,
+// which is fine for public keys), so the sum and the bits do not leak.
+type Accumulator struct {
+	f      *Field
+	sum    *FieldElement // the sum (big.Int backend)
+	limbs  []uint64      // the sum (Montgomery backend)
+	b, tmp []uint64      // scratch space for the addend and the new sum
+}
+
+// NewAccumulator returns an accumulator with a sum of zero
+func (f *Field) NewAccumulator() *Accumulator {
+	acc := &Accumulator{f: f}
+	if f.mont == nil {
+		acc.sum = f.AddIdentity()
+		return acc
+	}
+
+	acc.limbs = make([]uint64, f.mont.n)
+	acc.b = make([]uint64, f.mont.n)
+	acc.tmp = make([]uint64, f.mont.n)
+
+	return acc
+}
+
+// CondAdd adds b to the sum if bit is 1 and leaves the sum unchanged if
+// bit is 0, in the same way as CondAddInplace
+func (acc *Accumulator) CondAdd(b *FieldElement, bit byte) {
+	if acc.limbs == nil {
+		acc.f.CondAddInplace(acc.sum, b, bit)
+		return
+	}
+
+	m := acc.f.mont
+	m.setLimbs(acc.b, acc.f.reduce(b.Int))
+	m.add(acc.tmp, acc.limbs, acc.b)
+	ctSelect(acc.limbs, acc.tmp, uint64(bit&1))
+}
+
+// Sum returns (a copy of) the sum
+func (acc *Accumulator) Sum() *FieldElement {
+	if acc.limbs == nil {
+		return &FieldElement{new(big.Int).Set(acc.sum.Int)}
+	}
+
+	return &FieldElement{acc.f.mont.setBig(new(big.Int), acc.limbs)}
+}
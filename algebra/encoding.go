@@ -0,0 +1,91 @@
+package algebra
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// Elements are encoded canonically as fixed-width big-endian integers of
+// the byte length of the modulus: an element has exactly one encoding,
+// and decoding rejects encodings of the wrong length or out of range.
+
+// ByteLen returns the number of bytes of the encoding of an element
+func (f *Field) ByteLen() int {
+	return (f.P.BitLen() + 7) / 8
+}
+
+// Encode returns the fixed-width encoding of the element
+// (or an error if it is not reduced mod P)
+func (f *Field) Encode(e *FieldElement) ([]byte, error) {
+	return f.AppendEncoding(make([]byte, 0, f.ByteLen()), e)
+}
+
+// AppendEncoding appends the fixed-width encoding of the element to b
+// (or returns an error if it is not reduced mod P)
+func (f *Field) AppendEncoding(b []byte, e *FieldElement) ([]byte, error) {
+	if err := f.Validate(e); err != nil {
+		return nil, fmt.Errorf("cannot encode element: %v", err)
+	}
+
+	n := len(b)
+	b = append(b, make([]byte, f.ByteLen())...)
+	e.Int.FillBytes(b[n:])
+	return b, nil
+}
+
+// Decode returns the element of the fixed-width encoding b
+func (f *Field) Decode(b []byte) (*FieldElement, error) {
+	e := &FieldElement{new(big.Int)}
+	if err := f.DecodeInto(e, b); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// DecodeInto is the same as Decode but sets e to the element
+// (e.g., to decode many elements without allocating)
+func (f *Field) DecodeInto(e *FieldElement, b []byte) error {
+
+	if len(b) != f.ByteLen() {
+		return fmt.Errorf("encoding has %v bytes instead of %v", len(b), f.ByteLen())
+	}
+
+	e.Int.SetBytes(b)
+	if e.Int.Cmp(f.P) >= 0 {
+		return errors.New("encoded element is not reduced mod P")
+	}
+
+	return nil
+}
+
+// ByteLen returns the number of bytes of the encoding of a group element
+func (g *Group) ByteLen() int {
+	return g.Field.ByteLen()
+}
+
+// Encode returns the fixed-width encoding of the group element
+func (g *Group) Encode(e *GroupElement) ([]byte, error) {
+	if e == nil {
+		return nil, errors.New("cannot encode element: missing group element")
+	}
+
+	return g.Field.Encode(e.Value)
+}
+
+// Decode returns the group element of the fixed-width encoding b
+// (zero is not an element of the group and is rejected)
+func (g *Group) Decode(b []byte) (*GroupElement, error) {
+
+	value, err := g.Field.Decode(b)
+	if err != nil {
+		return nil, err
+	}
+
+	if value.Int.Sign() == 0 {
+		return nil, errors.New("zero is not an element of the group")
+	}
+
+	return &GroupElement{value}, nil
+}
@@ -2,70 +2,162 @@ package algebra
 
 import (
 	"crypto/rand"
+	"crypto/subtle"
+	"errors"
 	"math/big"
 )
 
 type Field struct {
-	P *big.Int // field modulus
+	P    *big.Int    // field modulus
+	mont *montgomery // set when using the Montgomery backend
 }
 
 type FieldElement struct {
 	Int *big.Int
 }
 
+// Backend is the implementation of the field arithmetic
+type Backend int
+
+const (
+	// BigIntBackend uses math/big (which is not constant-time)
+	BigIntBackend Backend = iota
+
+	// MontgomeryBackend uses fixed-width limbs and Montgomery multiplication
+	// (the modulus must be odd). Elements are still big.Ints, converted to
+	// limbs for every operation and back, so only the limb arithmetic of
+	// Add, Sub, Mul, Exp and the conditional moves is constant-time: the
+	// conversions take time that depends on the number of nonzero words of
+	// the values, and reduce branches on whether a value is in [0, P).
+	// MulInv blinds its input (see montInv). The exponent field of a
+	// safe-prime group has an even order (P-1) and uses math/big instead.
+	MontgomeryBackend
+)
+
 // new field of order p
 func NewField(p *big.Int) *Field {
 	return &Field{P: p}
 }
 
+// NewFieldWithBackend returns a field of order p using the given backend
+func NewFieldWithBackend(p *big.Int, backend Backend) (*Field, error) {
+
+	switch backend {
+	case BigIntBackend:
+		return NewField(p), nil
+	case MontgomeryBackend:
+		mont, err := newMontgomery(p)
+		if err != nil {
+			return nil, err
+		}
+		return &Field{P: p, mont: mont}, nil
+	}
+
+	return nil, errors.New("unknown field backend")
+}
+
+// Backend returns the backend of the field arithmetic
+func (f *Field) Backend() Backend {
+	if f.mont != nil {
+		return MontgomeryBackend
+	}
+
+	return BigIntBackend
+}
+
 // add modulo P
 func (f *Field) Add(a, b *FieldElement) *FieldElement {
+	if f.mont != nil {
+		return f.montOp(f.mont.add, a, b)
+	}
+
 	newValue := new(big.Int).Mod(new(big.Int).Add(a.Int, b.Int), f.P)
 	return f.NewElement(newValue)
 }
 
 func (f *Field) AddInplace(a *FieldElement, b *FieldElement) {
+	if f.mont != nil {
+		a.Int.Set(f.montOp(f.mont.add, a, b).Int)
+		return
+	}
+
 	a.Int.Add(a.Int, b.Int).Mod(a.Int, f.P)
 }
 
 // sub modulo P
 func (f *Field) Sub(a, b *FieldElement) *FieldElement {
+	if f.mont != nil {
+		return f.montOp(f.mont.sub, a, b)
+	}
+
 	newValue := new(big.Int).Mod(new(big.Int).Sub(a.Int, b.Int), f.P)
 	return f.NewElement(newValue)
 }
 
 func (f *Field) SubInplace(a *FieldElement, b *FieldElement) {
+	if f.mont != nil {
+		a.Int.Set(f.montOp(f.mont.sub, a, b).Int)
+		return
+	}
+
 	a.Int.Sub(a.Int, b.Int).Mod(a.Int, f.P)
 }
 
 func (f *Field) Negate(a *FieldElement) *FieldElement {
+	if f.mont != nil {
+		return f.montOp(f.mont.sub, f.AddIdentity(), a)
+	}
+
 	newValue := new(big.Int).Mod(new(big.Int).Sub(f.P, a.Int), f.P)
 	return f.NewElement(newValue)
 }
 
 // return multiplicative inverse with mod P
+// (blinded by a random factor with the Montgomery backend, see montInv)
 func (f *Field) MulInv(a *FieldElement) *FieldElement {
+	if f.mont != nil {
+		return f.montInv(a)
+	}
+
 	newValue := new(big.Int).ModInverse(a.Int, f.P)
 	return f.NewElement(newValue)
 }
 
 // multiply mod P
 func (f *Field) Mul(a, b *FieldElement) *FieldElement {
+	if f.mont != nil {
+		return f.montOp(f.mont.mulMod, a, b)
+	}
+
 	newValue := new(big.Int).Mul(a.Int, b.Int)
 	return f.NewElement(newValue)
 }
 
 func (f *Field) MulInplace(a *FieldElement, b *FieldElement) {
+	if f.mont != nil {
+		a.Int.Set(f.montOp(f.mont.mulMod, a, b).Int)
+		return
+	}
+
 	a.Int.Mul(a.Int, b.Int).Mod(a.Int, f.P)
 }
 
 // exponentiation mod P
 func (f *Field) Exp(a *FieldElement, c *big.Int) *FieldElement {
+	if f.mont != nil {
+		return f.montExp(a, c)
+	}
+
 	newValue := exp(a.Int, c, f.P)
 	return f.NewElement(newValue)
 }
 
 func (f *Field) ExpInplace(a *FieldElement, c *big.Int) {
+	if f.mont != nil {
+		a.Int.Set(f.montExp(a, c).Int)
+		return
+	}
+
 	expInplace(a.Int, c, f.P)
 }
 
@@ -105,6 +197,110 @@ func (elem *FieldElement) Cmp(b *FieldElement) int {
 	return elem.Int.Cmp(b.Int)
 }
 
+// CondAddInplace adds b to a (mod P) if bit is 1 and leaves a unchanged if
+// bit is 0; the sum is computed either way and a is then set with a
+// constant-time conditional move (only the least significant bit is used),
+// so that e.g. the time an audit takes does not depend on the DPF bits
+func (f *Field) CondAddInplace(a *FieldElement, b *FieldElement, bit byte) {
+	if f.mont != nil {
+		f.selectInplace(a, f.montOp(f.mont.add, a, b).Int, bit)
+		return
+	}
+
+	sum := new(big.Int).Add(a.Int, b.Int)
+	sum.Mod(sum, f.P)
+	f.selectInplace(a, sum, bit)
+}
+
+// sets a to b if bit is 1 by copying both through buffers of the byte
+// length of P; a is reduced mod P either way (as b is by the callers)
+func (f *Field) selectInplace(a *FieldElement, b *big.Int, bit byte) {
+	n := (f.P.BitLen() + 7) / 8
+	bufA := f.reduce(a.Int).FillBytes(make([]byte, n))
+	bufB := f.reduce(b).FillBytes(make([]byte, n))
+	subtle.ConstantTimeCopy(int(bit&1), bufA, bufB)
+	a.Int.SetBytes(bufA)
+}
+
+// Equal returns true if a == b (mod P) comparing the fixed-width
+// encodings of the elements in constant time (elements that are not
+// reduced mod P, e.g., received from a client, are reduced first)
+func (f *Field) Equal(a, b *FieldElement) bool {
+	n := (f.P.BitLen() + 7) / 8
+	bufA := f.reduce(a.Int).FillBytes(make([]byte, n))
+	bufB := f.reduce(b.Int).FillBytes(make([]byte, n))
+	return subtle.ConstantTimeCompare(bufA, bufB) == 1
+}
+
+// Size returns the number of bytes of the (minimal, big-endian)
+// encoding of the element; a nil element has size zero
+func (elem *FieldElement) Size() int {
+	if elem == nil || elem.Int == nil {
+		return 0
+	}
+
+	return (elem.Int.BitLen() + 7) / 8
+}
+
+// applies a binary operation of the Montgomery backend to a and b; the
+// operation is constant-time but the conversions from and to big.Int are
+// not (see MontgomeryBackend)
+func (f *Field) montOp(op func(z, x, y []uint64), a, b *FieldElement) *FieldElement {
+	z := make([]uint64, f.mont.n)
+	op(z, f.mont.limbs(f.reduce(a.Int)), f.mont.limbs(f.reduce(b.Int)))
+	return &FieldElement{f.mont.setBig(new(big.Int), z)}
+}
+
+// a^-1 mod P with the Montgomery backend: a is multiplied by a random
+// nonzero r in constant time, so that the (faster but variable-time)
+// inversion only sees a uniformly random value, and the inverse is then
+// multiplied by r; as with a^(P-2), the inverse of zero is zero
+func (f *Field) montInv(a *FieldElement) *FieldElement {
+	r := randomInt(f.P)
+	for r.Sign() == 0 {
+		r = randomInt(f.P)
+	}
+
+	blinded := f.montOp(f.mont.mulMod, a, &FieldElement{r})
+	inv := new(big.Int).ModInverse(blinded.Int, f.P)
+	if inv == nil {
+		return f.AddIdentity()
+	}
+
+	return f.montOp(f.mont.mulMod, &FieldElement{inv}, &FieldElement{r})
+}
+
+// a^c mod P with the Montgomery backend; the exponent is processed over
+// as many limbs as P has (or more for larger exponents)
+func (f *Field) montExp(a *FieldElement, c *big.Int) *FieldElement {
+
+	if c.Sign() < 0 {
+		// a^-c = (a^-1)^c
+		return f.montExp(f.MulInv(a), new(big.Int).Neg(c))
+	}
+
+	n := f.mont.n
+	if words := (c.BitLen() + 63) / 64; words > n {
+		n = words
+	}
+
+	e := (&montgomery{n: n}).limbs(c)
+	z := make([]uint64, f.mont.n)
+	f.mont.exp(z, f.mont.limbs(f.reduce(a.Int)), e)
+	return &FieldElement{f.mont.setBig(new(big.Int), z)}
+}
+
+// returns a if it is already in [0, P) and a mod P otherwise (elements
+// of the field are always reduced); this is not constant-time, but for
+// reduced elements the branch only reveals that they are
+func (f *Field) reduce(a *big.Int) *big.Int {
+	if a.Sign() < 0 || a.Cmp(f.P) >= 0 {
+		return new(big.Int).Mod(a, f.P)
+	}
+
+	return a
+}
+
 func randomInt(max *big.Int) *big.Int {
 	randomBig, _ := rand.Int(rand.Reader, new(big.Int).SetBytes(max.Bytes()))
 	return new(big.Int).SetBytes(randomBig.Bytes())
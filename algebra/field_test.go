@@ -1,6 +1,7 @@
 package algebra
 
 import (
+	"fmt"
 	"math/big"
 	"math/rand"
 	"testing"
@@ -46,6 +47,52 @@ func TestAddField(t *testing.T) {
 	}
 }
 
+func TestCondAddField(t *testing.T) {
+
+	n := 100
+	p := big.NewInt(1009)
+	field, elements := setupField(p, n)
+
+	sum := field.AddIdentity()
+	condSum := field.AddIdentity()
+	for i := 0; i < n; i++ {
+		bit := byte(rand.Intn(2))
+		if bit == 1 {
+			sum = field.Add(sum, elements[i])
+		}
+		field.CondAddInplace(condSum, elements[i], bit)
+	}
+
+	if !field.Equal(sum, condSum) {
+		t.Fatalf("Conditional sum over field is not correct. expected: %v, got: %v", sum.Int, condSum.Int)
+	}
+
+	if field.Equal(field.AddIdentity(), field.MulIdentity()) {
+		t.Fatalf("Equal is wrong")
+	}
+
+	// elements that are not reduced (wider than P or negative)
+	wide := &FieldElement{new(big.Int).Add(elements[0].Int, new(big.Int).Lsh(p, 8))}
+	negative := &FieldElement{new(big.Int).Sub(elements[0].Int, p)}
+	if !field.Equal(wide, elements[0]) || !field.Equal(negative, elements[0]) {
+		t.Fatalf("Equal is wrong on elements that are not reduced")
+	}
+
+	for _, bit := range []byte{0, 1} {
+		a := &FieldElement{new(big.Int).Set(wide.Int)}
+		field.CondAddInplace(a, negative, bit)
+
+		expected := elements[0]
+		if bit == 1 {
+			expected = field.Add(elements[0], elements[0])
+		}
+
+		if a.Int.Cmp(expected.Int) != 0 {
+			t.Fatalf("Conditional sum of elements that are not reduced is not correct. expected: %v, got: %v", expected.Int, a.Int)
+		}
+	}
+}
+
 func TestSubField(t *testing.T) {
 
 	n := 100
@@ -127,3 +174,364 @@ func TestMulInverseField(t *testing.T) {
 		t.Fatalf("x * (x^-1). Expected: 1, got: %v", res.Int)
 	}
 }
+
+func TestMontgomeryBackend(t *testing.T) {
+
+	// odd moduli of one limb, a partial limb and several limbs
+	// (the last one is not prime so inverses are skipped)
+	moduli := []*big.Int{big.NewInt(1009), mersennePrime(61), mersennePrime(127), mersennePrime(521)}
+	composite, _ := new(big.Int).SetString("c7f1b9c3b2f2a5d6e4f1a3b5c7d9e1f3a5b7c9d1e3f5a7b9c1d3e5f7a9b1c3d5e7", 16)
+	moduli = append(moduli, composite)
+
+	if _, err := NewFieldWithBackend(big.NewInt(1008), MontgomeryBackend); err == nil {
+		t.Fatalf("Montgomery backend accepted an even modulus")
+	}
+
+	for _, p := range moduli {
+		field := NewField(p)
+		ctField, err := NewFieldWithBackend(p, MontgomeryBackend)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if ctField.Backend() != MontgomeryBackend || field.Backend() != BigIntBackend {
+			t.Fatalf("Wrong field backend")
+		}
+
+		edge := []*FieldElement{field.AddIdentity(), field.MulIdentity(), field.NewElement(new(big.Int).Sub(p, big.NewInt(1)))}
+		for i := 0; i < 100; i++ {
+			a, b := field.RandomElement(), field.RandomElement()
+			if i < len(edge) {
+				a = edge[i]
+			}
+
+			check := func(op string, expected, got *FieldElement) {
+				if expected.Cmp(got) != 0 {
+					t.Fatalf("%v over %v-bit field is not correct. expected: %v, got: %v", op, p.BitLen(), expected.Int, got.Int)
+				}
+			}
+
+			check("Add", field.Add(a, b), ctField.Add(a, b))
+			check("Sub", field.Sub(a, b), ctField.Sub(a, b))
+			check("Negate", field.Negate(a), ctField.Negate(a))
+			check("Mul", field.Mul(a, b), ctField.Mul(a, b))
+
+			// exponents smaller and larger than P and negative
+			for _, e := range []*big.Int{big.NewInt(0), big.NewInt(rand.Int63()), b.Int, new(big.Int).Mul(b.Int, p)} {
+				check("Exp", field.Exp(a, e), ctField.Exp(a, e))
+			}
+
+			if p != composite && !field.IsZero(a) {
+				check("MulInv", field.MulInv(a), ctField.MulInv(a))
+				check("Exp", field.Exp(a, big.NewInt(-7)), ctField.Exp(a, big.NewInt(-7)))
+			}
+
+			acc := a.Int
+			sum := &FieldElement{new(big.Int).Set(acc)}
+			ctField.AddInplace(sum, b)
+			check("AddInplace", field.Add(a, b), sum)
+
+			bit := byte(rand.Intn(2))
+			cond := &FieldElement{new(big.Int).Set(acc)}
+			ctField.CondAddInplace(cond, b, bit)
+			if bit == 1 {
+				check("CondAddInplace", field.Add(a, b), cond)
+			} else {
+				check("CondAddInplace", a, cond)
+			}
+		}
+	}
+}
+
+func TestAccumulator(t *testing.T) {
+
+	p := mersennePrime(521)
+	for _, backend := range BenchmarkBackends {
+		field, _ := NewFieldWithBackend(p, backend)
+
+		sum := field.AddIdentity()
+		acc := field.NewAccumulator()
+		for i := 0; i < 100; i++ {
+			a := field.RandomElement()
+			if i == 0 {
+				// not reduced mod P
+				a = &FieldElement{new(big.Int).Add(a.Int, p)}
+			}
+
+			bit := byte(rand.Intn(2))
+			if bit == 1 {
+				sum = field.Add(sum, a)
+			}
+			acc.CondAdd(a, bit)
+		}
+
+		if acc.Sum().Cmp(sum) != 0 {
+			t.Fatalf("Accumulated sum with the %v backend is not correct. expected: %v, got: %v", backendName(backend), sum.Int, acc.Sum().Int)
+		}
+	}
+}
+
+func TestEncoding(t *testing.T) {
+
+	// 2^127 - 1 takes 16 bytes
+	p := mersennePrime(127)
+	field := NewField(p)
+	group := NewGroup(field, field.NewElement(big.NewInt(3)))
+
+	if field.ByteLen() != 16 || group.ByteLen() != 16 {
+		t.Fatalf("Wrong encoding length %v", field.ByteLen())
+	}
+
+	// small elements keep their leading zeros
+	for _, e := range []*FieldElement{field.AddIdentity(), field.MulIdentity(), field.RandomElement(), field.NewElement(big.NewInt(-1))} {
+		b, err := field.Encode(e)
+		if err != nil || len(b) != field.ByteLen() {
+			t.Fatalf("Encoding of %v has %v bytes: %v", e.Int, len(b), err)
+		}
+
+		d, err := field.Decode(b)
+		if err != nil || d.Cmp(e) != 0 {
+			t.Fatalf("Decoding of %v failed: %v", e.Int, err)
+		}
+	}
+
+	// out of range or of the wrong length
+	invalid := [][]byte{
+		p.FillBytes(make([]byte, 16)),
+		new(big.Int).Add(p, big.NewInt(1)).FillBytes(make([]byte, 16)),
+		make([]byte, 15),
+		make([]byte, 17),
+	}
+	for _, b := range invalid {
+		if _, err := field.Decode(b); err == nil {
+			t.Fatalf("Decoded invalid encoding %x", b)
+		}
+	}
+
+	// elements that are not reduced are not encoded
+	for _, e := range []*FieldElement{{p}, {big.NewInt(-1)}, {nil}, nil} {
+		if _, err := field.Encode(e); err == nil {
+			t.Fatalf("Encoded an element that is not reduced mod P")
+		}
+	}
+
+	if _, err := group.Decode(make([]byte, 16)); err == nil {
+		t.Fatalf("Decoded zero as a group element")
+	}
+
+	elem := group.NewElement(big.NewInt(rand.Int63()))
+	b, err := group.Encode(elem)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := group.Decode(b)
+	if err != nil || d.Cmp(elem) != 0 {
+		t.Fatalf("Decoding of group element failed: %v", err)
+	}
+}
+
+// exponents of the Mersenne primes 2^k - 1 used as benchmark moduli
+// (from 128-bit up to roughly the 3072-bit group used by pacl-sposs)
+func TestPolynomial(t *testing.T) {
+
+	field := NewField(mersennePrime(127))
+
+	// p(x) = 3 + 2x + x^2
+	p := NewPolynomial(field, []*FieldElement{
+		field.NewElement(big.NewInt(3)), field.NewElement(big.NewInt(2)), field.MulIdentity()})
+	if p.Degree() != 2 || p.Eval(field.NewElement(big.NewInt(5))).Int.Int64() != 38 {
+		t.Fatalf("Wrong evaluation of the polynomial")
+	}
+
+	// (p + q)(x) = p(x) + q(x) and (p * q)(x) = p(x) q(x)
+	q := field.RandomPolynomial(4, field.RandomElement())
+	for i := 0; i < 10; i++ {
+		x := field.RandomElement()
+		if p.Add(q).Eval(x).Cmp(field.Add(p.Eval(x), q.Eval(x))) != 0 {
+			t.Fatalf("Wrong sum of polynomials")
+		}
+		if p.Mul(q).Eval(x).Cmp(field.Mul(p.Eval(x), q.Eval(x))) != 0 {
+			t.Fatalf("Wrong product of polynomials")
+		}
+	}
+
+	// interpolation recovers a random polynomial from degree+1 points
+	for _, backend := range BenchmarkBackends {
+		f, _ := NewFieldWithBackend(mersennePrime(127), backend)
+		poly := f.RandomPolynomial(6, f.RandomElement())
+		xs := make([]*FieldElement, 7)
+		ys := make([]*FieldElement, 7)
+		for i := range xs {
+			xs[i] = f.RandomElement()
+			ys[i] = poly.Eval(xs[i])
+		}
+
+		res, err := f.Interpolate(xs, ys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range poly.Coeffs {
+			if res.Coeffs[i].Cmp(poly.Coeffs[i]) != 0 {
+				t.Fatalf("Interpolation (%v) did not recover the polynomial", backendName(backend))
+			}
+		}
+
+		x := f.RandomElement()
+		y, err := f.InterpolateAt(xs, ys, x)
+		if err != nil || y.Cmp(poly.Eval(x)) != 0 {
+			t.Fatalf("Wrong interpolation at a point (%v)", backendName(backend))
+		}
+	}
+
+	// interpolation points must be distinct
+	xs := []*FieldElement{field.MulIdentity(), field.NewElement(big.NewInt(2)), field.MulIdentity()}
+	if _, err := field.Interpolate(xs, xs); err == nil {
+		t.Fatalf("Interpolated over repeated points")
+	}
+	if _, err := field.InterpolateAt(xs, xs, field.AddIdentity()); err == nil {
+		t.Fatalf("Interpolated over repeated points")
+	}
+}
+
+func TestBatchMulInv(t *testing.T) {
+
+	field := NewField(mersennePrime(127))
+	elems := make([]*FieldElement, 20)
+	for i := range elems {
+		elems[i] = field.NewElement(big.NewInt(rand.Int63n(1<<62) + 1))
+	}
+
+	invs, err := field.BatchMulInv(elems)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range elems {
+		if invs[i].Cmp(field.MulInv(elems[i])) != 0 {
+			t.Fatalf("Wrong inverse of %v", elems[i].Int)
+		}
+	}
+
+	elems[7] = field.AddIdentity()
+	if _, err := field.BatchMulInv(elems); err == nil {
+		t.Fatalf("Inverted zero")
+	}
+}
+
+func TestShamir(t *testing.T) {
+
+	field := NewField(mersennePrime(127))
+	secret := field.RandomElement()
+
+	for _, tn := range [][2]int{{1, 1}, {2, 3}, {3, 5}, {5, 5}} {
+		threshold, n := tn[0], tn[1]
+		shares, err := field.ShamirShares(secret, threshold, n)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// any t shares (the first t after shuffling)
+		rand.Shuffle(len(shares), func(i, j int) { shares[i], shares[j] = shares[j], shares[i] })
+		res, err := field.ShamirReconstruct(shares[:threshold])
+		if err != nil || res.Cmp(secret) != 0 {
+			t.Fatalf("%v-of-%v shares did not reconstruct the secret", threshold, n)
+		}
+
+		// all shares as well
+		res, err = field.ShamirReconstruct(shares)
+		if err != nil || res.Cmp(secret) != 0 {
+			t.Fatalf("%v-of-%v shares did not reconstruct the secret from all shares", threshold, n)
+		}
+
+		// fewer than t shares do not
+		if threshold > 1 {
+			res, err = field.ShamirReconstruct(shares[:threshold-1])
+			if err != nil || res.Cmp(secret) == 0 {
+				t.Fatalf("%v shares reconstructed a %v-of-%v secret", threshold-1, threshold, n)
+			}
+		}
+	}
+
+	if _, err := field.ShamirShares(secret, 4, 3); err == nil {
+		t.Fatalf("Shared with a threshold larger than the number of parties")
+	}
+
+	if _, err := NewField(big.NewInt(7)).ShamirShares(secret, 2, 7); err == nil {
+		t.Fatalf("Shared among more parties than the field has points")
+	}
+
+	shares, _ := field.ShamirShares(secret, 2, 3)
+	if _, err := field.ShamirReconstruct([]*ShamirShare{shares[0], shares[0]}); err == nil {
+		t.Fatalf("Reconstructed from the same share twice")
+	}
+}
+
+var BenchmarkMersenneExps = []uint{127, 521, 2203, 3217}
+
+var BenchmarkBackends = []Backend{BigIntBackend, MontgomeryBackend}
+
+func backendName(backend Backend) string {
+	if backend == MontgomeryBackend {
+		return "montgomery"
+	}
+	return "bigint"
+}
+
+func mersennePrime(k uint) *big.Int {
+	p := new(big.Int).Lsh(big.NewInt(1), k)
+	return p.Sub(p, big.NewInt(1))
+}
+
+func BenchmarkFieldMul(b *testing.B) {
+	for _, k := range BenchmarkMersenneExps {
+		for _, backend := range BenchmarkBackends {
+			b.Run(fmt.Sprintf("bits=%v/%v", k, backendName(backend)), func(b *testing.B) {
+				field, _ := NewFieldWithBackend(mersennePrime(k), backend)
+				x := field.RandomElement()
+				y := field.RandomElement()
+
+				b.ReportAllocs()
+				b.ResetTimer()
+
+				for i := 0; i < b.N; i++ {
+					field.Mul(x, y)
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkFieldExp(b *testing.B) {
+	for _, k := range BenchmarkMersenneExps {
+		for _, backend := range BenchmarkBackends {
+			b.Run(fmt.Sprintf("bits=%v/%v", k, backendName(backend)), func(b *testing.B) {
+				field, _ := NewFieldWithBackend(mersennePrime(k), backend)
+				x := field.RandomElement()
+				e := field.RandomElement().Int
+
+				b.ReportAllocs()
+				b.ResetTimer()
+
+				for i := 0; i < b.N; i++ {
+					field.Exp(x, e)
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkBatchMulInv(b *testing.B) {
+	field := NewField(mersennePrime(521))
+	elems := make([]*FieldElement, 256)
+	for i := range elems {
+		elems[i] = field.NewElement(big.NewInt(rand.Int63n(1<<62) + 1))
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		field.BatchMulInv(elems)
+	}
+}
@@ -2,11 +2,22 @@ package algebra
 
 import (
 	"math/big"
+	"sync"
 )
 
 type Group struct {
 	Field *Field
 	G     *FieldElement
+	Order *big.Int // order of G (P-1 when G generates the multiplicative group)
+
+	base *groupBase // precomputed powers of G (Montgomery backend only)
+}
+
+// powers of the generator, computed the first time they are needed
+type groupBase struct {
+	once  sync.Once
+	g     *big.Int // the generator the table was computed for
+	table fixedBase
 }
 
 type GroupElement struct {
@@ -14,8 +25,15 @@ type GroupElement struct {
 }
 
 // new group over a specified field with generator g
+// (of the multiplicative group of the field)
 func NewGroup(f *Field, g *FieldElement) *Group {
-	return &Group{f, g}
+	return &Group{Field: f, G: g, Order: f.Pminus1(), base: &groupBase{}}
+}
+
+// new group over a specified field with a generator g of prime order
+// (e.g., the subgroup of a Schnorr group, see GenerateSchnorrGroup)
+func NewSubgroup(f *Field, g *FieldElement, order *big.Int) *Group {
+	return &Group{Field: f, G: g, Order: new(big.Int).Set(order), base: &groupBase{}}
 }
 
 // multiply two group elements
@@ -36,16 +54,44 @@ func (g *Group) Exp(a *GroupElement, b *FieldElement) *GroupElement {
 	return &GroupElement{newElement}
 }
 
-// new element g**alpha mod P = 2q+1
+// new element g**alpha mod P
 func (g *Group) NewElement(a *big.Int) *GroupElement {
+	if newElement := g.fixedExp(a); newElement != nil {
+		return &GroupElement{newElement}
+	}
+
 	newElement := g.Field.Exp(g.G, a)
 	return &GroupElement{newElement}
 }
 
+// g**alpha using the precomputed powers of G with the Montgomery backend;
+// returns nil when there is no table (or alpha does not fit in it)
+func (g *Group) fixedExp(a *big.Int) *FieldElement {
+
+	m := g.Field.mont
+	if m == nil || g.base == nil || a.Sign() < 0 || a.BitLen() > g.Order.BitLen() {
+		return nil
+	}
+
+	g.base.once.Do(func() {
+		g.base.g = new(big.Int).Set(g.G.Int)
+		g.base.table = m.newFixedBase(m.limbs(g.Field.reduce(g.G.Int)), g.Order.BitLen())
+	})
+
+	if g.base.g.Cmp(g.G.Int) != 0 {
+		return nil // G was changed after the table was computed
+	}
+
+	e := (&montgomery{n: (4*len(g.base.table) + 63) / 64}).limbs(a)
+	z := make([]uint64, m.n)
+	m.expFixed(z, g.base.table, e)
+	return &FieldElement{m.setBig(new(big.Int), z)}
+}
+
 // new random element in the group (also returns discrete log)
 func (g *Group) RandomElement() (*GroupElement, *big.Int) {
 	// should make it not repeat this calculation
-	a := randomInt(g.Field.P)
+	a := randomInt(g.Order)
 
 	return g.NewElement(a), a
 }
@@ -58,8 +104,17 @@ func (elem *GroupElement) Cmp(b *GroupElement) int {
 	return elem.Value.Cmp(b.Value)
 }
 
+// Size returns the number of bytes of the encoding of the element
+func (elem *GroupElement) Size() int {
+	if elem == nil {
+		return 0
+	}
+
+	return elem.Value.Size()
+}
+
 func (elem *GroupElement) Copy() *GroupElement {
-	return &GroupElement{&FieldElement{big.NewInt(0).SetBytes(elem.Value.Int.Bytes())}}
+	return &GroupElement{&FieldElement{new(big.Int).Set(elem.Value.Int)}}
 }
 
 func (f *Field) Pminus1() *big.Int {
@@ -1,6 +1,8 @@
 package algebra
 
 import (
+	crand "crypto/rand"
+	"fmt"
 	"math/big"
 	"math/rand"
 	"testing"
@@ -70,6 +72,77 @@ func TestInverseGroup(t *testing.T) {
 	}
 }
 
+func TestValidateGroup(t *testing.T) {
+
+	p := big.NewInt(1523) // 1523 = 2 * 761 + 1 is a safe prime
+	field := NewField(p)
+	group := NewGroup(field, findRandomGenerator(field))
+
+	valid := []int64{2, 3, 761, 1521}
+	for _, v := range valid {
+		if err := group.Validate(&GroupElement{field.NewElement(big.NewInt(v))}); err != nil {
+			t.Fatalf("Validate rejected %v: %v", v, err)
+		}
+	}
+
+	// small order (1 and P-1), zero and out of range
+	invalid := []int64{1, 1522, 0, 1523, 1600, -3}
+	for _, v := range invalid {
+		if err := group.Validate(&GroupElement{&FieldElement{big.NewInt(v)}}); err == nil {
+			t.Fatalf("Validate accepted %v", v)
+		}
+	}
+
+	if group.Validate(nil) == nil || field.Validate(nil) == nil {
+		t.Fatalf("Validate accepted a missing element")
+	}
+
+	// the subgroup of quadratic residues (of order 761) does not contain
+	// the generator of the whole group
+	qr := &Group{Field: field, G: field.Mul(group.G, group.G), Order: big.NewInt(761)}
+	if !qr.Contains(&GroupElement{qr.G}) || qr.Contains(&GroupElement{group.G}) {
+		t.Fatalf("Wrong subgroup membership")
+	}
+}
+
+func TestSchnorrGroup(t *testing.T) {
+
+	group, err := GenerateSchnorrGroup(crand.Reader, 512, 160)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, q := group.Field.P, group.Order
+	if p.BitLen() != 512 || q.BitLen() != 160 || !p.ProbablyPrime(20) || !q.ProbablyPrime(20) {
+		t.Fatalf("Wrong Schnorr group parameters")
+	}
+
+	if new(big.Int).Mod(group.Field.Pminus1(), q).Sign() != 0 {
+		t.Fatalf("q does not divide P-1")
+	}
+
+	// g has order q and random elements are in the subgroup
+	if group.Field.IsMulIdentity(group.G) || !group.Field.IsMulIdentity(group.Field.Exp(group.G, q)) {
+		t.Fatalf("Generator does not have order q")
+	}
+
+	for i := 0; i < 10; i++ {
+		e, a := group.RandomElement()
+		if a.Cmp(q) >= 0 || group.Validate(e) != nil {
+			t.Fatalf("Random element is not in the subgroup")
+		}
+
+		// -e is not in a group of odd order
+		if group.Contains(&GroupElement{group.Field.Negate(e.Value)}) {
+			t.Fatalf("Subgroup contains the negation of an element")
+		}
+	}
+
+	if _, err := GenerateSchnorrGroup(crand.Reader, 160, 160); err == nil {
+		t.Fatalf("Generated a subgroup as large as the group")
+	}
+}
+
 // Get all prime factors of a given number n
 // taken from https://siongui.github.io/2017/05/09/go-find-all-prime-factors-of-integer-number/
 func PrimeFactors(n *big.Int) []*big.Int {
@@ -140,3 +213,54 @@ func findRandomGenerator(field *Field) *FieldElement {
 
 	return field.NewElement(g)
 }
+
+func BenchmarkGroupNewElement(b *testing.B) {
+	for _, k := range BenchmarkMersenneExps {
+		b.Run(fmt.Sprintf("bits=%v", k), func(b *testing.B) {
+			// 3 need not generate the whole group for timing purposes
+			field := NewField(mersennePrime(k))
+			group := NewGroup(field, field.NewElement(big.NewInt(3)))
+			x := field.RandomElement().Int
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				group.NewElement(x)
+			}
+		})
+	}
+}
+
+func TestFixedBaseGroup(t *testing.T) {
+
+	p := mersennePrime(521)
+	field := NewField(p)
+	ctField, err := NewFieldWithBackend(p, MontgomeryBackend)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g := field.NewElement(big.NewInt(3))
+	group := NewGroup(ctField, g)
+
+	// exponents in the table and (negative or too large) exponents
+	// that are not
+	order := group.Order
+	exponents := []*big.Int{big.NewInt(0), big.NewInt(1), new(big.Int).Sub(order, big.NewInt(1)), big.NewInt(-5), new(big.Int).Lsh(order, 3)}
+	for i := 0; i < 20; i++ {
+		exponents = append(exponents, randomInt(order))
+	}
+
+	for _, e := range exponents {
+		if group.NewElement(e).Value.Cmp(field.Exp(g, e)) != 0 {
+			t.Fatalf("Fixed-base exponentiation is not correct for %v", e)
+		}
+	}
+
+	// changing the generator does not use the stale table
+	group.G = field.NewElement(big.NewInt(5))
+	if group.NewElement(big.NewInt(1000)).Value.Cmp(field.Exp(group.G, big.NewInt(1000))) != 0 {
+		t.Fatalf("Fixed-base exponentiation used the table of another generator")
+	}
+}
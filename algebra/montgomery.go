@@ -0,0 +1,273 @@
+package algebra
+
+import (
+	"errors"
+	"math/big"
+	"math/bits"
+)
+
+// montgomery implements arithmetic modulo an odd P over fixed-width
+// 64-bit limbs (least significant first). Every operation runs over all
+// the limbs and selects results with masks rather than branches, so that
+// the time taken only depends on the size of P (and not on the values);
+// the conversions from and to big.Int (setLimbs and setBig) do not.
+type montgomery struct {
+	n    int      // number of limbs
+	p    []uint64 // modulus
+	pInv uint64   // -P^-1 mod 2^64
+	rr   []uint64 // R^2 mod P for R = 2^(64n)
+	one  []uint64 // R mod P (1 in the Montgomery domain)
+}
+
+func newMontgomery(p *big.Int) (*montgomery, error) {
+
+	if p.Sign() <= 0 || p.Bit(0) == 0 {
+		return nil, errors.New("Montgomery arithmetic requires an odd modulus")
+	}
+
+	m := &montgomery{}
+	m.n = (p.BitLen() + 63) / 64
+	m.p = m.limbs(p)
+
+	// Newton iteration for P^-1 mod 2^64 (each step doubles the correct bits)
+	inv := uint64(1)
+	for i := 0; i < 6; i++ {
+		inv *= 2 - m.p[0]*inv
+	}
+	m.pInv = -inv
+
+	r := new(big.Int).Lsh(big.NewInt(1), uint(64*m.n))
+	m.one = m.limbs(new(big.Int).Mod(r, p))
+	m.rr = m.limbs(new(big.Int).Mod(new(big.Int).Mul(r, r), p))
+
+	return m, nil
+}
+
+// fixed-width limbs of x (which must be in [0, P))
+func (m *montgomery) limbs(x *big.Int) []uint64 {
+	z := make([]uint64, m.n)
+	m.setLimbs(z, x)
+	return z
+}
+
+// sets the limbs z to x (which must be in [0, P)) from the words of x,
+// without going through a byte encoding; big.Int trims leading zero words,
+// so the time taken depends on the size of x
+func (m *montgomery) setLimbs(z []uint64, x *big.Int) {
+	for i := range z {
+		z[i] = 0
+	}
+
+	for i, w := range x.Bits() {
+		if bits.UintSize == 64 {
+			z[i] = uint64(w)
+		} else {
+			z[i/2] |= uint64(w) << (32 * uint(i%2))
+		}
+	}
+}
+
+// sets z to the value of the limbs x
+func (m *montgomery) setBig(z *big.Int, x []uint64) *big.Int {
+	words := make([]big.Word, m.n*64/bits.UintSize)
+	for i := range words {
+		if bits.UintSize == 64 {
+			words[i] = big.Word(x[i])
+		} else {
+			words[i] = big.Word(x[i/2] >> (32 * uint(i%2)))
+		}
+	}
+
+	return z.SetBits(words)
+}
+
+// sets z to x if bit is 1 (and leaves z unchanged otherwise)
+func ctSelect(z, x []uint64, bit uint64) {
+	mask := -bit
+	for i := range z {
+		z[i] ^= (z[i] ^ x[i]) & mask
+	}
+}
+
+// z = x + y mod P
+func (m *montgomery) add(z, x, y []uint64) {
+	var carry, borrow uint64
+	sum := make([]uint64, m.n)
+	for i := range sum {
+		sum[i], carry = bits.Add64(x[i], y[i], carry)
+	}
+
+	// subtract P unless the sum is already reduced
+	for i := range z {
+		z[i], borrow = bits.Sub64(sum[i], m.p[i], borrow)
+	}
+	_, borrow = bits.Sub64(carry, 0, borrow)
+	ctSelect(z, sum, borrow)
+}
+
+// z = x - y mod P
+func (m *montgomery) sub(z, x, y []uint64) {
+	var borrow, carry uint64
+	for i := range z {
+		z[i], borrow = bits.Sub64(x[i], y[i], borrow)
+	}
+
+	// add P back if the difference is negative
+	mask := -borrow
+	for i := range z {
+		z[i], carry = bits.Add64(z[i], m.p[i]&mask, carry)
+	}
+}
+
+// z = x * y * R^-1 mod P
+func (m *montgomery) mul(z, x, y []uint64) {
+	m.mulScratch(z, x, y, make([]uint64, m.n+2))
+}
+
+// same as mul using t (of n+2 limbs) as scratch space
+// (coarsely integrated operand scanning)
+func (m *montgomery) mulScratch(z, x, y, t []uint64) {
+	n := m.n
+	for i := range t {
+		t[i] = 0
+	}
+
+	for i := 0; i < n; i++ {
+		// t += x * y[i]
+		var c, cc uint64
+		for j := 0; j < n; j++ {
+			hi, lo := bits.Mul64(x[j], y[i])
+			lo, cc = bits.Add64(lo, t[j], 0)
+			hi += cc
+			lo, cc = bits.Add64(lo, c, 0)
+			hi += cc
+			t[j], c = lo, hi
+		}
+		t[n], cc = bits.Add64(t[n], c, 0)
+		t[n+1] = cc
+
+		// t = (t + u * P) / 2^64 where u makes the lowest limb zero
+		u := t[0] * m.pInv
+		hi, lo := bits.Mul64(u, m.p[0])
+		_, cc = bits.Add64(lo, t[0], 0)
+		c = hi + cc
+		for j := 1; j < n; j++ {
+			hi, lo = bits.Mul64(u, m.p[j])
+			lo, cc = bits.Add64(lo, t[j], 0)
+			hi += cc
+			lo, cc = bits.Add64(lo, c, 0)
+			hi += cc
+			t[j-1], c = lo, hi
+		}
+		t[n-1], cc = bits.Add64(t[n], c, 0)
+		t[n] = t[n+1] + cc
+	}
+
+	// t < 2P: subtract P unless t is already reduced
+	var borrow uint64
+	for i := 0; i < n; i++ {
+		z[i], borrow = bits.Sub64(t[i], m.p[i], borrow)
+	}
+	_, borrow = bits.Sub64(t[n], 0, borrow)
+	ctSelect(z, t[:n], borrow)
+}
+
+// z = x * y mod P (for x and y outside the Montgomery domain)
+func (m *montgomery) mulMod(z, x, y []uint64) {
+	m.mul(z, x, y)
+	m.mul(z, z, m.rr)
+}
+
+// z = x^e mod P with a fixed window of 4 bits; every window performs the
+// same squarings and multiplication, and the multiplier is looked up by
+// scanning the whole table, so the time taken only depends on the number
+// of limbs of e
+func (m *montgomery) exp(z, x []uint64, e []uint64) {
+
+	// table[i] = x^i in the Montgomery domain
+	var table [16][]uint64
+	table[0] = append([]uint64{}, m.one...)
+	table[1] = make([]uint64, m.n)
+	m.mul(table[1], x, m.rr)
+	for i := 2; i < len(table); i++ {
+		table[i] = make([]uint64, m.n)
+		m.mul(table[i], table[i-1], table[1])
+	}
+
+	acc := append([]uint64{}, m.one...)
+	entry := make([]uint64, m.n)
+	t := make([]uint64, m.n+2)
+	for i := len(e) - 1; i >= 0; i-- {
+		for shift := 60; shift >= 0; shift -= 4 {
+			m.mulScratch(acc, acc, acc, t)
+			m.mulScratch(acc, acc, acc, t)
+			m.mulScratch(acc, acc, acc, t)
+			m.mulScratch(acc, acc, acc, t)
+
+			window := (e[i] >> uint(shift)) & 0xf
+			for j := range table {
+				ctSelect(entry, table[j], ctEq(uint64(j), window))
+			}
+			m.mulScratch(acc, acc, entry, t)
+		}
+	}
+
+	// leave the Montgomery domain
+	one := make([]uint64, m.n)
+	one[0] = 1
+	m.mul(z, acc, one)
+}
+
+// returns 1 if x == y and 0 otherwise (without branching)
+func ctEq(x, y uint64) uint64 {
+	d := x ^ y
+	return 1 ^ ((d | -d) >> 63)
+}
+
+// powers of a fixed base x in the Montgomery domain: window w holds
+// x^(j * 16^w) for j = 0, ..., 15
+type fixedBase [][16][]uint64
+
+// precomputes the powers of x for exponents of up to the given bit length
+func (m *montgomery) newFixedBase(x []uint64, bitLen int) fixedBase {
+
+	table := make(fixedBase, (bitLen+3)/4)
+	base := make([]uint64, m.n)
+	m.mul(base, x, m.rr)
+
+	t := make([]uint64, m.n+2)
+	for w := range table {
+		table[w][0] = append([]uint64{}, m.one...)
+		for j := 1; j < 16; j++ {
+			table[w][j] = make([]uint64, m.n)
+			m.mulScratch(table[w][j], table[w][j-1], base, t)
+		}
+
+		// base^16 for the next window
+		m.mulScratch(base, table[w][15], base, t)
+	}
+
+	return table
+}
+
+// z = x^e mod P for the base x of the table (e must fit in the table);
+// one multiplication per window and no squarings, with the multiplier
+// looked up by scanning the whole window as in exp
+func (m *montgomery) expFixed(z []uint64, table fixedBase, e []uint64) {
+
+	acc := append([]uint64{}, m.one...)
+	entry := make([]uint64, m.n)
+	t := make([]uint64, m.n+2)
+	for w := range table {
+		window := (e[w/16] >> uint(4*(w%16))) & 0xf
+		for j := range table[w] {
+			ctSelect(entry, table[w][j], ctEq(uint64(j), window))
+		}
+		m.mulScratch(acc, acc, entry, t)
+	}
+
+	// leave the Montgomery domain
+	one := make([]uint64, m.n)
+	one[0] = 1
+	m.mul(z, acc, one)
+}
@@ -0,0 +1,233 @@
+package algebra
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Polynomial is a polynomial over a field; Coeffs[i] is the coefficient
+// of x^i (so Coeffs[0] is the constant term)
+type Polynomial struct {
+	Field  *Field
+	Coeffs []*FieldElement
+}
+
+// new polynomial with the coefficients (constant term first)
+func NewPolynomial(f *Field, coeffs []*FieldElement) *Polynomial {
+	return &Polynomial{Field: f, Coeffs: coeffs}
+}
+
+// RandomPolynomial returns a random polynomial of the degree
+// with the given constant term (e.g., a secret to share)
+func (f *Field) RandomPolynomial(degree int, constant *FieldElement) *Polynomial {
+	coeffs := make([]*FieldElement, degree+1)
+	coeffs[0] = f.NewElement(constant.Int)
+	for i := 1; i <= degree; i++ {
+		coeffs[i] = f.RandomElement()
+	}
+
+	return NewPolynomial(f, coeffs)
+}
+
+// Degree returns the degree of the polynomial (-1 for the zero polynomial)
+func (p *Polynomial) Degree() int {
+	for i := len(p.Coeffs) - 1; i >= 0; i-- {
+		if !p.Field.IsZero(p.Coeffs[i]) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Eval returns p(x) (with Horner's rule)
+func (p *Polynomial) Eval(x *FieldElement) *FieldElement {
+	res := p.Field.AddIdentity()
+	for i := len(p.Coeffs) - 1; i >= 0; i-- {
+		res = p.Field.Add(p.Field.Mul(res, x), p.Coeffs[i])
+	}
+
+	return res
+}
+
+// Add returns p + q
+func (p *Polynomial) Add(q *Polynomial) *Polynomial {
+	n := len(p.Coeffs)
+	if len(q.Coeffs) > n {
+		n = len(q.Coeffs)
+	}
+
+	coeffs := make([]*FieldElement, n)
+	for i := range coeffs {
+		coeffs[i] = p.Field.AddIdentity()
+		if i < len(p.Coeffs) {
+			coeffs[i] = p.Field.Add(coeffs[i], p.Coeffs[i])
+		}
+		if i < len(q.Coeffs) {
+			coeffs[i] = p.Field.Add(coeffs[i], q.Coeffs[i])
+		}
+	}
+
+	return NewPolynomial(p.Field, coeffs)
+}
+
+// Mul returns p * q
+func (p *Polynomial) Mul(q *Polynomial) *Polynomial {
+	if len(p.Coeffs) == 0 || len(q.Coeffs) == 0 {
+		return NewPolynomial(p.Field, nil)
+	}
+
+	coeffs := make([]*FieldElement, len(p.Coeffs)+len(q.Coeffs)-1)
+	for i := range coeffs {
+		coeffs[i] = p.Field.AddIdentity()
+	}
+
+	for i, a := range p.Coeffs {
+		for j, b := range q.Coeffs {
+			coeffs[i+j] = p.Field.Add(coeffs[i+j], p.Field.Mul(a, b))
+		}
+	}
+
+	return NewPolynomial(p.Field, coeffs)
+}
+
+// Scale returns c * p
+func (p *Polynomial) Scale(c *FieldElement) *Polynomial {
+	coeffs := make([]*FieldElement, len(p.Coeffs))
+	for i, a := range p.Coeffs {
+		coeffs[i] = p.Field.Mul(a, c)
+	}
+
+	return NewPolynomial(p.Field, coeffs)
+}
+
+// BatchMulInv returns the inverses of the elements with a single inversion
+// (Montgomery's trick: 3(n-1) multiplications instead of n-1 inversions);
+// it fails if any of the elements is zero
+func (f *Field) BatchMulInv(elems []*FieldElement) ([]*FieldElement, error) {
+
+	if len(elems) == 0 {
+		return nil, nil
+	}
+
+	// prefix[i] = elems[0] * ... * elems[i]
+	prefix := make([]*FieldElement, len(elems))
+	acc := f.MulIdentity()
+	for i, e := range elems {
+		if f.IsZero(f.NewElement(e.Int)) {
+			return nil, fmt.Errorf("element %v has no inverse", i)
+		}
+		acc = f.Mul(acc, e)
+		prefix[i] = acc
+	}
+
+	// peel the elements off the inverse of the product
+	inv := f.MulInv(acc)
+	res := make([]*FieldElement, len(elems))
+	for i := len(elems) - 1; i > 0; i-- {
+		res[i] = f.Mul(inv, prefix[i-1])
+		inv = f.Mul(inv, elems[i])
+	}
+	res[0] = inv
+
+	return res, nil
+}
+
+// LagrangeCoefficients returns the coefficients l_i such that
+// p(x) = sum_i l_i p(xs[i]) for every polynomial p of degree
+// smaller than len(xs) (the points xs must be distinct)
+func (f *Field) LagrangeCoefficients(xs []*FieldElement, x *FieldElement) ([]*FieldElement, error) {
+
+	if len(xs) == 0 {
+		return nil, errors.New("no interpolation points")
+	}
+
+	// l_i = prod_{j != i} (x - xs[j]) / (xs[i] - xs[j])
+	nums := make([]*FieldElement, len(xs))
+	dens := make([]*FieldElement, len(xs))
+	for i := range xs {
+		nums[i], dens[i] = f.MulIdentity(), f.MulIdentity()
+		for j := range xs {
+			if i == j {
+				continue
+			}
+			nums[i] = f.Mul(nums[i], f.Sub(x, xs[j]))
+			dens[i] = f.Mul(dens[i], f.Sub(xs[i], xs[j]))
+		}
+	}
+
+	invs, err := f.BatchMulInv(dens)
+	if err != nil {
+		return nil, errors.New("interpolation points are not distinct")
+	}
+
+	for i := range nums {
+		nums[i] = f.Mul(nums[i], invs[i])
+	}
+
+	return nums, nil
+}
+
+// InterpolateAt returns p(x) for the polynomial p of degree smaller
+// than len(xs) such that p(xs[i]) = ys[i]
+func (f *Field) InterpolateAt(xs, ys []*FieldElement, x *FieldElement) (*FieldElement, error) {
+
+	if len(xs) != len(ys) {
+		return nil, fmt.Errorf("%v interpolation points for %v values", len(xs), len(ys))
+	}
+
+	coeffs, err := f.LagrangeCoefficients(xs, x)
+	if err != nil {
+		return nil, err
+	}
+
+	res := f.AddIdentity()
+	for i, l := range coeffs {
+		res = f.Add(res, f.Mul(l, ys[i]))
+	}
+
+	return res, nil
+}
+
+// Interpolate returns the polynomial p of degree smaller
+// than len(xs) such that p(xs[i]) = ys[i]
+func (f *Field) Interpolate(xs, ys []*FieldElement) (*Polynomial, error) {
+
+	if len(xs) != len(ys) {
+		return nil, fmt.Errorf("%v interpolation points for %v values", len(xs), len(ys))
+	}
+
+	if len(xs) == 0 {
+		return nil, errors.New("no interpolation points")
+	}
+
+	// denominators of the Lagrange basis polynomials
+	dens := make([]*FieldElement, len(xs))
+	for i := range xs {
+		dens[i] = f.MulIdentity()
+		for j := range xs {
+			if i != j {
+				dens[i] = f.Mul(dens[i], f.Sub(xs[i], xs[j]))
+			}
+		}
+	}
+
+	invs, err := f.BatchMulInv(dens)
+	if err != nil {
+		return nil, errors.New("interpolation points are not distinct")
+	}
+
+	// p = sum_i ys[i] / dens[i] * prod_{j != i} (x - xs[j])
+	res := NewPolynomial(f, nil)
+	for i := range xs {
+		basis := NewPolynomial(f, []*FieldElement{f.Mul(ys[i], invs[i])})
+		for j := range xs {
+			if i != j {
+				basis = basis.Mul(NewPolynomial(f, []*FieldElement{f.Negate(xs[j]), f.MulIdentity()}))
+			}
+		}
+		res = res.Add(basis)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,50 @@
+package algebra
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	"math/big"
+)
+
+// GenerateSchnorrGroup returns the subgroup of prime order q (of qBits bits)
+// of the multiplicative group of a prime P = kq + 1 (of pBits bits).
+// Exponents of the group are then qBits long rather than pBits long.
+func GenerateSchnorrGroup(random io.Reader, pBits, qBits int) (*Group, error) {
+
+	if qBits < 2 || pBits <= qBits {
+		return nil, fmt.Errorf("cannot generate a %v-bit subgroup of a %v-bit prime", qBits, pBits)
+	}
+
+	q, err := rand.Prime(random, qBits)
+	if err != nil {
+		return nil, err
+	}
+
+	// search for a prime P = 1 mod 2q of pBits bits
+	twoQ := new(big.Int).Lsh(q, 1)
+	var p *big.Int
+	for {
+		p, err = rand.Int(random, new(big.Int).Lsh(big.NewInt(1), uint(pBits)))
+		if err != nil {
+			return nil, err
+		}
+		p.SetBit(p, pBits-1, 1)
+		p.Sub(p, new(big.Int).Mod(p, twoQ))
+		p.Add(p, big.NewInt(1))
+
+		if p.BitLen() == pBits && p.ProbablyPrime(20) {
+			break
+		}
+	}
+
+	// g = h^((P-1)/q) for the smallest h such that g != 1
+	field := NewField(p)
+	cofactor := new(big.Int).Div(field.Pminus1(), q)
+	for h := int64(2); ; h++ {
+		g := field.Exp(field.NewElement(big.NewInt(h)), cofactor)
+		if !field.IsMulIdentity(g) {
+			return NewSubgroup(field, g, q), nil
+		}
+	}
+}
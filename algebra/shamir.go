@@ -0,0 +1,62 @@
+package algebra
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// ShamirShare is the share of party X of a secret shared with a random
+// polynomial p of degree t-1: Y = p(X) where p(0) is the secret.
+// Any t shares reconstruct the secret and fewer reveal nothing about it.
+type ShamirShare struct {
+	X *FieldElement // point of the party (1, ..., n)
+	Y *FieldElement
+}
+
+// ShamirShares returns n shares of the secret (for the parties 1, ..., n)
+// such that any t of them reconstruct it
+func (f *Field) ShamirShares(secret *FieldElement, t, n int) ([]*ShamirShare, error) {
+
+	if t < 1 || t > n {
+		return nil, fmt.Errorf("cannot share with threshold %v among %v parties", t, n)
+	}
+
+	if big.NewInt(int64(n)).Cmp(f.P) >= 0 {
+		return nil, fmt.Errorf("field is too small for %v parties", n)
+	}
+
+	poly := f.RandomPolynomial(t-1, secret)
+	shares := make([]*ShamirShare, n)
+	for i := range shares {
+		x := f.NewElement(big.NewInt(int64(i + 1)))
+		shares[i] = &ShamirShare{X: x, Y: poly.Eval(x)}
+	}
+
+	return shares, nil
+}
+
+// ShamirReconstruct returns the secret of the shares (interpolated at 0);
+// with fewer shares than the threshold the result is unrelated to the secret
+func (f *Field) ShamirReconstruct(shares []*ShamirShare) (*FieldElement, error) {
+
+	if len(shares) == 0 {
+		return nil, errors.New("no shares to reconstruct")
+	}
+
+	xs := make([]*FieldElement, len(shares))
+	ys := make([]*FieldElement, len(shares))
+	for i, share := range shares {
+		if share == nil || share.X == nil || share.Y == nil {
+			return nil, errors.New("missing share")
+		}
+
+		if f.IsZero(f.NewElement(share.X.Int)) {
+			return nil, errors.New("share has point 0 (the secret)")
+		}
+
+		xs[i], ys[i] = share.X, share.Y
+	}
+
+	return f.InterpolateAt(xs, ys, f.AddIdentity())
+}
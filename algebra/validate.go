@@ -0,0 +1,63 @@
+package algebra
+
+import (
+	"errors"
+	"math/big"
+)
+
+// Validate returns an error unless e is an element of the field
+// (i.e., an integer reduced mod P)
+func (f *Field) Validate(e *FieldElement) error {
+
+	if e == nil || e.Int == nil {
+		return errors.New("missing field element")
+	}
+
+	if e.Int.Sign() < 0 || e.Int.Cmp(f.P) >= 0 {
+		return errors.New("field element is not reduced mod P")
+	}
+
+	return nil
+}
+
+// Contains returns true if e is in the subgroup generated by G,
+// i.e., e is in [1, P) and e^Order = 1
+func (g *Group) Contains(e *GroupElement) bool {
+
+	if e == nil || g.Field.Validate(e.Value) != nil || e.Value.Int.Sign() == 0 {
+		return false
+	}
+
+	// every non-zero element is in the group when G
+	// generates the multiplicative group of the field
+	if g.Order == nil || g.Order.Cmp(g.Field.Pminus1()) == 0 {
+		return true
+	}
+
+	// both e and the order are public, so this does not need the
+	// constant-time exponentiation of the Montgomery backend
+	return exp(e.Value.Int, g.Order, g.Field.P).Cmp(big.NewInt(1)) == 0
+}
+
+// IsSmallOrder returns true if e has order 1 or 2 (i.e., e = 1 or e = P-1,
+// the only square roots of 1 in the field), the elements an adversary
+// can use to confine a value to a small set
+func (g *Group) IsSmallOrder(e *GroupElement) bool {
+	return g.Field.IsMulIdentity(e.Value) || e.Value.Int.Cmp(g.Field.Pminus1()) == 0
+}
+
+// Validate returns an error unless e is an element of the group generated
+// by G that does not have small order (see IsSmallOrder); received public
+// keys and proof values should be validated before they are used
+func (g *Group) Validate(e *GroupElement) error {
+
+	if !g.Contains(e) {
+		return errors.New("element is not in the group")
+	}
+
+	if g.IsSmallOrder(e) {
+		return errors.New("element has small order")
+	}
+
+	return nil
+}
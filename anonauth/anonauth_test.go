@@ -0,0 +1,284 @@
+package anonauth
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sachaservan/pacl/algebra"
+	paclsposs "github.com/sachaservan/pacl/pacl-sposs"
+	"github.com/sachaservan/pacl/transport"
+	dpf "github.com/sachaservan/vdpf"
+)
+
+// test configuration parameters
+const TestMaxAccounts = 16
+const TestNumAccounts = 5
+
+func setup(t *testing.T) (*Client, []*Verifier, []*algebra.FieldElement, []uint64) {
+
+	group := paclsposs.DefaultGroup()
+	params := NewParams(group, dpf.GenerateVDPFHashKeys(), TestMaxAccounts)
+
+	verifiers := []*Verifier{NewVerifier(0, params), NewVerifier(1, params)}
+	servers := []*httptest.Server{
+		httptest.NewServer(verifiers[0].Handler()),
+		httptest.NewServer(verifiers[1].Handler()),
+	}
+	t.Cleanup(servers[0].Close)
+	t.Cleanup(servers[1].Close)
+
+	verifiers[0].PeerURL = servers[1].URL
+	verifiers[1].PeerURL = servers[0].URL
+
+	peerKey := NewPeerKey()
+	verifiers[0].PeerKey = peerKey
+	verifiers[1].PeerKey = peerKey
+
+	client := NewClient(params, [2]string{servers[0].URL, servers[1].URL})
+
+	expField := algebra.NewField(group.Field.Pminus1())
+	keys := make([]*algebra.FieldElement, TestNumAccounts)
+	indices := make([]uint64, TestNumAccounts)
+	for i := range keys {
+		keys[i] = expField.RandomElement()
+
+		idx, err := client.Enroll(keys[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		indices[i] = idx
+	}
+
+	return client, verifiers, keys, indices
+}
+
+func TestLogin(t *testing.T) {
+
+	client, _, keys, indices := setup(t)
+
+	for i := range keys {
+		token, err := client.Login(indices[i], keys[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := client.CheckToken(token); err != nil {
+			t.Fatalf("Session token was rejected: %v", err)
+		}
+	}
+}
+
+func TestLoginWrongKey(t *testing.T) {
+
+	client, _, keys, indices := setup(t)
+
+	if _, err := client.Login(indices[0], keys[1]); err == nil {
+		t.Fatalf("Logged in without knowing the account key")
+	}
+}
+
+func TestEnrollInvalidKey(t *testing.T) {
+
+	_, verifiers, _, _ := setup(t)
+	group := verifiers[0].KeyList.Group
+
+	// the identity has small order
+	if _, err := verifiers[0].Enroll(group.Identity()); err == nil {
+		t.Fatalf("Enrolled a small-order public key")
+	}
+}
+
+func TestTokenShares(t *testing.T) {
+
+	client, verifiers, keys, indices := setup(t)
+
+	token, err := client.Login(indices[2], keys[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a token with a share missing is rejected
+	partial := &SessionToken{Nonce: token.Nonce, Shares: [2]*TokenShare{token.Shares[0], nil}}
+	if client.CheckToken(partial) == nil {
+		t.Fatalf("Accepted a token with a missing share")
+	}
+
+	// token shares are bound to the nonce
+	forged := &SessionToken{Nonce: make([]byte, NonceSize), Shares: token.Shares}
+	if verifiers[0].CheckToken(forged) || verifiers[1].CheckToken(forged) {
+		t.Fatalf("Accepted a token for the wrong nonce")
+	}
+
+	// expired token shares are rejected
+	expired := &TokenShare{Expiry: token.Shares[0].Expiry - 2*int64(DefaultTokenLifetime.Seconds())}
+	expired.Tag = verifiers[0].tag(token.Nonce, expired.Expiry)
+	if verifiers[0].CheckToken(&SessionToken{Nonce: token.Nonce, Shares: [2]*TokenShare{expired, nil}}) {
+		t.Fatalf("Accepted an expired token")
+	}
+}
+
+func TestLoginOverPeerConn(t *testing.T) {
+
+	client, verifiers, keys, indices := setup(t)
+
+	// the verifiers exchange audit shares over a pipe instead of HTTP
+	a, b := transport.Pipe()
+	t.Cleanup(func() { a.Close(); b.Close() })
+	for i, conn := range []transport.PeerConn{a, b} {
+		verifiers[i].PeerURL = ""
+		verifiers[i].Peer = conn
+		go verifiers[i].ServePeer(conn)
+	}
+
+	token, err := client.Login(indices[1], keys[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.CheckToken(token); err != nil {
+		t.Fatalf("Session token was rejected: %v", err)
+	}
+
+	if _, err := client.Login(indices[0], keys[1]); err == nil {
+		t.Fatalf("Logged in without knowing the account key")
+	}
+}
+
+func TestForgedPeerAudit(t *testing.T) {
+
+	_, verifiers, _, _ := setup(t)
+
+	// audit shares posted to the second verifier
+	url := verifiers[0].PeerURL + "/audit"
+	encode := func(audit *paclsposs.AuditShare) []byte {
+		b, err := verifiers[0].KeyList.EncodeAudit(audit)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return b
+	}
+
+	audit := &PeerAudit{ID: "login", Audit: encode(&paclsposs.AuditShare{}), Time: time.Now().Unix()}
+
+	if post(url, audit, nil) == nil {
+		t.Fatalf("Accepted an audit share without a tag")
+	}
+
+	// the second verifier's own audit share sent back to it
+	audit.Tag = verifiers[1].peerTag(1, audit)
+	if post(url, audit, nil) == nil {
+		t.Fatalf("Accepted an audit share of the verifier itself")
+	}
+
+	// the tag covers the audit share
+	audit.Tag = verifiers[0].peerTag(0, audit)
+	forged := *audit
+	forged.Audit = encode(&paclsposs.AuditShare{BitSum: true})
+	if post(url, &forged, nil) == nil {
+		t.Fatalf("Accepted a modified audit share")
+	}
+
+	// authenticated audit shares must still be well-formed
+	malformed := &PeerAudit{ID: "malformed login", Audit: audit.Audit[1:], Time: audit.Time}
+	malformed.Tag = verifiers[0].peerTag(0, malformed)
+	if post(url, malformed, nil) == nil {
+		t.Fatalf("Accepted a malformed audit share")
+	}
+
+	if err := post(url, audit, nil); err != nil {
+		t.Fatalf("Rejected an audit share of the other verifier: %v", err)
+	}
+
+	if post(url, audit, nil) == nil {
+		t.Fatalf("Accepted a duplicate audit share")
+	}
+
+	// an audit share sent long ago
+	stale := &PeerAudit{ID: "other login", Audit: audit.Audit, Time: audit.Time - 2*int64(peerTimeout.Seconds())}
+	stale.Tag = verifiers[0].peerTag(0, stale)
+	if post(url, stale, nil) == nil {
+		t.Fatalf("Accepted an expired audit share")
+	}
+
+	// audit shares are only accepted over the connection once it is set
+	a, b := transport.Pipe()
+	t.Cleanup(func() { a.Close(); b.Close() })
+	verifiers[1].Peer = a
+
+	for _, handler := range []http.HandlerFunc{verifiers[1].Handler().ServeHTTP, verifiers[1].handleAudit} {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodPost, "/audit", nil))
+		if rec.Code != http.StatusNotFound {
+			t.Fatalf("Served /audit with a connection to the other verifier (status %v)", rec.Code)
+		}
+	}
+}
+
+func TestPendingLimit(t *testing.T) {
+
+	_, verifiers, _, _ := setup(t)
+	v := verifiers[0]
+
+	created := time.Now()
+	for i := 0; i < maxPending; i++ {
+		v.pending[fmt.Sprint(i)] = &pendingAudit{created: created}
+	}
+
+	if _, err := v.peerAudits("new login"); err == nil {
+		t.Fatalf("Kept more than %v pending logins", maxPending)
+	}
+
+	// the entries expire
+	for _, p := range v.pending {
+		p.created = created.Add(-2 * peerTimeout)
+	}
+
+	if _, err := v.peerAudits("new login"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(v.pending) != 1 {
+		t.Fatalf("Kept %v expired pending logins", len(v.pending)-1)
+	}
+}
+
+// run with -race: logins must not race with enrollments
+func TestConcurrentEnrollLogin(t *testing.T) {
+
+	client, _, keys, indices := setup(t)
+	expField := algebra.NewField(client.Params.Group.Field.Pminus1())
+
+	done := make(chan error)
+	go func() {
+		for i := TestNumAccounts; i < TestMaxAccounts; i++ {
+			if _, err := client.Enroll(expField.RandomElement()); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+
+	// the audit fails while the lists of the verifiers differ
+	// (i.e., an account is enrolled with one verifier only)
+	for i := range keys {
+		if token, err := client.Login(indices[i], keys[i]); err == nil {
+			if err := client.CheckToken(token); err != nil {
+				t.Fatalf("Session token was rejected: %v", err)
+			}
+		}
+	}
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Login(indices[0], keys[0]); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,110 @@
+package anonauth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+
+	"github.com/sachaservan/pacl/algebra"
+	paclsposs "github.com/sachaservan/pacl/pacl-sposs"
+)
+
+// Client enrolls and logs in to accounts held by two verifiers
+type Client struct {
+	Params *paclsposs.KeyListParams // public parameters of the account list
+	URLs   [2]string                // base URLs of both verifiers (in order)
+}
+
+func NewClient(params *paclsposs.KeyListParams, urls [2]string) *Client {
+	return &Client{Params: params, URLs: urls}
+}
+
+// Enroll creates an account with the secret key x and returns its index
+func (c *Client) Enroll(x *algebra.FieldElement) (uint64, error) {
+
+	publicKey, err := c.Params.Group.Encode(c.Params.Group.NewElement(x.Int))
+	if err != nil {
+		return 0, err
+	}
+
+	req := &EnrollRequest{PublicKey: publicKey}
+
+	var res [2]EnrollResponse
+	for i, url := range c.URLs {
+		if err := post(url+"/enroll", req, &res[i]); err != nil {
+			return 0, err
+		}
+	}
+
+	if res[0].Index != res[1].Index {
+		return 0, errors.New("verifiers enrolled the account at different indices")
+	}
+
+	return res[0].Index, nil
+}
+
+// Login proves knowledge of the secret key x of the account at index idx
+// and returns a session token issued jointly by both verifiers
+func (c *Client) Login(idx uint64, x *algebra.FieldElement) (*SessionToken, error) {
+
+	id := make([]byte, 16)
+	nonce := make([]byte, NonceSize)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	var proofs [2][]byte
+	for i, proof := range c.Params.NewProof(idx, x) {
+		encoded, err := c.Params.EncodeProof(proof)
+		if err != nil {
+			return nil, err
+		}
+
+		proofs[i] = encoded
+	}
+
+	// both requests must be in flight at the same time since
+	// each verifier waits for the audit share of the other
+	type result struct {
+		share *TokenShare
+		err   error
+	}
+
+	results := make([]chan result, 2)
+	for i := range c.URLs {
+		results[i] = make(chan result, 1)
+		go func(i int) {
+			req := &LoginRequest{ID: hex.EncodeToString(id), Nonce: nonce, Proof: proofs[i]}
+			share := &TokenShare{}
+			err := post(c.URLs[i]+"/login", req, share)
+			results[i] <- result{share, err}
+		}(i)
+	}
+
+	token := &SessionToken{Nonce: nonce}
+	for i := range results {
+		res := <-results[i]
+		if res.err != nil {
+			return nil, res.err
+		}
+
+		token.Shares[i] = res.share
+	}
+
+	return token, nil
+}
+
+// CheckToken returns nil if both verifiers accept the session token
+func (c *Client) CheckToken(token *SessionToken) error {
+	for _, url := range c.URLs {
+		if err := post(url+"/check", token, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
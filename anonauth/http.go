@@ -0,0 +1,353 @@
+package anonauth
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	paclsposs "github.com/sachaservan/pacl/pacl-sposs"
+)
+
+// how long a verifier waits for the audit share of the other verifier
+// (and keeps an audit share of the other verifier for a login)
+const peerTimeout = 10 * time.Second
+
+// maximum number of logins for which a verifier keeps audit shares
+// of the other verifier at once
+const maxPending = 1 << 16
+
+// PeerKeySize is the size (in bytes) of the keys returned by NewPeerKey
+const PeerKeySize = 32
+
+// Messages are sent as JSON, with the public keys, proof shares and audit
+// shares in their binary encodings (with field and group elements in their
+// canonical fixed-width encodings, see algebra.Field.Encode).
+
+// EnrollRequest is sent by a client to both verifiers to create an account
+type EnrollRequest struct {
+	PublicKey []byte // encoding of g^x (see algebra.Group.Encode)
+}
+
+type EnrollResponse struct {
+	Index uint64
+}
+
+// LoginRequest is sent by a client to one of the verifiers; the ID and
+// nonce must be the same in the login requests sent to both verifiers
+type LoginRequest struct {
+	ID    string
+	Nonce []byte
+	Proof []byte // encoding of the proof share (see paclsposs.KeyListParams.EncodeProof)
+}
+
+// PeerAudit is sent by a verifier to the other verifier during a login
+type PeerAudit struct {
+	ID    string
+	Audit []byte // encoding of the audit share (see paclsposs.KeyListParams.EncodeAudit)
+	Time  int64  // unix time at which the audit share was sent
+	Tag   []byte // MAC under the PeerKey of the verifiers (only sent to /audit)
+}
+
+// pending audit share of the other verifier for a login
+type pendingAudit struct {
+	audits  chan *paclsposs.AuditShare
+	created time.Time
+}
+
+// NewPeerKey returns a random key to be shared by both verifiers (see PeerKey)
+func NewPeerKey() []byte {
+	key := make([]byte, PeerKeySize)
+	_, err := rand.Read(key)
+	if err != nil {
+		panic(err)
+	}
+
+	return key
+}
+
+// Handler returns the HTTP handler of the verifier which serves
+//
+//	POST /enroll  EnrollRequest -> EnrollResponse
+//	POST /login   LoginRequest  -> TokenShare
+//	POST /audit   PeerAudit (from the other verifier, unless Peer is set)
+//	POST /check   SessionToken  (200 if the verifier's token share is valid)
+func (v *Verifier) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/enroll", v.handleEnroll)
+	mux.HandleFunc("/login", v.handleLogin)
+	if v.Peer == nil {
+		mux.HandleFunc("/audit", v.handleAudit)
+	}
+	mux.HandleFunc("/check", v.handleCheck)
+	return mux
+}
+
+func (v *Verifier) handleEnroll(w http.ResponseWriter, r *http.Request) {
+
+	var req EnrollRequest
+	if err := decode(r, &req); err != nil {
+		http.Error(w, "malformed enroll request", http.StatusBadRequest)
+		return
+	}
+
+	publicKey, err := v.params.Group.Decode(req.PublicKey)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("malformed public key: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	idx, err := v.Enroll(publicKey)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	encode(w, &EnrollResponse{Index: idx})
+}
+
+func (v *Verifier) handleLogin(w http.ResponseWriter, r *http.Request) {
+
+	var req LoginRequest
+	if err := decode(r, &req); err != nil || req.ID == "" {
+		http.Error(w, "malformed login request", http.StatusBadRequest)
+		return
+	}
+
+	proof, err := v.params.DecodeProof(req.Proof)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("malformed login proof: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	audit, err := v.Audit(proof)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	encoded, err := v.params.EncodeAudit(audit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	incoming, err := v.peerAudits(req.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	defer v.forget(req.ID)
+
+	if err := v.sendToPeer(&PeerAudit{ID: req.ID, Audit: encoded, Time: time.Now().Unix()}); err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	var other *paclsposs.AuditShare
+	select {
+	case other = <-incoming:
+	case <-time.After(peerTimeout):
+		http.Error(w, "timed out waiting for the other verifier", http.StatusGatewayTimeout)
+		return
+	}
+
+	share, err := v.Issue(audit, other, req.Nonce)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	encode(w, share)
+}
+
+func (v *Verifier) handleAudit(w http.ResponseWriter, r *http.Request) {
+
+	// audit shares are then only accepted over Peer (see ServePeer)
+	if v.Peer != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	var req PeerAudit
+	if err := decode(r, &req); err != nil || req.ID == "" || req.Audit == nil {
+		http.Error(w, "malformed audit share", http.StatusBadRequest)
+		return
+	}
+
+	if !v.checkPeerTag(&req) {
+		http.Error(w, "audit share is not authenticated by the other verifier", http.StatusUnauthorized)
+		return
+	}
+
+	if err := v.deliver(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+	}
+}
+
+func (v *Verifier) handleCheck(w http.ResponseWriter, r *http.Request) {
+
+	var token SessionToken
+	if err := decode(r, &token); err != nil {
+		http.Error(w, "malformed session token", http.StatusBadRequest)
+		return
+	}
+
+	if !v.CheckToken(&token) {
+		http.Error(w, "invalid session token", http.StatusUnauthorized)
+	}
+}
+
+// returns the channel on which the peer's audit share for the login is
+// delivered. The audit share can arrive before the login (which may then
+// never reach this verifier) so entries expire after peerTimeout; expired
+// entries are dropped once maxPending logins are pending.
+func (v *Verifier) peerAudits(id string) (chan *paclsposs.AuditShare, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	now := time.Now()
+	if p, ok := v.pending[id]; ok && now.Sub(p.created) <= peerTimeout {
+		return p.audits, nil
+	}
+
+	if len(v.pending) >= maxPending {
+		for other, p := range v.pending {
+			if now.Sub(p.created) > peerTimeout {
+				delete(v.pending, other)
+			}
+		}
+	}
+
+	if len(v.pending) >= maxPending {
+		return nil, errors.New("too many pending logins")
+	}
+
+	p := &pendingAudit{audits: make(chan *paclsposs.AuditShare, 1), created: now}
+	v.pending[id] = p
+
+	return p.audits, nil
+}
+
+// hands the audit share of the other verifier to the pending login
+// (only the first audit share of a login is kept)
+func (v *Verifier) deliver(audit *PeerAudit) error {
+
+	if d := time.Since(time.Unix(audit.Time, 0)); d > peerTimeout || d < -peerTimeout {
+		return errors.New("audit share has expired")
+	}
+
+	share, err := v.params.DecodeAudit(audit.Audit)
+	if err != nil {
+		return fmt.Errorf("malformed audit share: %v", err)
+	}
+
+	c, err := v.peerAudits(audit.ID)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case c <- share:
+		return nil
+	default:
+		return errors.New("duplicate audit share")
+	}
+}
+
+func (v *Verifier) forget(id string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	delete(v.pending, id)
+}
+
+func (v *Verifier) sendToPeer(audit *PeerAudit) error {
+	if v.Peer != nil {
+		return v.sendOverPeer(audit)
+	}
+
+	if v.PeerURL == "" {
+		return errors.New("the other verifier is unknown")
+	}
+
+	if len(v.PeerKey) == 0 {
+		return errors.New("no key is shared with the other verifier")
+	}
+
+	audit.Tag = v.peerTag(v.ServerNumber, audit)
+
+	return post(v.PeerURL+"/audit", audit, nil)
+}
+
+// returns true if the audit share was sent by the other verifier
+func (v *Verifier) checkPeerTag(audit *PeerAudit) bool {
+	if len(v.PeerKey) == 0 {
+		return false
+	}
+
+	return hmac.Equal(v.peerTag(1-v.ServerNumber, audit), audit.Tag)
+}
+
+// MAC of the audit share sent by the given verifier (so that a verifier's
+// own audit shares cannot be sent back to it)
+func (v *Verifier) peerTag(sender int, audit *PeerAudit) []byte {
+
+	header := make([]byte, 24)
+	binary.BigEndian.PutUint64(header, uint64(sender))
+	binary.BigEndian.PutUint64(header[8:], uint64(audit.Time))
+	binary.BigEndian.PutUint64(header[16:], uint64(len(audit.ID)))
+
+	mac := hmac.New(sha256.New, v.PeerKey)
+	mac.Write([]byte("pacl-peer-audit"))
+	mac.Write(header)
+	mac.Write([]byte(audit.ID))
+	mac.Write(audit.Audit)
+
+	return mac.Sum(nil)
+}
+
+// posts req as JSON to url and decodes the JSON response into res (if not nil)
+func post(url string, req interface{}, res interface{}) error {
+
+	data, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg := new(bytes.Buffer)
+		msg.ReadFrom(resp.Body)
+		return fmt.Errorf("%v: %v", resp.Status, string(bytes.TrimSpace(msg.Bytes())))
+	}
+
+	if res == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(res)
+}
+
+func decode(r *http.Request, v interface{}) error {
+	if r.Method != http.MethodPost {
+		return errors.New("expected a POST request")
+	}
+
+	return json.NewDecoder(r.Body).Decode(v)
+}
+
+func encode(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
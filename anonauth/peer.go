@@ -0,0 +1,56 @@
+package anonauth
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"time"
+
+	"github.com/sachaservan/pacl/transport"
+)
+
+// Instead of posting audit shares to the other verifier's /audit endpoint,
+// the verifiers can exchange them over a transport.PeerConn (e.g., a TCP
+// connection with mutual TLS): set Peer on both verifiers and run ServePeer
+// on the same connection. Every message is a JSON-encoded PeerAudit (with
+// the audit share in its binary encoding), which is authenticated by the
+// connection (so Tag is not set) and /audit is not served.
+
+// ServePeer delivers the audit shares received from the other verifier over
+// conn to the pending logins until conn is closed (it returns nil if it was
+// closed by either end)
+func (v *Verifier) ServePeer(conn transport.PeerConn) error {
+
+	for {
+		msg, err := conn.Receive()
+		if errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		var req PeerAudit
+		if err := json.Unmarshal(msg, &req); err != nil || req.ID == "" || req.Audit == nil {
+			return errors.New("malformed audit share from the other verifier")
+		}
+
+		// duplicate or expired audit shares (and audit shares of logins
+		// beyond maxPending) are dropped
+		v.deliver(&req)
+	}
+}
+
+func (v *Verifier) sendOverPeer(audit *PeerAudit) error {
+
+	msg, err := json.Marshal(audit)
+	if err != nil {
+		return err
+	}
+
+	v.peerMu.Lock()
+	defer v.peerMu.Unlock()
+
+	v.Peer.SetSendDeadline(time.Now().Add(peerTimeout))
+	return v.Peer.Send(msg)
+}
@@ -0,0 +1,224 @@
+package anonauth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/sachaservan/pacl/algebra"
+	paclsposs "github.com/sachaservan/pacl/pacl-sposs"
+	"github.com/sachaservan/pacl/sposs"
+	"github.com/sachaservan/pacl/transport"
+	dpf "github.com/sachaservan/vdpf"
+)
+
+// Accounts are identified by a public key g^x in a SPoSS key list held by
+// two verifiers. To log in, a client proves knowledge of the secret key x of
+// *some* account in the list without revealing which one. If both verifiers
+// accept the proof, each of them MACs a random nonce chosen by the client;
+// the resulting session token is only valid with both MACs and cannot be
+// linked to an account since the verifiers never learn the account index.
+
+// DefaultTokenLifetime is the lifetime of session tokens unless set otherwise
+const DefaultTokenLifetime = 15 * time.Minute
+
+// NonceSize is the size (in bytes) of session token nonces
+const NonceSize = 16
+
+// TokenShare is the part of a session token issued by one of the verifiers
+type TokenShare struct {
+	Expiry int64  // unix time after which the share is no longer valid
+	Tag    []byte // MAC of the nonce and expiry
+}
+
+// SessionToken is valid if the token shares of both verifiers are valid
+type SessionToken struct {
+	Nonce  []byte
+	Shares [2]*TokenShare
+}
+
+// Verifier is one of the two verifiers holding the list of accounts
+type Verifier struct {
+	ServerNumber  int
+	KeyList       *paclsposs.KeyList
+	MaxAccounts   uint64
+	TokenLifetime time.Duration
+	PeerURL       string             // base URL of the other verifier (see Handler)
+	PeerKey       []byte             // key shared with the other verifier authenticating audit shares sent to PeerURL (see NewPeerKey)
+	Peer          transport.PeerConn // connection to the other verifier, used instead of PeerURL (see ServePeer)
+
+	// copy of the public parameters, which Enroll does not change (so that
+	// messages are decoded without holding mu)
+	params *paclsposs.KeyListParams
+
+	macKey  []byte
+	mu      sync.RWMutex             // guards KeyList
+	peerMu  sync.Mutex               // serializes sends (and their deadlines) to Peer
+	pending map[string]*pendingAudit // audit shares received from the peer
+}
+
+// NewParams returns the public parameters of a list of at most maxAccounts accounts.
+// The VDPF hash keys should be chosen by the verifiers.
+func NewParams(group *algebra.Group, hashKeys [2]dpf.HashKey, maxAccounts uint64) *paclsposs.KeyListParams {
+
+	params := &paclsposs.KeyListParams{}
+	params.NumKeys = maxAccounts
+	params.Group = group
+	params.Field = group.Field
+	params.ParamSet = paclsposs.IdentifyParamSet(group)
+	params.FSSDomain = domainSize(maxAccounts)
+	params.PredicateType = paclsposs.Equality
+	params.HKey1 = hashKeys[0]
+	params.HKey2 = hashKeys[1]
+	params.ProofPP = sposs.NewPublicParams(group)
+
+	return params
+}
+
+// NewVerifier returns a verifier without any enrolled accounts
+func NewVerifier(serverNumber int, params *paclsposs.KeyListParams) *Verifier {
+
+	snapshot := *params
+	kl := &paclsposs.KeyList{KeyListParams: *params}
+	kl.NumKeys = 0
+	kl.KeyIndices = make([]uint64, 0)
+	kl.PublicKeys = make([]*algebra.GroupElement, 0)
+
+	macKey := make([]byte, 32)
+	_, err := rand.Read(macKey)
+	if err != nil {
+		panic(err)
+	}
+
+	return &Verifier{
+		ServerNumber:  serverNumber,
+		KeyList:       kl,
+		params:        &snapshot,
+		MaxAccounts:   params.NumKeys,
+		TokenLifetime: DefaultTokenLifetime,
+		macKey:        macKey,
+		pending:       make(map[string]*pendingAudit),
+	}
+}
+
+// Enroll adds the public key g^x of a new account to the list and returns its index.
+// Accounts must be enrolled in the same order with both verifiers; logins
+// fail while an account is enrolled with one verifier only.
+func (v *Verifier) Enroll(publicKey *algebra.GroupElement) (uint64, error) {
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	kl := v.KeyList
+	if kl.NumKeys >= v.MaxAccounts {
+		return 0, errors.New("account list is full")
+	}
+
+	if err := kl.Group.Validate(publicKey); err != nil {
+		return 0, fmt.Errorf("invalid public key: %v", err)
+	}
+
+	key := publicKey.Copy()
+	if v.ServerNumber == 1 {
+		// the second verifier holds -g^x (see paclsposs.NewProof)
+		key.Value = kl.Field.Sub(kl.Field.NewElement(kl.Field.P), key.Value)
+	}
+
+	idx := kl.NumKeys
+	kl.KeyIndices = append(kl.KeyIndices, idx)
+	kl.PublicKeys = append(kl.PublicKeys, key)
+	kl.NumKeys++
+	kl.FullDomain = (1<<kl.FSSDomain == kl.NumKeys) // only applies when domain = #keys
+
+	return idx, nil
+}
+
+// Audit computes the verifier's audit share of a login proof.
+// The audit share must be sent to the other verifier.
+func (v *Verifier) Audit(proof *paclsposs.ProofShare) (*paclsposs.AuditShare, error) {
+
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	if v.KeyList.NumKeys == 0 {
+		return nil, errors.New("no accounts are enrolled")
+	}
+
+	if err := v.KeyList.ValidateProof(proof); err != nil {
+		return nil, fmt.Errorf("malformed login proof: %v", err)
+	}
+
+	if proof.ShareNumber != uint(v.ServerNumber) {
+		return nil, errors.New("login proof is for the other verifier")
+	}
+
+	audit := v.KeyList.Audit(proof)
+
+	// the key share would reveal the selected key when combined
+	// with the other verifier's share so it is never sent out
+	audit.KeyShare = nil
+
+	return audit, nil
+}
+
+// Issue returns the verifier's share of a session token for the nonce
+// if the audit shares of both verifiers check out
+func (v *Verifier) Issue(own, other *paclsposs.AuditShare, nonce []byte) (*TokenShare, error) {
+
+	if len(nonce) != NonceSize {
+		return nil, errors.New("nonce has the wrong size")
+	}
+
+	v.mu.RLock()
+	ok := other != nil && v.KeyList.CheckAudit(own, other)
+	v.mu.RUnlock()
+
+	if !ok {
+		return nil, errors.New("PACL audit failed")
+	}
+
+	expiry := time.Now().Add(v.TokenLifetime).Unix()
+	return &TokenShare{Expiry: expiry, Tag: v.tag(nonce, expiry)}, nil
+}
+
+// CheckToken returns true if the verifier's share of the token is valid
+func (v *Verifier) CheckToken(token *SessionToken) bool {
+
+	share := token.Shares[v.ServerNumber]
+	if share == nil || len(token.Nonce) != NonceSize {
+		return false
+	}
+
+	if time.Now().Unix() > share.Expiry {
+		return false
+	}
+
+	return hmac.Equal(share.Tag, v.tag(token.Nonce, share.Expiry))
+}
+
+func (v *Verifier) tag(nonce []byte, expiry int64) []byte {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, uint64(expiry))
+
+	mac := hmac.New(sha256.New, v.macKey)
+	mac.Write([]byte("pacl-session"))
+	mac.Write(nonce)
+	mac.Write(data)
+
+	return mac.Sum(nil)
+}
+
+// number of DPF input bits required to address every account
+func domainSize(numKeys uint64) uint {
+	if numKeys <= 2 {
+		return 1
+	}
+
+	return uint(math.Ceil(math.Log2(float64(numKeys))))
+}
@@ -1,5 +0,0 @@
-#!/bin/bash
-
-go build -o bench-anon
-./bench-anon
-rm bench-anon
\ No newline at end of file
@@ -1,5 +0,0 @@
-#!/bin/bash
-
-go build -o bench-auth
-./bench-auth
-rm bench-auth
\ No newline at end of file
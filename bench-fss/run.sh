@@ -1,4 +0,0 @@
-#!/bin/bash
-go build -o bench-fss
-./bench-fss --numeval $NUMEVAL
-rm bench-fss
\ No newline at end of file
@@ -1,5 +0,0 @@
-#!/bin/bash
-
-go build -o bench-pir
-./bench-pir
-rm bench-pir
\ No newline at end of file
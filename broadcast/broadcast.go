@@ -0,0 +1,185 @@
+package broadcast
+
+import (
+	"errors"
+	"math"
+
+	"github.com/sachaservan/pacl/algebra"
+	"github.com/sachaservan/pacl/dpf128"
+	paclsposs "github.com/sachaservan/pacl/pacl-sposs"
+	"github.com/sachaservan/pacl/sposs"
+)
+
+// As in Spectrum, a write is a single DPF key whose output is the message:
+// the key (see dpf128.GenPayloadKeys) shares the message on the slot it
+// points to and zero everywhere else. The PACL proof of the write is
+// audited on the control bits of the same key, and the proofs of the
+// (verifiable) key also cover the message correction word, so a client
+// can only write to a slot whose secret key it knows.
+//
+// The key list has one slot per channel followed by one cover slot per
+// client, with a key g^y that only the client knows. Cover traffic is a
+// write of zeros to the client's cover slot (whose contents are discarded),
+// which the servers cannot tell apart from a broadcast.
+
+// Server holds one share of every channel for the current round
+type Server struct {
+	ServerNumber int
+	KeyList      *paclsposs.KeyList
+	NumChannels  uint64
+	MessageSize  int
+	contents     [][]byte
+}
+
+// WriteRequest is the request sent by a client to one of the servers
+type WriteRequest struct {
+	Proof *paclsposs.ProofShare // payload DPF key (WideDPFKey) with its PACL proof
+}
+
+// PendingWrite is a write request that has been audited by the server
+// but not yet aggregated into the channels
+type PendingWrite struct {
+	Request  *WriteRequest
+	Payloads [][]byte              // this server's share of the message written to every channel
+	Audit    *paclsposs.AuditShare // this server's audit share (sent to the other server)
+}
+
+// NewKeyList returns the list of channel keys g^x (one per channel) followed
+// by the cover keys g^y (one per client)
+func NewKeyList(group *algebra.Group, channelKeys, coverKeys []*algebra.GroupElement) *paclsposs.KeyList {
+
+	numKeys := uint64(len(channelKeys) + len(coverKeys))
+
+	kl := paclsposs.KeyList{}
+	kl.PublicKeys = append(append([]*algebra.GroupElement{}, channelKeys...), coverKeys...)
+	kl.NumKeys = numKeys
+	kl.Group = group
+	kl.Field = group.Field
+	kl.ParamSet = paclsposs.IdentifyParamSet(group)
+	kl.FSSDomain = domainSize(numKeys)
+	kl.PredicateType = paclsposs.Equality
+	kl.WideKeyIndices = make([]dpf128.Index, numKeys)
+	kl.ProofPP = sposs.NewPublicParams(group)
+
+	for i := range kl.WideKeyIndices {
+		kl.WideKeyIndices[i] = dpf128.Index{Lo: uint64(i)}
+	}
+
+	return &kl
+}
+
+// NewServer returns a server for the first numChannels slots of kl
+// (the others are cover slots); the second server gets a copy of the
+// key list with the sign of all keys flipped
+func NewServer(serverNumber int, kl *paclsposs.KeyList, numChannels uint64, messageSize int) *Server {
+
+	if numChannels > kl.NumKeys {
+		panic("key list has fewer slots than channels")
+	}
+
+	if serverNumber == 1 {
+		kl = kl.CloneKeyList()
+		kl.FlipSignOfKeys()
+	}
+
+	s := &Server{
+		ServerNumber: serverNumber,
+		KeyList:      kl,
+		NumChannels:  numChannels,
+		MessageSize:  messageSize,
+	}
+	s.resetRound()
+
+	return s
+}
+
+// Audit expands the write key and computes the server's audit share.
+// The audit share must be sent to the other server before the write is accepted.
+func (s *Server) Audit(req *WriteRequest) (*PendingWrite, error) {
+
+	if req == nil {
+		return nil, errors.New("missing write request")
+	}
+
+	if err := s.KeyList.ValidateProof(req.Proof); err != nil {
+		return nil, err
+	}
+
+	key := req.Proof.WideDPFKey
+	if len(key.CWPayload) != s.MessageSize {
+		return nil, errors.New("write request has the wrong message size")
+	}
+
+	bits, payloads, pi, err := dpf128.BatchVerEvalPayload(key, s.KeyList.WideKeyIndices)
+	if err != nil {
+		return nil, err
+	}
+
+	audit := s.KeyList.AuditExpanded(req.Proof, bits, pi)
+
+	// the key share would reveal the selected key when combined
+	// with the other server's share so it is never sent out
+	audit.KeyShare = nil
+
+	return &PendingWrite{
+		Request:  req,
+		Payloads: payloads[:s.NumChannels],
+		Audit:    audit,
+	}, nil
+}
+
+// Accept aggregates the write into the channels if the audit checks out;
+// returns false (and drops the write) otherwise
+func (s *Server) Accept(pw *PendingWrite, other *paclsposs.AuditShare) bool {
+
+	if !s.KeyList.CheckAudit(pw.Audit, other) {
+		return false
+	}
+
+	for j, payload := range pw.Payloads {
+		for k := range payload {
+			s.contents[j][k] ^= payload[k]
+		}
+	}
+
+	return true
+}
+
+// Publish returns the server's share of all channels for the
+// current round and starts a new round
+func (s *Server) Publish() [][]byte {
+	contents := s.contents
+	s.resetRound()
+	return contents
+}
+
+// CombineShares reconstructs the channel contents from the shares published by the servers
+func CombineShares(shares ...[][]byte) [][]byte {
+	contents := make([][]byte, len(shares[0]))
+	for j := range contents {
+		contents[j] = make([]byte, len(shares[0][j]))
+		for _, share := range shares {
+			for k := range contents[j] {
+				contents[j][k] ^= share[j][k]
+			}
+		}
+	}
+
+	return contents
+}
+
+func (s *Server) resetRound() {
+	s.contents = make([][]byte, s.NumChannels)
+	for j := range s.contents {
+		s.contents[j] = make([]byte, s.MessageSize)
+	}
+}
+
+// number of DPF input bits required to address every slot
+func domainSize(numKeys uint64) uint {
+	if numKeys <= 2 {
+		return 1
+	}
+
+	return uint(math.Ceil(math.Log2(float64(numKeys))))
+}
@@ -0,0 +1,182 @@
+package broadcast
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/sachaservan/pacl/algebra"
+	paclsposs "github.com/sachaservan/pacl/pacl-sposs"
+)
+
+// test configuration parameters
+const TestNumChannels = 7
+const TestNumClients = 3
+const TestMessageSize = 2
+
+// returns a client, the servers, the secret keys of the channels
+// and the secret keys of the cover slots
+func setup() (*Client, []*Server, []*algebra.FieldElement, []*algebra.FieldElement) {
+
+	group := paclsposs.DefaultGroup()
+	expField := algebra.NewField(group.Field.Pminus1())
+
+	keys, channelKeys := newKeys(group, expField, TestNumChannels)
+	covers, coverKeys := newKeys(group, expField, TestNumClients)
+
+	kl := NewKeyList(group, channelKeys, coverKeys)
+	servers := []*Server{
+		NewServer(0, kl, TestNumChannels, TestMessageSize),
+		NewServer(1, kl, TestNumChannels, TestMessageSize),
+	}
+
+	return NewClient(&kl.KeyListParams, TestNumChannels, TestMessageSize), servers, keys, covers
+}
+
+func newKeys(group *algebra.Group, expField *algebra.Field, n int) ([]*algebra.FieldElement, []*algebra.GroupElement) {
+	keys := make([]*algebra.FieldElement, n)
+	pubKeys := make([]*algebra.GroupElement, n)
+	for i := range keys {
+		keys[i] = expField.RandomElement()
+		pubKeys[i] = group.NewElement(keys[i].Int)
+	}
+
+	return keys, pubKeys
+}
+
+// runs the two-server write protocol and returns true if the write was accepted
+func processWrite(t *testing.T, servers []*Server, reqs []*WriteRequest) bool {
+
+	pendingA, err := servers[0].Audit(reqs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pendingB, err := servers[1].Audit(reqs[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the servers exchange audit shares
+	okA := servers[0].Accept(pendingA, pendingB.Audit)
+	okB := servers[1].Accept(pendingB, pendingA.Audit)
+
+	if okA != okB {
+		t.Fatalf("Servers disagree on the audit")
+	}
+
+	return okA
+}
+
+func coverRequests(t *testing.T, client *Client, cover uint64, y *algebra.FieldElement) []*WriteRequest {
+	t.Helper()
+
+	reqs, err := client.CoverRequests(cover, y)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return reqs
+}
+
+func publish(servers []*Server) [][]byte {
+	return CombineShares(servers[0].Publish(), servers[1].Publish())
+}
+
+func checkEmpty(t *testing.T, servers []*Server, what string) {
+	t.Helper()
+
+	for j, c := range publish(servers) {
+		if !bytes.Equal(c, make([]byte, TestMessageSize)) {
+			t.Fatalf("%v modified channel %v", what, j)
+		}
+	}
+}
+
+func TestBroadcast(t *testing.T) {
+
+	client, servers, keys, covers := setup()
+
+	channel := uint64(3)
+	msg := []byte{0xab, 0x5c}
+	reqs, err := client.WriteRequests(channel, keys[channel], msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !processWrite(t, servers, reqs) {
+		t.Fatalf("Authorized broadcast was dropped")
+	}
+
+	// other clients send cover traffic
+	for i := uint64(1); i < TestNumClients; i++ {
+		if !processWrite(t, servers, coverRequests(t, client, i, covers[i])) {
+			t.Fatalf("Cover traffic was dropped")
+		}
+	}
+
+	contents := publish(servers)
+	for j := range contents {
+		expected := make([]byte, TestMessageSize)
+		if uint64(j) == channel {
+			expected = msg
+		}
+
+		if !bytes.Equal(contents[j], expected) {
+			t.Fatalf("Channel %v has the wrong contents. expected: %v, got: %v", j, expected, contents[j])
+		}
+	}
+
+	// a new round starts with empty channels
+	checkEmpty(t, servers, "The previous round")
+}
+
+func TestCoverTraffic(t *testing.T) {
+
+	client, servers, keys, covers := setup()
+
+	for i := uint64(0); i < TestNumClients; i++ {
+		if !processWrite(t, servers, coverRequests(t, client, i, covers[i])) {
+			t.Fatalf("Cover traffic was dropped")
+		}
+	}
+
+	checkEmpty(t, servers, "Cover traffic")
+
+	// cover traffic is a write key of the same size as a broadcast
+	// (the size of the SPoSS proofs varies with their values)
+	reqs, _ := client.WriteRequests(0, keys[0], []byte{1})
+	if coverRequests(t, client, 0, covers[0])[0].Proof.WideDPFKey.Size() != reqs[0].Proof.WideDPFKey.Size() {
+		t.Fatalf("Cover traffic and broadcasts have different sizes")
+	}
+
+	// the cover slot of another client
+	if processWrite(t, servers, coverRequests(t, client, 1, covers[0])) {
+		t.Fatalf("Cover traffic with the wrong key was accepted")
+	}
+}
+
+func TestUnauthorizedBroadcast(t *testing.T) {
+
+	client, servers, keys, _ := setup()
+
+	// a client tries to broadcast on channel 2 with the key of channel 1
+	reqs, _ := client.WriteRequests(2, keys[1], []byte{0xff, 0xff})
+	if processWrite(t, servers, reqs) {
+		t.Fatalf("Unauthorized broadcast was accepted")
+	}
+
+	checkEmpty(t, servers, "Unauthorized broadcast")
+
+	// a client with the key of channel 1 tries to also write to other
+	// channels by giving the servers different message corrections
+	reqs, _ = client.WriteRequests(1, keys[1], []byte{0xff, 0xff})
+	bad := *reqs[1].Proof.WideDPFKey
+	bad.CWPayload = []byte{0x01, 0x01}
+	reqs[1].Proof.WideDPFKey = &bad
+
+	if processWrite(t, servers, reqs) {
+		t.Fatalf("Write with inconsistent message corrections was accepted")
+	}
+
+	checkEmpty(t, servers, "Write with inconsistent message corrections")
+}
@@ -0,0 +1,59 @@
+package broadcast
+
+import (
+	"errors"
+
+	"github.com/sachaservan/pacl/algebra"
+	"github.com/sachaservan/pacl/dpf128"
+	paclsposs "github.com/sachaservan/pacl/pacl-sposs"
+)
+
+// Client submits one write request per round (either a broadcast or cover traffic)
+type Client struct {
+	Params      *paclsposs.KeyListParams // public parameters of the key list
+	NumChannels uint64
+	MessageSize int
+}
+
+func NewClient(params *paclsposs.KeyListParams, numChannels uint64, messageSize int) *Client {
+	return &Client{Params: params, NumChannels: numChannels, MessageSize: messageSize}
+}
+
+// WriteRequests returns the write requests for both servers (in order)
+// broadcasting msg to the channel owned by x
+func (c *Client) WriteRequests(channel uint64, x *algebra.FieldElement, msg []byte) ([]*WriteRequest, error) {
+
+	if channel >= c.NumChannels {
+		return nil, errors.New("channel does not exist")
+	}
+
+	if len(msg) > c.MessageSize {
+		return nil, errors.New("message is too long")
+	}
+
+	padded := make([]byte, c.MessageSize)
+	copy(padded, msg)
+
+	return c.writeRequests(channel, x, padded), nil
+}
+
+// CoverRequests returns write requests for both servers (in order) that
+// leave the contents of all channels unchanged, writing to the cover slot
+// (counted from zero after the channels) owned by y
+func (c *Client) CoverRequests(cover uint64, y *algebra.FieldElement) ([]*WriteRequest, error) {
+
+	if cover >= c.Params.NumKeys-c.NumChannels {
+		return nil, errors.New("cover slot does not exist")
+	}
+
+	return c.writeRequests(c.NumChannels+cover, y, make([]byte, c.MessageSize)), nil
+}
+
+func (c *Client) writeRequests(slot uint64, x *algebra.FieldElement, msg []byte) []*WriteRequest {
+
+	idx := c.Params.WideKeyIndices[slot]
+	keyA, keyB := dpf128.GenPayloadKeys(idx, c.Params.FSSDomain, msg)
+	shares := c.Params.NewWideProofWithDPFKeys(idx, keyA, keyB, x)
+
+	return []*WriteRequest{{Proof: shares[0]}, {Proof: shares[1]}}
+}
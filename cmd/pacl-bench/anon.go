@@ -3,9 +3,7 @@ package main
 import (
 	"crypto/elliptic"
 	crand "crypto/rand"
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"math"
 	"math/big"
 	"time"
@@ -16,13 +14,14 @@ import (
 	dpf "github.com/sachaservan/vdpf"
 )
 
-func main() {
+func runAnon(cfg *AnonConfig) []*AnonExperiment {
 
-	n := []int{512, 1024, 2048, 4096, 8192, 16384, 32768, 65536, 131072, 262144, 524288, 1048576, 2097152, 4194304}
+	numTrials := cfg.Trials.Trials
+	experiments := make([]*AnonExperiment, 0)
 
-	for i, numAccounts := range n {
+	for _, numAccounts := range cfg.NumKeys {
 
-		experiment := &Experiment{
+		experiment := &AnonExperiment{
 			NumKeys: uint64(numAccounts),
 		}
 		experiment.ServerExpressMS = make([]int64, 0)
@@ -30,12 +29,19 @@ func main() {
 		experiment.ServerSpectrumMS = make([]int64, 0)
 		experiment.ServerSpectrumPACLMS = make([]int64, 0)
 
-		for trial := 0; trial < 10; trial++ {
+		for trial := 0; trial < cfg.Warmup; trial++ {
+			benchmarkVanillaExpress(numAccounts)
+			benchmarkPACLExpress(numAccounts)
+			benchmarkVanillaSpectrum(numAccounts)
+			benchmarkPACLSpectrum(numAccounts)
+		}
+
+		for trial := 0; trial < numTrials; trial++ {
 			experiment.ServerExpressMS = append(experiment.ServerExpressMS, benchmarkVanillaExpress(numAccounts))
 			experiment.ServerExpressPACLMS = append(experiment.ServerExpressPACLMS, benchmarkPACLExpress(numAccounts))
 			experiment.ServerSpectrumMS = append(experiment.ServerSpectrumMS, benchmarkVanillaSpectrum(numAccounts))
 			experiment.ServerSpectrumPACLMS = append(experiment.ServerSpectrumPACLMS, benchmarkPACLSpectrum(numAccounts))
-			fmt.Printf("Finished trial %v of %v\n", trial, 10)
+			fmt.Printf("Finished trial %v of %v\n", trial, numTrials)
 		}
 
 		fmt.Printf("Express          @ %v mailboxes: %v\n", numAccounts, experiment.ServerExpressMS[0])
@@ -43,10 +49,10 @@ func main() {
 		fmt.Printf("Spectrum         @ %v mailboxes: %v\n", numAccounts, experiment.ServerSpectrumMS[0])
 		fmt.Printf("Spectrum (PACL)  @ %v mailboxes: %v\n", numAccounts, experiment.ServerSpectrumPACLMS[0])
 
-		experimentJSON, _ := json.MarshalIndent(experiment, "", " ")
-		ioutil.WriteFile("experiment"+fmt.Sprint(i)+".json", experimentJSON, 0644)
+		experiments = append(experiments, experiment)
 	}
 
+	return experiments
 }
 
 func benchmarkVanillaExpress(numMailboxes int) int64 {
@@ -175,7 +181,7 @@ func benchmarkPACLSpectrum(numChannels int) int64 {
 	// setup parameters
 	group := paclsposs.DefaultGroup()
 	n := uint(math.Log2(float64(numChannels)))
-	kl, key, idx := paclsposs.GenerateBenchmarkKeyList(
+	kl, key, _, idx := paclsposs.GenerateBenchmarkKeyList(
 		uint64(numChannels), n, group, paclsposs.Equality, 0)
 
 	// client-side computation (precomputed here because we're
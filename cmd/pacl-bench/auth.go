@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	paclsposs "github.com/sachaservan/pacl/pacl-sposs"
+)
+
+func runAuth(cfg *AuthConfig) []*AuthExperiment {
+
+	numTrials := cfg.Trials.Trials
+	experiments := make([]*AuthExperiment, 0)
+
+	for _, numAccounts := range cfg.NumKeys {
+
+		experiment := &AuthExperiment{
+			NumKeys: uint64(numAccounts),
+		}
+
+		experiment.AuthTimeMS = make([]int64, 0)
+
+		for trial := 0; trial < cfg.Warmup; trial++ {
+			benchmarkPACLAuthTime(numAccounts)
+		}
+
+		for trial := 0; trial < numTrials; trial++ {
+			res := benchmarkPACLAuthTime(numAccounts)
+			experiment.AuthTimeMS = append(experiment.AuthTimeMS, res.AuthTime)
+			experiment.ClientProofTimeMS = append(experiment.ClientProofTimeMS, res.ClientTime)
+			experiment.ProofSize = uint64(res.ProofSize)
+			experiment.AuditSize = uint64(res.AuditSize)
+			fmt.Printf("Finished trial %v of %v\n", trial, numTrials)
+		}
+
+		fmt.Printf("Auth time @ %v accounts: %v\n", numAccounts, experiment.AuthTimeMS)
+		fmt.Printf("Client time @ %v accounts: %v (proof %v B, audit %v B)\n",
+			numAccounts, experiment.ClientProofTimeMS, experiment.ProofSize, experiment.AuditSize)
+
+		experiments = append(experiments, experiment)
+	}
+
+	return experiments
+}
+
+// result of one authentication trial
+type authTrial struct {
+	AuthTime   int64 // server-side (ms)
+	ClientTime int64 // client-side proof generation (ms)
+	ProofSize  int   // bytes sent to each server
+	AuditSize  int   // bytes exchanged between the servers
+}
+
+func benchmarkPACLAuthTime(numAccount int) *authTrial {
+
+	// setup parameters
+	group := paclsposs.DefaultGroup()
+	n := uint(math.Log2(float64(numAccount)))
+	kl, key, _, idx := paclsposs.GenerateBenchmarkKeyList(
+		uint64(numAccount), n, group, paclsposs.Equality, 0)
+
+	// client-side computation (timed separately from the server overhead)
+	start := time.Now()
+	shares := kl.NewProof(idx, key)
+	clientTime := time.Since(start).Milliseconds()
+
+	auditB := kl.Audit(shares[0])
+
+	start = time.Now()
+	auditA := kl.Audit(shares[0])
+	kl.CheckAudit(auditA, auditB)
+
+	return &authTrial{
+		AuthTime:   time.Since(start).Milliseconds(),
+		ClientTime: clientTime,
+		ProofSize:  shares[0].Size(),
+		AuditSize:  auditA.Size(),
+	}
+}
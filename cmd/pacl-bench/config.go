@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// Scenarios that can be selected (in the order they are run)
+var Scenarios = []string{"fss", "pir", "anon", "auth"}
+
+// Config selects the scenarios to run and their parameters.
+// Any field left out of a JSON config file keeps its default value.
+type Config struct {
+	Scenarios []string `json:"scenarios"`
+	OutDir    string   `json:"out_dir"`
+	Formats   []string `json:"formats"` // json and/or csv
+
+	FSS  FSSConfig  `json:"fss"`
+	PIR  PIRConfig  `json:"pir"`
+	Anon AnonConfig `json:"anon"`
+	Auth AuthConfig `json:"auth"`
+}
+
+// Trials configures how many times each configuration is measured
+type Trials struct {
+	Trials int `json:"trials"`
+	Warmup int `json:"warmup"` // untimed trials run before the measurements
+}
+
+type FSSConfig struct {
+	Trials
+	NumKeys    []uint64 `json:"num_keys"`    // amortize the proof verification across numKeys
+	NumSubkeys []uint64 `json:"num_subkeys"` // subkeys per key for the inclusion predicate
+	Domains    []uint   `json:"fss_domains"`
+}
+
+type PIRConfig struct {
+	Trials
+	DBSizes   []int `json:"db_sizes"`
+	ItemSizes []int `json:"item_sizes"` // in bytes
+}
+
+type AnonConfig struct {
+	Trials
+	NumKeys []int `json:"num_keys"` // mailboxes (Express) and channels (Spectrum)
+}
+
+type AuthConfig struct {
+	Trials
+	NumKeys []int `json:"num_keys"` // accounts
+}
+
+// DefaultConfig returns the parameters used in the paper
+func DefaultConfig() *Config {
+	return &Config{
+		Scenarios: Scenarios,
+		OutDir:    ".",
+		Formats:   []string{"json"},
+		FSS: FSSConfig{
+			Trials:     Trials{Trials: 1000, Warmup: 1000},
+			NumKeys:    []uint64{1, 2, 4, 8, 16, 32, 64, 128, 256, 512, 1024},
+			NumSubkeys: []uint64{1, 10, 20},
+			Domains:    []uint{32},
+		},
+		PIR: PIRConfig{
+			Trials:    Trials{Trials: 10},
+			DBSizes:   []int{16384, 32768, 65536, 131072, 262144, 524288, 1048576, 2097152, 4194304},
+			ItemSizes: []int{1024, 2048},
+		},
+		Anon: AnonConfig{
+			Trials:  Trials{Trials: 10},
+			NumKeys: []int{512, 1024, 2048, 4096, 8192, 16384, 32768, 65536, 131072, 262144, 524288, 1048576, 2097152, 4194304},
+		},
+		Auth: AuthConfig{
+			Trials:  Trials{Trials: 10},
+			NumKeys: []int{262144, 524288, 1048576, 2097152, 4194304, 8388608},
+		},
+	}
+}
+
+// QuickConfig returns small parameters that run in a few seconds (e.g., in CI)
+func QuickConfig() *Config {
+	return &Config{
+		Scenarios: Scenarios,
+		OutDir:    ".",
+		Formats:   []string{"json"},
+		FSS: FSSConfig{
+			Trials:     Trials{Trials: 10, Warmup: 1},
+			NumKeys:    []uint64{1, 16},
+			NumSubkeys: []uint64{1, 10},
+			Domains:    []uint{32},
+		},
+		PIR: PIRConfig{
+			Trials:    Trials{Trials: 2, Warmup: 1},
+			DBSizes:   []int{1024},
+			ItemSizes: []int{64},
+		},
+		Anon: AnonConfig{
+			Trials:  Trials{Trials: 2, Warmup: 1},
+			NumKeys: []int{512},
+		},
+		Auth: AuthConfig{
+			Trials:  Trials{Trials: 2, Warmup: 1},
+			NumKeys: []int{1024},
+		},
+	}
+}
+
+// LoadConfig overrides the fields of cfg with those set in the JSON file
+func (cfg *Config) LoadConfig(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return fmt.Errorf("%v: %v", path, err)
+	}
+
+	return nil
+}
+
+// SetTrials overrides the number of trials of every scenario
+func (cfg *Config) SetTrials(trials int) {
+	cfg.FSS.Trials.Trials = trials
+	cfg.PIR.Trials.Trials = trials
+	cfg.Anon.Trials.Trials = trials
+	cfg.Auth.Trials.Trials = trials
+}
+
+// SetWarmup overrides the number of warmup trials of every scenario
+func (cfg *Config) SetWarmup(warmup int) {
+	cfg.FSS.Warmup = warmup
+	cfg.PIR.Warmup = warmup
+	cfg.Anon.Warmup = warmup
+	cfg.Auth.Warmup = warmup
+}
+
+// Validate checks that the selected scenarios and formats exist
+func (cfg *Config) Validate() error {
+	for _, s := range cfg.Scenarios {
+		if !contains(Scenarios, s) {
+			return fmt.Errorf("unknown scenario %q (expected one of %v)", s, strings.Join(Scenarios, ", "))
+		}
+	}
+
+	for _, f := range cfg.Formats {
+		if f != "json" && f != "csv" {
+			return fmt.Errorf("unknown output format %q (expected json or csv)", f)
+		}
+	}
+
+	for _, t := range []Trials{cfg.FSS.Trials, cfg.PIR.Trials, cfg.Anon.Trials, cfg.Auth.Trials} {
+		if t.Trials <= 0 || t.Warmup < 0 {
+			return fmt.Errorf("need a positive number of trials and a non-negative number of warmup trials")
+		}
+	}
+
+	return nil
+}
+
+func (cfg *Config) Runs(scenario string) bool {
+	return contains(cfg.Scenarios, scenario)
+}
+
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+
+	return false
+}
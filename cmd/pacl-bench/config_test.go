@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	config := `{"scenarios": ["pir"], "pir": {"trials": 3, "db_sizes": [65536]}}`
+	if err := ioutil.WriteFile(path, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := DefaultConfig()
+	if err := cfg.LoadConfig(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !cfg.Runs("pir") || cfg.Runs("fss") {
+		t.Fatalf("Wrong scenarios: %v", cfg.Scenarios)
+	}
+
+	if cfg.PIR.Trials.Trials != 3 || len(cfg.PIR.DBSizes) != 1 || cfg.PIR.DBSizes[0] != 65536 {
+		t.Fatalf("Config file was not applied: %+v", cfg.PIR)
+	}
+
+	// fields that are not in the file keep their defaults
+	if len(cfg.PIR.ItemSizes) != len(DefaultConfig().PIR.ItemSizes) || cfg.FSS.Trials.Trials != 1000 {
+		t.Fatalf("Config file overrode unset fields")
+	}
+
+	cfg.Scenarios = []string{"nope"}
+	if cfg.Validate() == nil {
+		t.Fatalf("Accepted an unknown scenario")
+	}
+}
+
+func TestWriteResults(t *testing.T) {
+
+	dir := t.TempDir()
+	experiments := []*AuthExperiment{
+		{NumKeys: 16, AuthTimeMS: []int64{1, 2}},
+		{NumKeys: 32, AuthTimeMS: []int64{3, 4}},
+	}
+
+	if err := WriteResults(dir, []string{"json", "csv"}, "auth", experiments); err != nil {
+		t.Fatal(err)
+	}
+
+	// the JSON file is an array of experiments (as in paper_results/)
+	data, err := ioutil.ReadFile(filepath.Join(dir, "auth.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded []*AuthExperiment
+	if err := json.Unmarshal(data, &decoded); err != nil || len(decoded) != 2 || decoded[1].AuthTimeMS[1] != 4 {
+		t.Fatalf("Wrong JSON output: %v", string(data))
+	}
+
+	data, err = ioutil.ReadFile(filepath.Join(dir, "auth.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "num_keys,pacl_proof_bytes,pacl_audit_bytes,trial,pacl_auth_time_ms,pacl_client_proof_time_ms\n" +
+		"16,0,0,0,1,\n16,0,0,1,2,\n32,0,0,0,3,\n32,0,0,1,4,\n"
+	if string(data) != expected {
+		t.Fatalf("Wrong CSV output. expected: %q, got: %q", expected, string(data))
+	}
+
+	if !strings.HasPrefix(string(data), "num_keys,") {
+		t.Fatalf("CSV header does not use the JSON field names")
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+// The JSON schemas of the experiments are read by the plotting
+// scripts in paper_results/ and must not change (fields may be added).
+
+type FSSExperiment struct {
+	NumKeys                       uint64  `json:"num_keys"`
+	NumSubkeys                    uint64  `json:"num_subkeys"`
+	FSSDomain                     uint64  `json:"fss_domain"`
+	GroupExponentiation           uint64  `json:"group_exp_us"`
+	EqualityBaselineProcessing    []int64 `json:"equality_baseline_processing_us"`
+	EqualityBaselineVerProcessing []int64 `json:"equality_baseline_ver_processing_us"`
+	EqualityDPFPACLProcessing     []int64 `json:"equality_dpf_pacl_processing_us"`
+	EqualityDPFSKPACLProcessing   []int64 `json:"equality_dpf_sk_pacl_processing_us"`
+	EqualityVDPFPACLProcessing    []int64 `json:"equality_vdpf_pacl_processing_us"`
+	EqualityVDPFSKPACLProcessing  []int64 `json:"equality_vdpf_sk_pacl_processing_us"`
+
+	RangeBaselineProcessing    []int64 `json:"range_baseline_processing_us"`
+	RangeBaselineVerProcessing []int64 `json:"range_baseline_ver_processing_us"`
+	RangeDPFPACLProcessing     []int64 `json:"range_dpf_pacl_processing_us"`
+	RangeDPFSKPACLProcessing   []int64 `json:"range_dpf_sk_pacl_processing_us"`
+	RangeVDPFPACLProcessing    []int64 `json:"range_vdpf_pacl_processing_us"`
+	RangeVDPFSKPACLProcessing  []int64 `json:"range_vdpf_sk_pacl_processing_us"`
+
+	// client-side proof generation (not amortized over the keys)
+	ClientDPFPACLProof   []int64 `json:"client_dpf_pacl_proof_us"`
+	ClientDPFSKPACLProof []int64 `json:"client_dpf_sk_pacl_proof_us"`
+	ClientVDPFPACLProof  []int64 `json:"client_vdpf_pacl_proof_us"`
+
+	// bytes sent by the client to each verifier and between the verifiers
+	DPFPACLProofSize   uint64 `json:"dpf_pacl_proof_bytes"`
+	DPFPACLAuditSize   uint64 `json:"dpf_pacl_audit_bytes"`
+	DPFSKPACLProofSize uint64 `json:"dpf_sk_pacl_proof_bytes"`
+	DPFSKPACLAuditSize uint64 `json:"dpf_sk_pacl_audit_bytes"`
+	VDPFPACLProofSize  uint64 `json:"vdpf_pacl_proof_bytes"`
+	VDPFPACLAuditSize  uint64 `json:"vdpf_pacl_audit_bytes"`
+}
+
+type PIRExperiment struct {
+	DBSize                       uint64  `json:"db_size"`
+	ItemSize                     uint64  `json:"item_size"`
+	ServerXorProcessingMS        []int64 `json:"server_xor_processing_ms"`
+	ServerPIRProcessingMS        []int64 `json:"server_pir_processing_ms"`
+	ServerPIRKeywordProcessingMS []int64 `json:"server_pir_keyword_processing_ms"`
+	ServerPIRPACLProcessingMS    []int64 `json:"server_pir_pacl_processing_ms"`
+}
+
+type AnonExperiment struct {
+	NumKeys              uint64  `json:"num_keys"`
+	ServerExpressMS      []int64 `json:"server_express_ms"`
+	ServerSpectrumMS     []int64 `json:"server_spectrum_ms"`
+	ServerExpressPACLMS  []int64 `json:"server_express_pacl_ms"`
+	ServerSpectrumPACLMS []int64 `json:"server_spectrum_pacl_ms"`
+}
+
+type AuthExperiment struct {
+	NumKeys           uint64  `json:"num_keys"`
+	AuthTimeMS        []int64 `json:"pacl_auth_time_ms"`
+	ClientProofTimeMS []int64 `json:"pacl_client_proof_time_ms"`
+	ProofSize         uint64  `json:"pacl_proof_bytes"`
+	AuditSize         uint64  `json:"pacl_audit_bytes"`
+}
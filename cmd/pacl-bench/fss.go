@@ -2,15 +2,14 @@ package main
 
 import (
 	"crypto/elliptic"
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"math/rand"
 	"time"
 
 	paclpk "github.com/sachaservan/pacl/pacl-pk"
 	paclsk "github.com/sachaservan/pacl/pacl-sk"
 	paclsposs "github.com/sachaservan/pacl/pacl-sposs"
+	"github.com/sachaservan/pacl/slot"
 	dpf "github.com/sachaservan/vdpf"
 )
 
@@ -28,19 +27,14 @@ type BaselineKey struct {
 	Indices    []uint64
 }
 
-func main() {
+func runFSS(cfg *FSSConfig) []*FSSExperiment {
 
-	numTrials := 1000
+	numTrials := cfg.Trials.Trials
+	experiments := make([]*FSSExperiment, 0)
 
-	// amortize the proof verification across numEval
-	numEvalKeys := []uint64{1, 2, 4, 8, 16, 32, 64, 128, 256, 512, 1024}
-	numEvalSubkeys := []uint64{1, 10, 20}
-	domain := []uint{32}
-
-	i := 0
-	for _, fssDomain := range domain {
-		for _, numKeys := range numEvalKeys {
-			for _, numSubkeys := range numEvalSubkeys {
+	for _, fssDomain := range cfg.Domains {
+		for _, numKeys := range cfg.NumKeys {
+			for _, numSubkeys := range cfg.NumSubkeys {
 
 				amortization := int64(numKeys)
 
@@ -82,9 +76,9 @@ func main() {
 				//////////////////////////////////
 				// setup the keylists for this set of parameters
 				//////////////////////////////////
-				klpk, xpk, idxpk := paclpk.GenerateBenchmarkKeyList(numKeys, fssDomain, elliptic.P256(), paclpk.Inclusion, numSubkeys)
+				klpk, xpk, _, idxpk := paclpk.GenerateBenchmarkKeyList(numKeys, fssDomain, elliptic.P256(), paclpk.Inclusion, numSubkeys)
 				klsk, xsk, idxsk := paclsk.GenerateBenchmarkKeyList(numKeys, fssDomain, paclsk.Inclusion, numSubkeys)
-				klsposs, xsposs, idxsposs := paclsposs.GenerateBenchmarkKeyList(
+				klsposs, xsposs, _, idxsposs := paclsposs.GenerateBenchmarkKeyList(
 					numKeys, fssDomain, paclsposs.DefaultGroup(), paclsposs.Inclusion, numSubkeys)
 
 				// generate the PACL proofs
@@ -94,14 +88,20 @@ func main() {
 				//////////////////////////////////
 
 				// initialize the experiment for this set of parameters
-				experiment := &Experiment{
-					FSSDomain:  uint64(fssDomain),
-					NumKeys:    numKeys,
-					NumSubkeys: numSubkeys,
+				experiment := &FSSExperiment{
+					FSSDomain:          uint64(fssDomain),
+					NumKeys:            numKeys,
+					NumSubkeys:         numSubkeys,
+					DPFPACLProofSize:   uint64(sharesPk[0].Size()),
+					DPFPACLAuditSize:   uint64(klpk.Audit(sharesPk[0]).Size()),
+					DPFSKPACLProofSize: uint64(sharesSk[0].Size()),
+					DPFSKPACLAuditSize: uint64(klsk.Audit(sharesSk[0]).Size()),
+					VDPFPACLProofSize:  uint64(sharesSposs[0].Size()),
+					VDPFPACLAuditSize:  uint64(klsposs.Audit(sharesSposs[0]).Size()),
 				}
 
 				// WARMUP: do a trial run as a warmup
-				for trial := 0; trial < numTrials; trial++ {
+				for trial := 0; trial < cfg.Warmup; trial++ {
 					benchmarkBaselineFSS(baselineDPFKey, fssDomain, FSSRange)
 					benchmarkBaselineFSS(baselineVDPFKey, fssDomain, FSSRange)
 					benchmarkPACLPublicKeyFSS(baselineDPFKey, klpk, sharesPk[0], fssDomain, FSSRange)
@@ -195,6 +195,21 @@ func main() {
 					experiment.RangeVDPFSKPACLProcessing = append(experiment.RangeVDPFSKPACLProcessing, timeRange)
 				}
 
+				// client-side proof generation
+				for trial := 0; trial < numTrials; trial++ {
+					start := time.Now()
+					klpk.NewProof(idxpk, xpk)
+					experiment.ClientDPFPACLProof = append(experiment.ClientDPFPACLProof, time.Since(start).Microseconds())
+
+					start = time.Now()
+					klsk.NewProof(idxsk, xsk)
+					experiment.ClientDPFSKPACLProof = append(experiment.ClientDPFSKPACLProof, time.Since(start).Microseconds())
+
+					start = time.Now()
+					klsposs.NewProof(idxsposs, xsposs)
+					experiment.ClientVDPFPACLProof = append(experiment.ClientVDPFPACLProof, time.Since(start).Microseconds())
+				}
+
 				fmt.Println("---------------------------------")
 				fmt.Printf("FSS domain:     %v\n", fssDomain)
 				fmt.Printf("Num keys:       %v\n", numKeys)
@@ -215,17 +230,20 @@ func main() {
 				fmt.Printf("VDPF SK PACL (a < x < b) (size %v): %v\n", fssDomain, avg(experiment.RangeVDPFSKPACLProcessing))
 				fmt.Printf("VDPF PACL (a < x < b)    (size %v): %v\n", fssDomain, avg(experiment.RangeVDPFPACLProcessing))
 				fmt.Println("---------------------------------")
+				fmt.Printf("Client DPF SK-PACL  (proof %v B, audit %v B): %v\n",
+					experiment.DPFSKPACLProofSize, experiment.DPFSKPACLAuditSize, avg(experiment.ClientDPFSKPACLProof))
+				fmt.Printf("Client DPF PACL     (proof %v B, audit %v B): %v\n",
+					experiment.DPFPACLProofSize, experiment.DPFPACLAuditSize, avg(experiment.ClientDPFPACLProof))
+				fmt.Printf("Client VDPF PACL    (proof %v B, audit %v B): %v\n",
+					experiment.VDPFPACLProofSize, experiment.VDPFPACLAuditSize, avg(experiment.ClientVDPFPACLProof))
+				fmt.Println("---------------------------------")
 
-				experimentJSON, err := json.MarshalIndent(experiment, "", " ")
-				if err != nil {
-					panic(err)
-				}
-				ioutil.WriteFile("experiment"+fmt.Sprint(i)+".json", experimentJSON, 0644)
-				fmt.Println("File saved.")
-				i++
+				experiments = append(experiments, experiment)
 			}
 		}
 	}
+
+	return experiments
 }
 
 func avg(arr []int64) float64 {
@@ -353,16 +371,16 @@ func benchmarkPACLSymmetricKeyVFSS(
 
 	// make a bunch of random symmetric keys
 	// bits is suppposed to si
-	slots := make([]*paclsk.Slot, kl.NumKeys)
+	slots := make([]*slot.Slot, kl.NumKeys)
 	for i := 0; i < len(slots); i++ {
-		slots[i] = paclsk.NewRandomSlot(16) // symmetric key is 16 bytes
+		slots[i] = slot.NewRandom(16) // symmetric key is 16 bytes
 	}
 
 	start = time.Now()
-	accumulator := paclsk.NewEmptySlot(16)
+	accumulator := slot.NewEmpty(16)
 	for i := 0; i < len(slots); i++ {
 		if bits[i]%2 == 1 {
-			paclsk.XorSlots(accumulator, slots[i])
+			slot.Xor(accumulator, slots[i])
 		}
 	}
 	totalTime += time.Since(start).Microseconds()
@@ -411,7 +429,7 @@ func expandBaselineDPF(key *BaselineKey) {
 func randomizeDPFKey(dpfKey *dpf.DPFKey) *dpf.DPFKey {
 	// super hacky way to generate a random gibberish DPF key
 	// but it's sufficient for accurate benchmarks
-	// see ../vdpf/wrapper.go
+	// see ../../vdpf/wrapper.go
 	keySize := 18*dpfKey.RangeSize + 18 + 16 + 16*4
 	r := make([]byte, keySize)
 	_, _ = rand.Read(r)
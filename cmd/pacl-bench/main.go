@@ -0,0 +1,102 @@
+// Command pacl-bench runs the benchmarks of the paper:
+//
+//	fss   DPF-PACLs and VDPF-PACLs
+//	pir   PIR with (and without) PACLs
+//	anon  Spectrum & Express with (and without) PACLs
+//	auth  anonymous authentication using VDPF-PACLs
+//
+// Parameters default to those used in the paper and can be overridden with
+// a JSON config file (see Config) and flags, in that order. For example,
+//
+//	pacl-bench -quick -scenarios fss,pir -format json,csv -out results/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+func main() {
+
+	configPath := flag.String("config", "", "JSON config file (overrides the defaults)")
+	quick := flag.Bool("quick", false, "start from small CI-sized parameters instead of the paper's")
+	scenarios := flag.String("scenarios", "", "comma-separated scenarios to run ("+strings.Join(Scenarios, ", ")+")")
+	out := flag.String("out", "", "output directory")
+	format := flag.String("format", "", "comma-separated output formats (json, csv)")
+	trials := flag.Int("trials", -1, "number of trials of every configuration")
+	warmup := flag.Int("warmup", -1, "number of untimed warmup trials of every configuration")
+	flag.Parse()
+
+	cfg := DefaultConfig()
+	if *quick {
+		cfg = QuickConfig()
+	}
+
+	if *configPath != "" {
+		if err := cfg.LoadConfig(*configPath); err != nil {
+			fail(err)
+		}
+	}
+
+	if *scenarios != "" {
+		cfg.Scenarios = strings.Split(*scenarios, ",")
+	}
+
+	if *out != "" {
+		cfg.OutDir = *out
+	}
+
+	if *format != "" {
+		cfg.Formats = strings.Split(*format, ",")
+	}
+
+	if *trials >= 0 {
+		cfg.SetTrials(*trials)
+	}
+
+	if *warmup >= 0 {
+		cfg.SetWarmup(*warmup)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		fail(err)
+	}
+
+	if err := run(cfg); err != nil {
+		fail(err)
+	}
+}
+
+func run(cfg *Config) error {
+
+	for _, scenario := range Scenarios {
+		if !cfg.Runs(scenario) {
+			continue
+		}
+
+		var experiments interface{}
+		switch scenario {
+		case "fss":
+			experiments = runFSS(&cfg.FSS)
+		case "pir":
+			experiments = runPIR(&cfg.PIR)
+		case "anon":
+			experiments = runAnon(&cfg.Anon)
+		case "auth":
+			experiments = runAuth(&cfg.Auth)
+		}
+
+		if err := WriteResults(cfg.OutDir, cfg.Formats, scenario, experiments); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "pacl-bench:", err)
+	os.Exit(1)
+}
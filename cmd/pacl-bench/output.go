@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// WriteResults writes the experiments of the scenario (a slice of experiments)
+// to <dir>/<scenario>.json and/or <dir>/<scenario>.csv.
+//
+// The JSON file is an array of experiments, which is the format of the files
+// in paper_results/. The CSV file has one row per trial: the parameters of
+// an experiment (scalar fields) followed by the measurement of every metric
+// (slice fields) in that trial; the header uses the JSON field names.
+func WriteResults(dir string, formats []string, scenario string, experiments interface{}) error {
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	for _, format := range formats {
+		path := filepath.Join(dir, scenario+"."+format)
+
+		var err error
+		switch format {
+		case "json":
+			err = writeJSON(path, experiments)
+		case "csv":
+			err = writeCSV(path, experiments)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Results saved to %v\n", path)
+	}
+
+	return nil
+}
+
+func writeJSON(path string, experiments interface{}) error {
+	data, err := json.MarshalIndent(experiments, "", " ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+func writeCSV(path string, experiments interface{}) error {
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	w := csv.NewWriter(file)
+
+	list := reflect.ValueOf(experiments)
+	if list.Len() == 0 {
+		w.Flush()
+		return w.Error()
+	}
+
+	typ := list.Index(0).Elem().Type()
+
+	var params, metrics []int
+	header := []string{}
+	for i := 0; i < typ.NumField(); i++ {
+		if typ.Field(i).Type.Kind() != reflect.Slice {
+			params = append(params, i)
+			header = append(header, fieldName(typ.Field(i)))
+		}
+	}
+
+	header = append(header, "trial")
+	for i := 0; i < typ.NumField(); i++ {
+		if typ.Field(i).Type.Kind() == reflect.Slice {
+			metrics = append(metrics, i)
+			header = append(header, fieldName(typ.Field(i)))
+		}
+	}
+
+	if err := w.Write(header); err != nil {
+		return err
+	}
+
+	for e := 0; e < list.Len(); e++ {
+		experiment := list.Index(e).Elem()
+
+		numTrials := 0
+		for _, m := range metrics {
+			if n := experiment.Field(m).Len(); n > numTrials {
+				numTrials = n
+			}
+		}
+
+		for trial := 0; trial < numTrials; trial++ {
+			row := []string{}
+			for _, p := range params {
+				row = append(row, fmt.Sprint(experiment.Field(p).Interface()))
+			}
+
+			row = append(row, fmt.Sprint(trial))
+			for _, m := range metrics {
+				if trial < experiment.Field(m).Len() {
+					row = append(row, fmt.Sprint(experiment.Field(m).Index(trial).Interface()))
+				} else {
+					row = append(row, "")
+				}
+			}
+
+			if err := w.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+
+	w.Flush()
+	return w.Error()
+}
+
+func fieldName(f reflect.StructField) string {
+	if tag := f.Tag.Get("json"); tag != "" {
+		return strings.Split(tag, ",")[0]
+	}
+
+	return f.Name
+}
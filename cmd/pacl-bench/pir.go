@@ -1,30 +1,27 @@
 package main
 
 import (
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"math"
 	"time"
 
 	paclsk "github.com/sachaservan/pacl/pacl-sk"
+	"github.com/sachaservan/pacl/slot"
 	dpf "github.com/sachaservan/vdpf"
 )
 
-func main() {
+func runPIR(cfg *PIRConfig) []*PIRExperiment {
 
-	n := []int{16384, 32768, 65536, 131072, 262144, 524288, 1048576, 2097152, 4194304}
-	byteParams := []int{1024, 2048}
-	numTrials := 10
+	numTrials := cfg.Trials.Trials
+	experiments := make([]*PIRExperiment, 0)
 
-	i := 0
-	for _, dbSize := range n {
-		for _, slotSize := range byteParams {
-			slots := make([]*Slot, dbSize)
+	for _, dbSize := range cfg.DBSizes {
+		for _, slotSize := range cfg.ItemSizes {
+			slots := make([]*slot.Slot, dbSize)
 			for i := 0; i < dbSize; i++ {
-				slots[i] = NewRandomSlot(slotSize)
+				slots[i] = slot.NewRandom(slotSize)
 			}
-			experiment := &Experiment{
+			experiment := &PIRExperiment{
 				DBSize:   uint64(dbSize),
 				ItemSize: uint64(slotSize),
 			}
@@ -33,6 +30,13 @@ func main() {
 			experiment.ServerPIRKeywordProcessingMS = make([]int64, 0)
 			experiment.ServerPIRPACLProcessingMS = make([]int64, 0)
 
+			for trial := 0; trial < cfg.Warmup; trial++ {
+				_, bits := benchmarkPIR(dbSize, slots)
+				benchmarkPIRPACL(dbSize, slots, bits)
+				benchmarkXor(dbSize, slots, bits)
+				benchmarkPIRKeywords(dbSize, slots)
+			}
+
 			for trial := 0; trial < numTrials; trial++ {
 				pirTimeMS, bits := benchmarkPIR(dbSize, slots)
 				pirPACLTimeMS := benchmarkPIRPACL(dbSize, slots, bits) // re-use expanded bits to avoid double counting DPF time
@@ -50,32 +54,27 @@ func main() {
 			fmt.Printf("PIR Keyword   (%v bytes per item with %v item DB): %v ms\n", slotSize, dbSize, experiment.ServerPIRKeywordProcessingMS[0])
 			fmt.Printf("PIRPACL       (%v bytes per item with %v item DB): %v ms\n", slotSize, dbSize, experiment.ServerPIRPACLProcessingMS[0])
 
-			experimentJSON, err := json.MarshalIndent(experiment, "", " ")
-			if err != nil {
-				panic(err)
-			}
-			ioutil.WriteFile("experiment"+fmt.Sprint(i)+".json", experimentJSON, 0644)
-			fmt.Println("File saved.")
-			i++
+			experiments = append(experiments, experiment)
 		}
 	}
 
+	return experiments
 }
 
-func benchmarkXor(dbSize int, slots []*Slot, bits []byte) int64 {
+func benchmarkXor(dbSize int, slots []*slot.Slot, bits []byte) int64 {
 
 	start := time.Now()
-	accumulator := NewEmptySlot(len(slots[0].Data))
+	accumulator := slot.NewEmpty(len(slots[0].Data))
 	for i := 0; i < len(slots); i++ {
 		if bits[i]%2 == 1 {
-			XorSlots(accumulator, slots[i])
+			slot.Xor(accumulator, slots[i])
 		}
 	}
 
 	return time.Since(start).Milliseconds()
 }
 
-func benchmarkPIR(dbSize int, slots []*Slot) (int64, []byte) {
+func benchmarkPIR(dbSize int, slots []*slot.Slot) (int64, []byte) {
 	prfKey := dpf.GeneratePRFKey()
 	client := dpf.ClientDPFInitialize(prfKey)
 	bits := uint(math.Ceil(math.Log2(float64(dbSize))))
@@ -86,17 +85,17 @@ func benchmarkPIR(dbSize int, slots []*Slot) (int64, []byte) {
 
 	shares := server.FullDomainEval(keyA)
 
-	accumulator := NewEmptySlot(len(slots[0].Data))
+	accumulator := slot.NewEmpty(len(slots[0].Data))
 	for i := 0; i < len(slots); i++ {
 		if shares[i]%2 == 1 {
-			XorSlots(accumulator, slots[i])
+			slot.Xor(accumulator, slots[i])
 		}
 	}
 
 	return time.Since(start).Milliseconds(), shares
 }
 
-func benchmarkPIRKeywords(dbSize int, slots []*Slot) int64 {
+func benchmarkPIRKeywords(dbSize int, slots []*slot.Slot) int64 {
 	prfKey := dpf.GeneratePRFKey()
 	client := dpf.ClientDPFInitialize(prfKey)
 
@@ -115,24 +114,24 @@ func benchmarkPIRKeywords(dbSize int, slots []*Slot) int64 {
 
 	shares := server.BatchEval(keyA, x)
 
-	accumulator := NewEmptySlot(len(slots[0].Data))
+	accumulator := slot.NewEmpty(len(slots[0].Data))
 	for i := 0; i < len(slots); i++ {
 		if shares[i]%2 == 1 {
-			XorSlots(accumulator, slots[i])
+			slot.Xor(accumulator, slots[i])
 		}
 	}
 
 	return time.Since(start).Milliseconds()
 }
 
-func benchmarkPIRPACL(dbSize int, slots []*Slot, bits []byte) int64 {
+func benchmarkPIRPACL(dbSize int, slots []*slot.Slot, bits []byte) int64 {
 	// we can resuse the DPF expansion performed in
 	// cloak for PIR so only measure the xor time
 	start := time.Now()
-	accumulator := NewEmptySlot(len(slots[0].Data))
+	accumulator := slot.NewEmpty(len(slots[0].Data))
 	for i := 0; i < len(slots); i++ {
 		if bits[i]%2 == 1 {
-			XorSlots(accumulator, slots[i])
+			slot.Xor(accumulator, slots[i])
 		}
 	}
 	xortime := time.Since(start).Milliseconds()
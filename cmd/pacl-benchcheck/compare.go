@@ -0,0 +1,213 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// An experiment file (as written by pacl-bench and stored in paper_results/)
+// is an array of experiments. Fields ending in _us or _ms are metrics, either
+// a single measurement or one measurement per trial; fields ending in _bytes
+// are message sizes and are ignored; every other field is a parameter and the
+// parameters together identify the configuration.
+
+// Experiment is one configuration of an experiment file with every metric
+// normalized to microseconds and summarized by its median across trials
+type Experiment struct {
+	Config  string             // e.g., "db_size=1024 item_size=64"
+	Metrics map[string]float64 // metric name (without the unit) -> median in microseconds
+}
+
+// Comparison is the comparison of a metric of one configuration
+type Comparison struct {
+	Config     string
+	Metric     string
+	Reference  float64 // microseconds
+	Current    float64 // microseconds
+	Ratio      float64 // current / reference (NaN if the reference is zero)
+	Regression bool
+}
+
+// LoadExperiments reads an experiment file
+func LoadExperiments(path string) ([]*Experiment, error) {
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw []map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("%v: %v", path, err)
+	}
+
+	experiments := make([]*Experiment, len(raw))
+	for i, fields := range raw {
+		experiments[i], err = parseExperiment(fields)
+		if err != nil {
+			return nil, fmt.Errorf("%v: experiment %v: %v", path, i, err)
+		}
+	}
+
+	return experiments, nil
+}
+
+func parseExperiment(fields map[string]interface{}) (*Experiment, error) {
+
+	e := &Experiment{Metrics: make(map[string]float64)}
+	params := []string{}
+
+	for name, value := range fields {
+		if strings.HasSuffix(name, "_bytes") {
+			continue
+		}
+
+		scale, metric := unit(name)
+		if scale == 0 {
+			params = append(params, name+"="+formatParam(value))
+			continue
+		}
+
+		values := []float64{}
+		switch v := value.(type) {
+		case float64:
+			values = append(values, v)
+		case []interface{}:
+			for _, x := range v {
+				f, ok := x.(float64)
+				if !ok {
+					return nil, fmt.Errorf("%v is not a list of numbers", name)
+				}
+				values = append(values, f)
+			}
+		default:
+			return nil, fmt.Errorf("%v is not a number or a list of numbers", name)
+		}
+
+		if len(values) > 0 {
+			e.Metrics[metric] = median(values) * scale
+		}
+	}
+
+	sort.Strings(params)
+	e.Config = strings.Join(params, " ")
+
+	return e, nil
+}
+
+func formatParam(value interface{}) string {
+	if v, ok := value.(float64); ok {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+
+	return fmt.Sprint(value)
+}
+
+// returns the factor that converts the field to microseconds and the name of
+// the field without its unit (or zero if the field is not a metric)
+func unit(name string) (float64, string) {
+	switch {
+	case strings.HasSuffix(name, "_us"):
+		return 1, strings.TrimSuffix(name, "_us")
+	case strings.HasSuffix(name, "_ms"):
+		return 1000, strings.TrimSuffix(name, "_ms")
+	default:
+		return 0, ""
+	}
+}
+
+func median(values []float64) float64 {
+	sorted := append([]float64{}, values...)
+	sort.Float64s(sorted)
+
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+// Compare compares every metric of every configuration present in both
+// the reference and current experiments. A metric regresses if it is more
+// than threshold (e.g., 0.1 for 10%) slower than the reference, unless both
+// measurements are below floor microseconds (where timings are mostly noise).
+// Also returns the configurations that are only in the reference and only in the current experiments.
+func Compare(reference, current []*Experiment, threshold, floor float64) ([]*Comparison, []string, []string) {
+
+	cur := make(map[string]*Experiment)
+	for _, e := range current {
+		cur[e.Config] = e
+	}
+
+	comparisons := []*Comparison{}
+	onlyReference := []string{}
+	onlyCurrent := []string{}
+	seen := make(map[string]bool)
+
+	for _, ref := range reference {
+		seen[ref.Config] = true
+
+		c, ok := cur[ref.Config]
+		if !ok {
+			onlyReference = append(onlyReference, ref.Config)
+			continue
+		}
+
+		for _, metric := range sortedKeys(ref.Metrics) {
+			value, ok := c.Metrics[metric]
+			if !ok {
+				continue
+			}
+
+			cmp := &Comparison{
+				Config:    ref.Config,
+				Metric:    metric,
+				Reference: ref.Metrics[metric],
+				Current:   value,
+				Ratio:     math.NaN(),
+			}
+
+			if cmp.Reference > 0 {
+				cmp.Ratio = cmp.Current / cmp.Reference
+			}
+
+			aboveFloor := cmp.Reference >= floor || cmp.Current >= floor
+			if aboveFloor && cmp.Current > cmp.Reference*(1+threshold) {
+				cmp.Regression = true
+			}
+
+			comparisons = append(comparisons, cmp)
+		}
+	}
+
+	for _, e := range current {
+		if !seen[e.Config] {
+			onlyCurrent = append(onlyCurrent, e.Config)
+		}
+	}
+
+	sort.SliceStable(comparisons, func(i, j int) bool {
+		return comparisons[i].Config < comparisons[j].Config
+	})
+
+	sort.Strings(onlyReference)
+	sort.Strings(onlyCurrent)
+
+	return comparisons, onlyReference, onlyCurrent
+}
+
+func sortedKeys(m map[string]float64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeExperiments(t *testing.T, dir, scenario, data string) {
+	if err := ioutil.WriteFile(filepath.Join(dir, scenario+".json"), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCompare(t *testing.T) {
+
+	refDir := t.TempDir()
+	curDir := t.TempDir()
+
+	writeExperiments(t, refDir, "pir", `[
+		{"db_size": 1048576, "item_size": 1024, "server_pir_processing_ms": [10, 11, 12], "server_pir_pacl_processing_ms": [20, 20, 20]},
+		{"db_size": 2048, "item_size": 1024, "server_pir_processing_ms": [1, 1, 1]}
+	]`)
+
+	// the PIR time regressed by 50%, the PACL time improved, and there is an extra metric
+	writeExperiments(t, curDir, "pir", `[
+		{"db_size": 1048576, "item_size": 1024, "server_pir_processing_ms": [17, 18, 19], "server_pir_pacl_processing_ms": [10],
+		 "server_xor_processing_ms": [1]},
+		{"db_size": 4096, "item_size": 1024, "server_pir_processing_ms": [2]}
+	]`)
+
+	reports, err := compareAll(curDir, refDir, Scenarios, 0.1, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(reports) != 1 || reports[0].Scenario != "pir" {
+		t.Fatalf("Expected a single pir report, got %v", reports)
+	}
+
+	r := reports[0]
+	if len(r.Comparisons) != 2 || r.NumRegressions() != 1 {
+		t.Fatalf("Expected 2 comparisons with 1 regression, got %v comparisons with %v regressions",
+			len(r.Comparisons), r.NumRegressions())
+	}
+
+	for _, c := range r.Comparisons {
+		if c.Config != "db_size=1048576 item_size=1024" {
+			t.Fatalf("Wrong configuration %q", c.Config)
+		}
+
+		switch c.Metric {
+		case "server_pir_processing":
+			if c.Reference != 11000 || c.Current != 18000 || !c.Regression {
+				t.Fatalf("Wrong comparison: %+v", c)
+			}
+		case "server_pir_pacl_processing":
+			if c.Ratio != 0.5 || c.Regression {
+				t.Fatalf("Wrong comparison: %+v", c)
+			}
+		default:
+			t.Fatalf("Unexpected metric %v", c.Metric)
+		}
+	}
+
+	if len(r.OnlyReference) != 1 || len(r.OnlyCurrent) != 1 {
+		t.Fatalf("Wrong unmatched configurations: %v and %v", r.OnlyReference, r.OnlyCurrent)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteReport(&buf, reports, 0.1); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(buf.String(), "1 regression(s)") || !strings.Contains(buf.String(), "| 1.64x | ⚠️ regression |") {
+		t.Fatalf("Wrong report:\n%v", buf.String())
+	}
+}
+
+func TestUnits(t *testing.T) {
+
+	e, err := parseExperiment(map[string]interface{}{
+		"num_keys":     float64(16),
+		"group_exp_us": float64(102),
+		"auth_ms":      []interface{}{float64(1), float64(3)},
+		"proof_bytes":  float64(1024),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if e.Config != "num_keys=16" || e.Metrics["group_exp"] != 102 || e.Metrics["auth"] != 2000 {
+		t.Fatalf("Wrong experiment: %+v", e)
+	}
+}
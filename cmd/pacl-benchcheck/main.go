@@ -0,0 +1,97 @@
+// Command pacl-benchcheck compares the output of pacl-bench to reference
+// results (by default the ones in paper_results/) and writes a Markdown
+// report of the ratio of every metric of every configuration in both.
+// It exits with status 1 if any metric regressed beyond the threshold.
+//
+//	pacl-benchcheck -current results/ -reference paper_results/ -threshold 0.1 -out report.md
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Scenarios whose experiment files (<scenario>.json) are compared
+var Scenarios = []string{"fss", "pir", "anon", "auth"}
+
+func main() {
+
+	current := flag.String("current", ".", "directory with the pacl-bench output")
+	reference := flag.String("reference", "paper_results", "directory with the reference results")
+	scenarios := flag.String("scenarios", strings.Join(Scenarios, ","), "comma-separated scenarios to compare")
+	threshold := flag.Float64("threshold", 0.1, "relative slowdown flagged as a regression (0.1 = 10%)")
+	floor := flag.Float64("floor", 1000, "measurements below this many microseconds are never flagged")
+	out := flag.String("out", "", "output Markdown file (default stdout)")
+	flag.Parse()
+
+	reports, err := compareAll(*current, *reference, strings.Split(*scenarios, ","), *threshold, *floor)
+	if err != nil {
+		fail(err)
+	}
+
+	if err := writeReport(*out, reports, *threshold); err != nil {
+		fail(err)
+	}
+
+	for _, r := range reports {
+		if r.NumRegressions() > 0 {
+			os.Exit(1)
+		}
+	}
+}
+
+// compares the experiment files of every scenario that was run
+func compareAll(current, reference string, scenarios []string, threshold, floor float64) ([]*Report, error) {
+
+	reports := []*Report{}
+	for _, scenario := range scenarios {
+		curPath := filepath.Join(current, scenario+".json")
+		if _, err := os.Stat(curPath); os.IsNotExist(err) {
+			continue // scenario was not run
+		}
+
+		cur, err := LoadExperiments(curPath)
+		if err != nil {
+			return nil, err
+		}
+
+		ref, err := LoadExperiments(filepath.Join(reference, scenario+".json"))
+		if err != nil {
+			return nil, err
+		}
+
+		r := &Report{Scenario: scenario}
+		r.Comparisons, r.OnlyReference, r.OnlyCurrent = Compare(ref, cur, threshold, floor)
+		reports = append(reports, r)
+	}
+
+	if len(reports) == 0 {
+		return nil, fmt.Errorf("no results found in %v", current)
+	}
+
+	return reports, nil
+}
+
+func writeReport(path string, reports []*Report, threshold float64) error {
+
+	var w io.Writer = os.Stdout
+	if path != "" {
+		file, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+
+	return WriteReport(w, reports, threshold)
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "pacl-benchcheck:", err)
+	os.Exit(2)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+)
+
+// Report holds the comparisons of one scenario
+type Report struct {
+	Scenario      string
+	Comparisons   []*Comparison
+	OnlyReference []string // configurations without current results
+	OnlyCurrent   []string // configurations without reference results
+}
+
+func (r *Report) NumRegressions() int {
+	n := 0
+	for _, c := range r.Comparisons {
+		if c.Regression {
+			n++
+		}
+	}
+
+	return n
+}
+
+// WriteReport writes the reports as Markdown
+func WriteReport(w io.Writer, reports []*Report, threshold float64) error {
+
+	total := 0
+	for _, r := range reports {
+		total += r.NumRegressions()
+	}
+
+	fmt.Fprintf(w, "# Benchmark comparison\n\n")
+	if total == 0 {
+		fmt.Fprintf(w, "No regressions beyond %.0f%%.\n\n", threshold*100)
+	} else {
+		fmt.Fprintf(w, "**%v regression(s) beyond %.0f%%.**\n\n", total, threshold*100)
+	}
+
+	for _, r := range reports {
+		fmt.Fprintf(w, "## %v\n\n", r.Scenario)
+
+		if len(r.Comparisons) == 0 {
+			fmt.Fprintf(w, "No configurations in common with the reference.\n\n")
+		} else {
+			fmt.Fprintf(w, "| Configuration | Metric | Reference | Current | Ratio | |\n")
+			fmt.Fprintf(w, "| :--- | :--- | ---: | ---: | ---: | :---: |\n")
+			for _, c := range r.Comparisons {
+				status := ""
+				if c.Regression {
+					status = "⚠️ regression"
+				}
+
+				fmt.Fprintf(w, "| %v | %v | %v | %v | %v | %v |\n",
+					c.Config, c.Metric, formatTime(c.Reference), formatTime(c.Current), formatRatio(c.Ratio), status)
+			}
+			fmt.Fprintln(w)
+		}
+
+		if len(r.OnlyReference) > 0 {
+			fmt.Fprintf(w, "%v reference configuration(s) were not run.\n\n", len(r.OnlyReference))
+		}
+
+		for _, config := range r.OnlyCurrent {
+			fmt.Fprintf(w, "- no reference results for `%v`\n", config)
+		}
+
+		if len(r.OnlyCurrent) > 0 {
+			fmt.Fprintln(w)
+		}
+	}
+
+	return nil
+}
+
+// formats a duration given in microseconds
+func formatTime(us float64) string {
+	switch {
+	case us >= 1e6:
+		return fmt.Sprintf("%.2f s", us/1e6)
+	case us >= 1e3:
+		return fmt.Sprintf("%.2f ms", us/1e3)
+	default:
+		return fmt.Sprintf("%.0f µs", us)
+	}
+}
+
+func formatRatio(ratio float64) string {
+	if math.IsNaN(ratio) {
+		return "n/a"
+	}
+
+	return fmt.Sprintf("%.2fx", ratio)
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"crypto/elliptic"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+
+	"github.com/sachaservan/pacl/algebra"
+	"github.com/sachaservan/pacl/ec"
+	paclpk "github.com/sachaservan/pacl/pacl-pk"
+	paclsk "github.com/sachaservan/pacl/pacl-sk"
+	paclsposs "github.com/sachaservan/pacl/pacl-sposs"
+	"github.com/sachaservan/pacl/slot"
+	"github.com/sachaservan/pacl/sposs"
+	dpf "github.com/sachaservan/vdpf"
+)
+
+// supported PACL schemes
+const (
+	SchemeSK    = "sk"
+	SchemePK    = "pk"
+	SchemeSPoSS = "sposs"
+)
+
+// KeyFile holds a user key with its values in the binary encodings of the
+// wire format (see algebra.Field.Encode, algebra.Group.Encode and
+// ec.EC.Encode). Public key files are the same but without Key; sk keys have
+// no public part (the verifiers hold the keys themselves) so there is no
+// public key file for them.
+type KeyFile struct {
+	Scheme    string
+	Key       []byte `json:",omitempty"` // sk: the 128-bit key; pk and sposs: the encoding of the secret key x
+	PublicKey []byte `json:",omitempty"` // pk and sposs: the encoding of the public key g^x
+	Params    string `json:",omitempty"` // sposs: the parameter set ("" for the default)
+}
+
+// KeyListFile holds the list of keys audited by the verifiers
+// (which for sk are the secret keys themselves)
+type KeyListFile struct {
+	Scheme     string
+	FSSDomain  uint
+	KeyIndices []uint64
+	HashKeys   [2]dpf.HashKey // sposs: VDPF hash keys
+	Keys       [][]byte       `json:",omitempty"` // encodings of the keys (as in KeyFile); not in the parameters file (see Public)
+	Params     string         `json:",omitempty"` // sposs: the parameter set ("" for the default)
+}
+
+// ProofFile holds the proof share of one verifier
+// (encoded with EncodeProof of the scheme's key list)
+type ProofFile struct {
+	Scheme string
+	Proof  []byte
+}
+
+// AuditFile holds the audit share of one verifier
+// (encoded with EncodeAudit of the scheme's key list)
+type AuditFile struct {
+	Scheme      string
+	ShareNumber uint
+	Audit       []byte
+}
+
+func readFile(path string, v interface{}) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%v: %v", path, err)
+	}
+
+	return nil
+}
+
+func writeFile(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", " ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, data, 0600)
+}
+
+func checkScheme(scheme string) error {
+	switch scheme {
+	case SchemeSK, SchemePK, SchemeSPoSS:
+		return nil
+	default:
+		return fmt.Errorf("unknown scheme %q (expected %v, %v or %v)", scheme, SchemeSK, SchemePK, SchemeSPoSS)
+	}
+}
+
+// identifier of the sposs parameter set of a file ("" for the default)
+func paramSet(id string) string {
+	if id == "" {
+		return paclsposs.DefaultParamSet
+	}
+
+	return id
+}
+
+func curve() *ec.EC {
+	c := elliptic.P256()
+	return &ec.EC{Curve: c, Field: algebra.NewField(c.Params().N)}
+}
+
+// Public returns the public part of the key
+func (f *KeyFile) Public() (*KeyFile, error) {
+	if f.Scheme == SchemeSK {
+		return nil, errors.New("sk keys have no public part")
+	}
+
+	return &KeyFile{Scheme: f.Scheme, PublicKey: f.PublicKey, Params: f.Params}, nil
+}
+
+func (f *KeyListFile) NumKeys() uint64 {
+	return uint64(len(f.KeyIndices))
+}
+
+// Add appends the key to the list, growing the FSS domain if needed
+// (the secret key for sk and the public key otherwise)
+func (f *KeyListFile) Add(key *KeyFile) error {
+
+	if key.Scheme != f.Scheme {
+		return fmt.Errorf("cannot add a %v key to a %v key list", key.Scheme, f.Scheme)
+	}
+
+	if len(f.Keys) != len(f.KeyIndices) {
+		return errors.New("cannot add keys to the parameters of a key list")
+	}
+
+	var encoded []byte
+	switch f.Scheme {
+	case SchemeSK:
+		if len(key.Key) != 128/8 {
+			return fmt.Errorf("malformed %v key", f.Scheme)
+		}
+		encoded = key.Key
+	case SchemePK:
+		if _, err := curve().Decode(key.PublicKey); err != nil {
+			return fmt.Errorf("malformed %v key: %v", f.Scheme, err)
+		}
+		encoded = key.PublicKey
+	case SchemeSPoSS:
+		if paramSet(key.Params) != paramSet(f.Params) {
+			return fmt.Errorf("cannot add a %v key to a %v key list", paramSet(key.Params), paramSet(f.Params))
+		}
+		group, err := paclsposs.NewGroup(paramSet(f.Params))
+		if err != nil {
+			return err
+		}
+		if _, err := group.Decode(key.PublicKey); err != nil {
+			return fmt.Errorf("malformed %v key: %v", f.Scheme, err)
+		}
+		encoded = key.PublicKey
+	default:
+		return checkScheme(f.Scheme)
+	}
+
+	f.Keys = append(f.Keys, encoded)
+	f.KeyIndices = append(f.KeyIndices, f.NumKeys())
+	if d := domainSize(f.NumKeys()); d > f.FSSDomain {
+		f.FSSDomain = d
+	}
+
+	return nil
+}
+
+// Public returns the parameters of the list without its keys, which is all
+// the prover needs (and keeps the keys of sk lists with the verifiers)
+func (f *KeyListFile) Public() *KeyListFile {
+	return &KeyListFile{Scheme: f.Scheme, FSSDomain: f.FSSDomain, KeyIndices: f.KeyIndices, HashKeys: f.HashKeys, Params: f.Params}
+}
+
+// SKKeyListParams returns the parameters of the list (without reading its keys)
+func (f *KeyListFile) SKKeyListParams() *paclsk.KeyListParams {
+	kl := &paclsk.KeyListParams{}
+	kl.NumKeys = f.NumKeys()
+	kl.FSSDomain = f.FSSDomain
+	kl.KeyIndices = f.KeyIndices
+	kl.PredicateType = paclsk.Equality
+	kl.FullDomain = (1<<kl.FSSDomain == kl.NumKeys) // only applies when domain = #keys
+
+	return kl
+}
+
+func (f *KeyListFile) SKKeyList() (*paclsk.KeyList, error) {
+	if len(f.Keys) != len(f.KeyIndices) {
+		return nil, errors.New("key list has a different number of keys and indices")
+	}
+
+	kl := &paclsk.KeyList{KeyListParams: *f.SKKeyListParams()}
+	kl.StatSecurity = 128
+	kl.Keys = make([]*slot.Slot, len(f.Keys))
+	for i, key := range f.Keys {
+		if len(key) != kl.StatSecurity/8 {
+			return nil, fmt.Errorf("malformed key %v", i)
+		}
+		kl.Keys[i] = slot.New(key)
+	}
+
+	return kl, nil
+}
+
+// PKKeyListParams returns the parameters of the list (without reading its keys)
+func (f *KeyListFile) PKKeyListParams() *paclpk.KeyListParams {
+	kl := &paclpk.KeyListParams{}
+	kl.NumKeys = f.NumKeys()
+	kl.FSSDomain = f.FSSDomain
+	kl.KeyIndices = f.KeyIndices
+	kl.PredicateType = paclpk.Equality
+	kl.FullDomain = (1<<kl.FSSDomain == kl.NumKeys) // only applies when domain = #keys
+	kl.Curve = curve()
+
+	return kl
+}
+
+func (f *KeyListFile) PKKeyList() (*paclpk.KeyList, error) {
+	if len(f.Keys) != len(f.KeyIndices) {
+		return nil, errors.New("key list has a different number of keys and indices")
+	}
+
+	kl := &paclpk.KeyList{KeyListParams: *f.PKKeyListParams()}
+	kl.PublicKeys = make([]*ec.Point, len(f.Keys))
+	for i, key := range f.Keys {
+		point, err := kl.Curve.Decode(key)
+		if err != nil {
+			return nil, fmt.Errorf("malformed key %v: %v", i, err)
+		}
+		kl.PublicKeys[i] = point
+	}
+
+	return kl, nil
+}
+
+// SPoSSKeyListParams returns the parameters of the list over the group of
+// its parameter set (without reading its keys)
+func (f *KeyListFile) SPoSSKeyListParams() (*paclsposs.KeyListParams, error) {
+	group, err := paclsposs.NewGroup(paramSet(f.Params))
+	if err != nil {
+		return nil, err
+	}
+
+	kl := &paclsposs.KeyListParams{}
+	kl.NumKeys = f.NumKeys()
+	kl.FSSDomain = f.FSSDomain
+	kl.KeyIndices = f.KeyIndices
+	kl.PredicateType = paclsposs.Equality
+	kl.FullDomain = (1<<kl.FSSDomain == kl.NumKeys) // only applies when domain = #keys
+	kl.Group = group
+	kl.Field = group.Field
+	kl.ParamSet = paramSet(f.Params)
+	kl.HKey1 = f.HashKeys[0]
+	kl.HKey2 = f.HashKeys[1]
+	kl.ProofPP = sposs.NewPublicParams(group)
+
+	return kl, nil
+}
+
+// SPoSSKeyList returns the key list over the group of its parameter set
+// (which both verifiers check with Validate)
+func (f *KeyListFile) SPoSSKeyList() (*paclsposs.KeyList, error) {
+	if len(f.Keys) != len(f.KeyIndices) {
+		return nil, errors.New("key list has a different number of keys and indices")
+	}
+
+	params, err := f.SPoSSKeyListParams()
+	if err != nil {
+		return nil, err
+	}
+
+	kl := &paclsposs.KeyList{KeyListParams: *params}
+	kl.PublicKeys = make([]*algebra.GroupElement, len(f.Keys))
+	for i, key := range f.Keys {
+		if kl.PublicKeys[i], err = kl.Group.Decode(key); err != nil {
+			return nil, fmt.Errorf("malformed key %v: %v", i, err)
+		}
+	}
+
+	return kl, nil
+}
+
+// number of DPF input bits required to address every key
+func domainSize(numKeys uint64) uint {
+	if numKeys <= 2 {
+		return 1
+	}
+
+	return uint(math.Ceil(math.Log2(float64(numKeys))))
+}
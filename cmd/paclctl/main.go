@@ -0,0 +1,513 @@
+// Command paclctl generates keys, manages key lists, and runs the prover
+// and verifiers of the pk, sk and sposs PACL constructions on JSON files
+// (which carry the keys, proofs and audits in the binary encodings of the
+// wire format). sk keys have no public part: the verifiers build the key
+// list from the key files themselves, and the prover only gets the parameters
+// of the list (see keylist params).
+//
+//	paclctl keygen -scheme sk|pk|sposs [-params modp3072] -out alice
+//	paclctl keylist build -scheme sk|pk|sposs [-params modp3072] -out list.json alice.pub.json bob.pub.json ...
+//	paclctl keylist build -scheme sk -out list.json alice.key.json bob.key.json ...
+//	paclctl keylist add -list list.json carol.pub.json ...
+//	paclctl keylist params -list list.json -out params.json
+//	paclctl prove -list params.json -key alice.key.json -index 0 -out proof
+//	paclctl audit -list list.json -proof proof.0.json -out audit.0.json
+//	paclctl check -list list.json audit.0.json audit.1.json
+package main
+
+import (
+	"crypto/rand"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/sachaservan/pacl/algebra"
+	paclpk "github.com/sachaservan/pacl/pacl-pk"
+	paclsk "github.com/sachaservan/pacl/pacl-sk"
+	paclsposs "github.com/sachaservan/pacl/pacl-sposs"
+	"github.com/sachaservan/pacl/slot"
+	dpf "github.com/sachaservan/vdpf"
+)
+
+const usage = `usage: paclctl <command> [flags]
+
+commands:
+  keygen          generate a user key (writes <out>.key.json, and <out>.pub.json for pk and sposs)
+  keylist build   build a key list from public key files (key files for sk)
+  keylist add     add public key files (key files for sk) to a key list
+  keylist params  write the parameters of a key list without its keys (for the prover)
+  prove           write the proof shares of both verifiers (<out>.0.json and <out>.1.json)
+  audit           run one verifier's audit on a proof share
+  check           combine the audit shares of both verifiers
+
+run paclctl <command> -h for the flags of a command`
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "paclctl:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+
+	switch args[0] {
+	case "keygen":
+		return keygen(args[1:])
+	case "keylist":
+		if len(args) > 1 && args[1] == "build" {
+			return keylistBuild(args[2:])
+		}
+		if len(args) > 1 && args[1] == "add" {
+			return keylistAdd(args[2:])
+		}
+		if len(args) > 1 && args[1] == "params" {
+			return keylistParams(args[2:])
+		}
+		return errors.New("expected keylist build, keylist add or keylist params")
+	case "prove":
+		return prove(args[1:])
+	case "audit":
+		return audit(args[1:])
+	case "check":
+		return check(args[1:])
+	default:
+		return errors.New(usage)
+	}
+}
+
+func keygen(args []string) error {
+
+	fs := flag.NewFlagSet("keygen", flag.ContinueOnError)
+	scheme := fs.String("scheme", SchemeSK, "PACL scheme (sk, pk or sposs)")
+	params := fs.String("params", "", "sposs parameter set (default "+paclsposs.DefaultParamSet+")")
+	out := fs.String("out", "", "output file prefix")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *out == "" {
+		return errors.New("keygen: missing -out")
+	}
+
+	if err := checkScheme(*scheme); err != nil {
+		return err
+	}
+
+	key := &KeyFile{Scheme: *scheme}
+	switch *scheme {
+	case SchemeSK:
+		key.Key = slot.NewRandom(128 / 8).Data
+	case SchemePK:
+		c := curve()
+		_, x, err := c.RandomCurveScalar(rand.Reader)
+		if err != nil {
+			return err
+		}
+		if key.Key, err = c.Field.Encode(c.Field.NewElement(x)); err != nil {
+			return err
+		}
+		if key.PublicKey, err = c.Encode(c.ScalarMult(x)); err != nil {
+			return err
+		}
+	case SchemeSPoSS:
+		group, err := paclsposs.NewGroup(paramSet(*params))
+		if err != nil {
+			return err
+		}
+		key.Params = paramSet(*params)
+		field := algebra.NewField(group.Order)
+		x := field.RandomElement()
+		if key.Key, err = field.Encode(x); err != nil {
+			return err
+		}
+		if key.PublicKey, err = group.Encode(group.NewElement(x.Int)); err != nil {
+			return err
+		}
+	}
+
+	if err := writeFile(*out+".key.json", key); err != nil || *scheme == SchemeSK {
+		return err
+	}
+
+	pub, err := key.Public()
+	if err != nil {
+		return err
+	}
+
+	return writeFile(*out+".pub.json", pub)
+}
+
+func keylistBuild(args []string) error {
+
+	fs := flag.NewFlagSet("keylist build", flag.ContinueOnError)
+	scheme := fs.String("scheme", SchemeSK, "PACL scheme (sk, pk or sposs)")
+	out := fs.String("out", "", "output key list file")
+	domain := fs.Uint("domain", 0, "minimum FSS domain in bits (grows with the list if needed)")
+	params := fs.String("params", "", "sposs parameter set (default "+paclsposs.DefaultParamSet+")")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *out == "" {
+		return errors.New("keylist build: missing -out")
+	}
+
+	if err := checkScheme(*scheme); err != nil {
+		return err
+	}
+
+	list := &KeyListFile{Scheme: *scheme, FSSDomain: *domain, KeyIndices: make([]uint64, 0)}
+	if *scheme == SchemeSPoSS {
+		// the VDPF hash keys are chosen by the verifiers (i.e., whoever builds the list)
+		list.HashKeys = dpf.GenerateVDPFHashKeys()
+
+		if _, err := paclsposs.LookupParamSet(paramSet(*params)); err != nil {
+			return err
+		}
+		list.Params = paramSet(*params)
+	}
+
+	if err := addKeys(list, fs.Args()); err != nil {
+		return err
+	}
+
+	return writeFile(*out, list)
+}
+
+func keylistAdd(args []string) error {
+
+	fs := flag.NewFlagSet("keylist add", flag.ContinueOnError)
+	listPath := fs.String("list", "", "key list file (updated in place)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	list := &KeyListFile{}
+	if err := readFile(*listPath, list); err != nil {
+		return err
+	}
+
+	if err := addKeys(list, fs.Args()); err != nil {
+		return err
+	}
+
+	return writeFile(*listPath, list)
+}
+
+func keylistParams(args []string) error {
+
+	fs := flag.NewFlagSet("keylist params", flag.ContinueOnError)
+	listPath := fs.String("list", "", "key list file")
+	out := fs.String("out", "", "output parameters file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *out == "" {
+		return errors.New("keylist params: missing -out")
+	}
+
+	list := &KeyListFile{}
+	if err := readFile(*listPath, list); err != nil {
+		return err
+	}
+
+	return writeFile(*out, list.Public())
+}
+
+func addKeys(list *KeyListFile, paths []string) error {
+	for _, path := range paths {
+		key := &KeyFile{}
+		if err := readFile(path, key); err != nil {
+			return err
+		}
+
+		if err := list.Add(key); err != nil {
+			return fmt.Errorf("%v: %v", path, err)
+		}
+
+		fmt.Printf("%v: index %v\n", path, list.NumKeys()-1)
+	}
+
+	return nil
+}
+
+func prove(args []string) error {
+
+	fs := flag.NewFlagSet("prove", flag.ContinueOnError)
+	listPath := fs.String("list", "", "key list (or key list parameters) file")
+	keyPath := fs.String("key", "", "secret key file")
+	index := fs.Uint64("index", 0, "index of the key in the list")
+	out := fs.String("out", "", "output file prefix")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *out == "" {
+		return errors.New("prove: missing -out")
+	}
+
+	list := &KeyListFile{}
+	if err := readFile(*listPath, list); err != nil {
+		return err
+	}
+
+	key := &KeyFile{}
+	if err := readFile(*keyPath, key); err != nil {
+		return err
+	}
+
+	if key.Scheme != list.Scheme {
+		return fmt.Errorf("cannot prove with a %v key for a %v key list", key.Scheme, list.Scheme)
+	}
+
+	if *index >= list.NumKeys() {
+		return errors.New("index is out of range of the key list")
+	}
+
+	idx := list.KeyIndices[*index]
+	proofs := []*ProofFile{{Scheme: list.Scheme}, {Scheme: list.Scheme}}
+	switch list.Scheme {
+	case SchemeSK:
+		if len(key.Key) != 128/8 {
+			return errors.New("malformed sk key")
+		}
+		kl := list.SKKeyListParams()
+		for i, share := range kl.NewProof(idx, slot.New(key.Key)) {
+			var err error
+			if proofs[i].Proof, err = kl.EncodeProof(share); err != nil {
+				return err
+			}
+		}
+	case SchemePK:
+		if key.Key == nil {
+			return errors.New("not a secret key")
+		}
+		kl := list.PKKeyListParams()
+		x, err := kl.Curve.Field.Decode(key.Key)
+		if err != nil {
+			return fmt.Errorf("malformed pk key: %v", err)
+		}
+		for i, share := range kl.NewProof(idx, x) {
+			if proofs[i].Proof, err = kl.EncodeProof(share); err != nil {
+				return err
+			}
+		}
+	case SchemeSPoSS:
+		if key.Key == nil {
+			return errors.New("not a secret key")
+		}
+		if paramSet(key.Params) != paramSet(list.Params) {
+			return fmt.Errorf("%v key does not match the %v key list", paramSet(key.Params), paramSet(list.Params))
+		}
+		kl, err := list.SPoSSKeyListParams()
+		if err != nil {
+			return err
+		}
+		x, err := algebra.NewField(kl.Group.Order).Decode(key.Key)
+		if err != nil {
+			return fmt.Errorf("malformed sposs key: %v", err)
+		}
+		for i, share := range kl.NewProof(idx, x) {
+			if proofs[i].Proof, err = kl.EncodeProof(share); err != nil {
+				return err
+			}
+		}
+	default:
+		return checkScheme(list.Scheme)
+	}
+
+	for i, proof := range proofs {
+		if err := writeFile(fmt.Sprintf("%v.%v.json", *out, i), proof); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func audit(args []string) error {
+
+	fs := flag.NewFlagSet("audit", flag.ContinueOnError)
+	listPath := fs.String("list", "", "key list file")
+	proofPath := fs.String("proof", "", "proof share file")
+	out := fs.String("out", "", "output audit share file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *out == "" {
+		return errors.New("audit: missing -out")
+	}
+
+	list := &KeyListFile{}
+	if err := readFile(*listPath, list); err != nil {
+		return err
+	}
+
+	proof := &ProofFile{}
+	if err := readFile(*proofPath, proof); err != nil {
+		return err
+	}
+
+	if proof.Scheme != list.Scheme {
+		return fmt.Errorf("cannot audit a %v proof with a %v key list", proof.Scheme, list.Scheme)
+	}
+
+	res := &AuditFile{Scheme: list.Scheme}
+	switch list.Scheme {
+	case SchemeSK:
+		kl, err := list.SKKeyList()
+		if err != nil {
+			return err
+		}
+		share, err := kl.DecodeProof(proof.Proof)
+		if err != nil {
+			return fmt.Errorf("malformed sk proof: %v", err)
+		}
+		res.ShareNumber = share.ShareNumber
+		if res.Audit, err = kl.EncodeAudit(kl.Audit(share)); err != nil {
+			return err
+		}
+	case SchemePK:
+		kl, err := list.PKKeyList()
+		if err != nil {
+			return err
+		}
+		share, err := kl.DecodeProof(proof.Proof)
+		if err != nil {
+			return fmt.Errorf("malformed pk proof: %v", err)
+		}
+		if share.ShareNumber == 1 {
+			// the second verifier holds g^-x
+			kl.FlipSignOfKeys()
+		}
+		res.ShareNumber = share.ShareNumber
+		if res.Audit, err = kl.EncodeAudit(kl.Audit(share)); err != nil {
+			return err
+		}
+	case SchemeSPoSS:
+		kl, err := list.SPoSSKeyList()
+		if err != nil {
+			return err
+		}
+		if err := kl.Validate(); err != nil {
+			return fmt.Errorf("invalid key list: %v", err)
+		}
+
+		share, err := kl.DecodeProof(proof.Proof)
+		if err == nil {
+			err = kl.ValidateProof(share)
+		}
+		if err != nil {
+			return fmt.Errorf("malformed sposs proof: %v", err)
+		}
+
+		if share.ShareNumber == 1 {
+			// the second verifier holds -g^x
+			kl.FlipSignOfKeys()
+		}
+		res.ShareNumber = share.ShareNumber
+		auditShare := kl.Audit(share)
+
+		// the key share would reveal the selected key when combined
+		// with the other verifier's share so it is never written out
+		auditShare.KeyShare = nil
+		if res.Audit, err = kl.EncodeAudit(auditShare); err != nil {
+			return err
+		}
+	default:
+		return checkScheme(list.Scheme)
+	}
+
+	return writeFile(*out, res)
+}
+
+func check(args []string) error {
+
+	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+	listPath := fs.String("list", "", "key list file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 2 {
+		return errors.New("check: expected the audit share files of both verifiers")
+	}
+
+	list := &KeyListFile{}
+	if err := readFile(*listPath, list); err != nil {
+		return err
+	}
+
+	audits := make([]*AuditFile, 2)
+	for i, path := range fs.Args() {
+		audits[i] = &AuditFile{}
+		if err := readFile(path, audits[i]); err != nil {
+			return err
+		}
+
+		if audits[i].Scheme != list.Scheme {
+			return fmt.Errorf("%v: cannot check a %v audit share with a %v key list", path, audits[i].Scheme, list.Scheme)
+		}
+	}
+
+	if audits[0].ShareNumber == audits[1].ShareNumber {
+		return errors.New("both audit shares are from the same verifier")
+	}
+
+	ok := false
+	switch list.Scheme {
+	case SchemeSK:
+		kl, err := list.SKKeyList()
+		if err != nil {
+			return err
+		}
+		shares := make([]*paclsk.AuditShare, 2)
+		for i, audit := range audits {
+			if shares[i], err = kl.DecodeAudit(audit.Audit); err != nil {
+				return fmt.Errorf("malformed sk audit share: %v", err)
+			}
+		}
+		ok = kl.CheckAudit(shares[0], shares[1])
+	case SchemePK:
+		kl, err := list.PKKeyList()
+		if err != nil {
+			return err
+		}
+		shares := make([]*paclpk.AuditShare, 2)
+		for i, audit := range audits {
+			if shares[i], err = kl.DecodeAudit(audit.Audit); err != nil {
+				return fmt.Errorf("malformed pk audit share: %v", err)
+			}
+		}
+		ok = kl.CheckAudit(shares[0], shares[1])
+	case SchemeSPoSS:
+		kl, err := list.SPoSSKeyList()
+		if err != nil {
+			return err
+		}
+		shares := make([]*paclsposs.AuditShare, 2)
+		for i, audit := range audits {
+			if shares[i], err = kl.DecodeAudit(audit.Audit); err != nil {
+				return fmt.Errorf("malformed sposs audit share: %v", err)
+			}
+		}
+		ok = kl.CheckAudit(shares[0], shares[1])
+	default:
+		return checkScheme(list.Scheme)
+	}
+
+	if !ok {
+		return errors.New("audit failed")
+	}
+
+	fmt.Println("audit passed")
+	return nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// test configuration parameters
+const TestNumKeys = 5
+
+func mustRun(t *testing.T, args ...string) {
+	if err := run(args); err != nil {
+		t.Fatalf("paclctl %v: %v", args, err)
+	}
+}
+
+func TestProveAuditCheck(t *testing.T) {
+
+	for _, scheme := range []string{SchemeSK, SchemePK, SchemeSPoSS} {
+		dir := t.TempDir()
+		path := func(name string) string { return filepath.Join(dir, name) }
+
+		pubs := make([]string, TestNumKeys)
+		for i := range pubs {
+			mustRun(t, "keygen", "-scheme", scheme, "-out", path(fmt.Sprint("user", i)))
+			pubs[i] = path(fmt.Sprint("user", i, ".pub.json"))
+			if scheme == SchemeSK {
+				// the verifiers hold the sk keys themselves
+				if _, err := os.Stat(pubs[i]); err == nil {
+					t.Fatalf("Wrote a public key file for an sk key")
+				}
+				pubs[i] = path(fmt.Sprint("user", i, ".key.json"))
+			}
+		}
+
+		list := path("list.json")
+		mustRun(t, append([]string{"keylist", "build", "-scheme", scheme, "-out", list}, pubs[:3]...)...)
+		mustRun(t, append([]string{"keylist", "add", "-list", list}, pubs[3:]...)...)
+
+		// the prover only gets the parameters of the list
+		params := path("params.json")
+		mustRun(t, "keylist", "params", "-list", list, "-out", params)
+		paramsFile := &KeyListFile{}
+		if err := readFile(params, paramsFile); err != nil {
+			t.Fatal(err)
+		}
+		if paramsFile.Keys != nil || paramsFile.NumKeys() != TestNumKeys {
+			t.Fatalf("%v: wrote %v keys of %v to the parameters file", scheme, len(paramsFile.Keys), paramsFile.NumKeys())
+		}
+
+		if run([]string{"keylist", "add", "-list", params, pubs[0]}) == nil {
+			t.Fatalf("%v: added a key to the parameters of a key list", scheme)
+		}
+
+		// the owner of key 4 can prove membership
+		mustRun(t, "prove", "-list", params, "-key", path("user4.key.json"), "-index", "4", "-out", path("proof"))
+		mustRun(t, "audit", "-list", list, "-proof", path("proof.0.json"), "-out", path("audit.0.json"))
+		mustRun(t, "audit", "-list", list, "-proof", path("proof.1.json"), "-out", path("audit.1.json"))
+		mustRun(t, "check", "-list", list, path("audit.0.json"), path("audit.1.json"))
+
+		// but not for somebody else's index
+		mustRun(t, "prove", "-list", params, "-key", path("user4.key.json"), "-index", "1", "-out", path("bad"))
+		mustRun(t, "audit", "-list", list, "-proof", path("bad.0.json"), "-out", path("bad-audit.0.json"))
+		mustRun(t, "audit", "-list", list, "-proof", path("bad.1.json"), "-out", path("bad-audit.1.json"))
+		if run([]string{"check", "-list", list, path("bad-audit.0.json"), path("bad-audit.1.json")}) == nil {
+			t.Fatalf("%v: check accepted a proof for the wrong key", scheme)
+		}
+
+		// public key files cannot be used to prove
+		if scheme != SchemeSK && run([]string{"prove", "-list", params, "-key", pubs[0], "-index", "0", "-out", path("pub")}) == nil {
+			t.Fatalf("%v: proved with a public key", scheme)
+		}
+	}
+}
+
+func TestSchemeMismatch(t *testing.T) {
+
+	dir := t.TempDir()
+	mustRun(t, "keygen", "-scheme", SchemePK, "-out", filepath.Join(dir, "user"))
+
+	err := run([]string{"keylist", "build", "-scheme", SchemeSK, "-out", filepath.Join(dir, "list.json"), filepath.Join(dir, "user.pub.json")})
+	if err == nil {
+		t.Fatalf("Added a pk key to an sk key list")
+	}
+}
+
+func TestParamSetMismatch(t *testing.T) {
+
+	dir := t.TempDir()
+	path := func(name string) string { return filepath.Join(dir, name) }
+	mustRun(t, "keygen", "-scheme", SchemeSPoSS, "-params", "modp2048s256", "-out", path("user"))
+
+	// the key is not over the default parameter set
+	err := run([]string{"keylist", "build", "-scheme", SchemeSPoSS, "-out", path("list.json"), path("user.pub.json")})
+	if err == nil {
+		t.Fatalf("Added a modp2048s256 key to a modp3072 key list")
+	}
+
+	mustRun(t, "keylist", "build", "-scheme", SchemeSPoSS, "-params", "modp2048s256", "-out", path("list.json"), path("user.pub.json"))
+	mustRun(t, "prove", "-list", path("list.json"), "-key", path("user.key.json"), "-index", "0", "-out", path("proof"))
+	mustRun(t, "audit", "-list", path("list.json"), "-proof", path("proof.0.json"), "-out", path("audit.0.json"))
+	mustRun(t, "audit", "-list", path("list.json"), "-proof", path("proof.1.json"), "-out", path("audit.1.json"))
+	mustRun(t, "check", "-list", path("list.json"), path("audit.0.json"), path("audit.1.json"))
+
+	if run([]string{"keygen", "-scheme", SchemeSPoSS, "-params", "modp1024", "-out", path("bad")}) == nil {
+		t.Fatalf("Generated a key for an unknown parameter set")
+	}
+}
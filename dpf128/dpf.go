@@ -0,0 +1,277 @@
+// Package dpf128 implements a two-party DPF (Boyle, Gilboa, Ishai 2016)
+// for point functions over domains of up to 128 bits with one-bit outputs.
+//
+// The C (V)DPF library only accepts 64-bit inputs, which is not enough for
+// applications (e.g., keyword PIR) that hash arbitrary strings into the domain
+// and rely on the hash being collision resistant. Full-domain evaluation is
+// not supported; keys are meant to be evaluated on a batch of points.
+//
+// Keys can also be verifiable (see GenVerifiableKeys), in which case
+// the verifiers can check that they are the keys of a point function,
+// and carry a payload (see GenPayloadKeys) that the point function
+// outputs on its special point in addition to the bit.
+package dpf128
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+)
+
+// MaxDomain is the largest supported domain (in bits)
+const MaxDomain = 128
+
+// Index is a 128-bit DPF input
+type Index struct {
+	Hi uint64
+	Lo uint64
+}
+
+// Key is one of the two DPF keys
+type Key struct {
+	ShareNumber uint
+	Domain      uint
+	Seed        [aes.BlockSize]byte
+	CWSeeds     [][aes.BlockSize]byte // per-level seed correction words
+	CWBitsL     []byte                // per-level left control bit correction words
+	CWBitsR     []byte                // per-level right control bit correction words
+	CWProof     []byte                // proof correction word (verifiable keys only, see GenVerifiableKeys)
+	CWPayload   []byte                // payload correction word (payload keys only, see GenPayloadKeys)
+}
+
+// fixed-key AES instances used as the length-doubling PRG
+var prgL, prgR cipher.Block
+
+func init() {
+	var err error
+	prgL, err = aes.NewCipher([]byte("pacl-dpf128-prgL"))
+	if err != nil {
+		panic(err)
+	}
+
+	prgR, err = aes.NewCipher([]byte("pacl-dpf128-prgR"))
+	if err != nil {
+		panic(err)
+	}
+}
+
+// HashToIndex hashes arbitrary bytes to a 128-bit index
+func HashToIndex(b []byte) Index {
+	h := sha256.New()
+	h.Write([]byte("pacl-dpf128-index"))
+	h.Write(b)
+	d := h.Sum(nil)
+
+	return NewIndex(d[:16])
+}
+
+// NewIndex returns the index encoded by 16 big-endian bytes
+func NewIndex(b []byte) Index {
+	var x Index
+	for i := 0; i < 8; i++ {
+		x.Hi = x.Hi<<8 | uint64(b[i])
+		x.Lo = x.Lo<<8 | uint64(b[8+i])
+	}
+
+	return x
+}
+
+// Bit returns the i-th least significant bit of x
+func (x Index) Bit(i uint) byte {
+	if i < 64 {
+		return byte(x.Lo>>i) & 1
+	}
+
+	return byte(x.Hi>>(i-64)) & 1
+}
+
+// GenKeys returns the two DPF keys of the point function that
+// outputs 1 on alpha and 0 everywhere else in the domain
+func GenKeys(alpha Index, domain uint) (*Key, *Key) {
+	keyA, keyB, _, _ := genKeys(alpha, domain)
+	return keyA, keyB
+}
+
+// same as GenKeys but also returns the seeds and control bits
+// of both keys at the leaf of alpha
+func genKeys(alpha Index, domain uint) (*Key, *Key, [2][aes.BlockSize]byte, [2]byte) {
+	if domain == 0 || domain > MaxDomain {
+		panic(fmt.Sprintf("unsupported DPF domain of %v bits", domain))
+	}
+
+	keyA := &Key{ShareNumber: 0, Domain: domain}
+	keyB := &Key{ShareNumber: 1, Domain: domain}
+	keyA.Seed = randomSeed()
+	keyB.Seed = randomSeed()
+
+	seeds := [2][aes.BlockSize]byte{keyA.Seed, keyB.Seed}
+	bits := [2]byte{0, 1}
+
+	for i := uint(0); i < domain; i++ {
+		sL0, tL0, sR0, tR0 := expand(&seeds[0])
+		sL1, tL1, sR1, tR1 := expand(&seeds[1])
+
+		a := alpha.Bit(domain - 1 - i)
+
+		// the seeds of the lost branch are made equal
+		var cw [aes.BlockSize]byte
+		if a == 0 {
+			xorSeed(&cw, &sR0, &sR1)
+		} else {
+			xorSeed(&cw, &sL0, &sL1)
+		}
+
+		cwL := tL0 ^ tL1 ^ a ^ 1
+		cwR := tR0 ^ tR1 ^ a
+
+		keyA.CWSeeds = append(keyA.CWSeeds, cw)
+		keyA.CWBitsL = append(keyA.CWBitsL, cwL)
+		keyA.CWBitsR = append(keyA.CWBitsR, cwR)
+
+		// follow the kept branch
+		if a == 0 {
+			seeds[0], bits[0] = correct(&sL0, tL0, &cw, cwL, bits[0])
+			seeds[1], bits[1] = correct(&sL1, tL1, &cw, cwL, bits[1])
+		} else {
+			seeds[0], bits[0] = correct(&sR0, tR0, &cw, cwR, bits[0])
+			seeds[1], bits[1] = correct(&sR1, tR1, &cw, cwR, bits[1])
+		}
+	}
+
+	keyB.CWSeeds = keyA.CWSeeds
+	keyB.CWBitsL = keyA.CWBitsL
+	keyB.CWBitsR = keyA.CWBitsR
+
+	return keyA, keyB, seeds, bits
+}
+
+// Validate returns an error unless the key has a supported domain and
+// one correction word per level (e.g., for a key received from a client)
+func (key *Key) Validate() error {
+
+	if key == nil {
+		return errors.New("missing DPF key")
+	}
+
+	if key.ShareNumber > 1 {
+		return fmt.Errorf("invalid share number %v", key.ShareNumber)
+	}
+
+	if key.Domain == 0 || key.Domain > MaxDomain {
+		return fmt.Errorf("unsupported DPF domain of %v bits", key.Domain)
+	}
+
+	n := int(key.Domain)
+	if len(key.CWSeeds) != n || len(key.CWBitsL) != n || len(key.CWBitsR) != n {
+		return fmt.Errorf("key has %v, %v and %v correction words for a domain of %v bits",
+			len(key.CWSeeds), len(key.CWBitsL), len(key.CWBitsR), n)
+	}
+
+	return nil
+}
+
+// Size returns the number of bytes of the key: the seed, one seed
+// correction word per level, and the control bit correction words
+// packed two per level into a byte (the share number is implicit)
+func (key *Key) Size() int {
+	if key == nil {
+		return 0
+	}
+
+	return 1 + aes.BlockSize*(1+len(key.CWSeeds)) + (len(key.CWBitsL)+len(key.CWBitsR)+7)/8 + len(key.CWProof) + len(key.CWPayload)
+}
+
+// Eval returns the key's share (0 or 1) of the point function evaluated on x
+func Eval(key *Key, x Index) (byte, error) {
+	if err := key.Validate(); err != nil {
+		return 0, err
+	}
+
+	_, bit := evalLeaf(key, x)
+	return bit, nil
+}
+
+// BatchEval evaluates the key on every point in xs
+func BatchEval(key *Key, xs []Index) ([]byte, error) {
+	if err := key.Validate(); err != nil {
+		return nil, err
+	}
+
+	res := make([]byte, len(xs))
+	for i, x := range xs {
+		_, res[i] = evalLeaf(key, x)
+	}
+
+	return res, nil
+}
+
+// returns the seed and control bit of the leaf of x (for a valid key)
+func evalLeaf(key *Key, x Index) ([aes.BlockSize]byte, byte) {
+	seed := key.Seed
+	bit := byte(key.ShareNumber)
+
+	for i := uint(0); i < key.Domain; i++ {
+		var s [aes.BlockSize]byte
+		var t byte
+		if x.Bit(key.Domain-1-i) == 0 {
+			s, t = expandOne(prgL, &seed)
+			seed, bit = correct(&s, t, &key.CWSeeds[i], key.CWBitsL[i], bit)
+		} else {
+			s, t = expandOne(prgR, &seed)
+			seed, bit = correct(&s, t, &key.CWSeeds[i], key.CWBitsR[i], bit)
+		}
+	}
+
+	return seed, bit
+}
+
+// expands a seed into the left and right seeds and control bits
+func expand(seed *[aes.BlockSize]byte) ([aes.BlockSize]byte, byte, [aes.BlockSize]byte, byte) {
+	sL, tL := expandOne(prgL, seed)
+	sR, tR := expandOne(prgR, seed)
+	return sL, tL, sR, tR
+}
+
+// Matyas–Meyer–Oseas with a fixed key; the control bit is taken
+// from (and then cleared in) the first byte of the output
+func expandOne(prg cipher.Block, seed *[aes.BlockSize]byte) ([aes.BlockSize]byte, byte) {
+	var out [aes.BlockSize]byte
+	prg.Encrypt(out[:], seed[:])
+	xorSeed(&out, &out, seed)
+
+	t := out[0] & 1
+	out[0] &^= 1
+
+	return out, t
+}
+
+// applies the correction words to a child seed when the parent control bit is set
+func correct(s *[aes.BlockSize]byte, t byte, cw *[aes.BlockSize]byte, cwBit byte, parentBit byte) ([aes.BlockSize]byte, byte) {
+	res := *s
+	if parentBit == 1 {
+		xorSeed(&res, &res, cw)
+		t ^= cwBit
+	}
+
+	return res, t
+}
+
+func xorSeed(dst, a, b *[aes.BlockSize]byte) {
+	for i := 0; i < aes.BlockSize; i++ {
+		dst[i] = a[i] ^ b[i]
+	}
+}
+
+func randomSeed() [aes.BlockSize]byte {
+	var seed [aes.BlockSize]byte
+	_, err := rand.Read(seed[:])
+	if err != nil {
+		panic(fmt.Sprintf("Generating random bytes failed with %v\n", err))
+	}
+
+	seed[0] &^= 1
+	return seed
+}
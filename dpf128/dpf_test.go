@@ -0,0 +1,285 @@
+package dpf128
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// test configuration parameters
+const TestNumPoints = 1000
+
+func randomIndex() Index {
+	return Index{Hi: rand.Uint64(), Lo: rand.Uint64()}
+}
+
+func TestEval(t *testing.T) {
+
+	alpha := randomIndex()
+	keyA, keyB := GenKeys(alpha, MaxDomain)
+
+	if eval(t, keyA, alpha)^eval(t, keyB, alpha) != 1 {
+		t.Fatalf("DPF does not evaluate to 1 on the special point")
+	}
+
+	xs := make([]Index, TestNumPoints)
+	for i := range xs {
+		xs[i] = randomIndex()
+	}
+
+	// points that only differ from alpha in the first or last bit
+	xs[0] = Index{Hi: alpha.Hi ^ (1 << 63), Lo: alpha.Lo}
+	xs[1] = Index{Hi: alpha.Hi, Lo: alpha.Lo ^ 1}
+
+	resA, _ := BatchEval(keyA, xs)
+	resB, _ := BatchEval(keyB, xs)
+	for i := range xs {
+		if resA[i]^resB[i] != 0 {
+			t.Fatalf("DPF evaluates to 1 on %v (special point is %v)", xs[i], alpha)
+		}
+	}
+}
+
+func TestSmallDomain(t *testing.T) {
+
+	domain := uint(10)
+	alpha := Index{Lo: 777}
+	keyA, keyB := GenKeys(alpha, domain)
+
+	for x := uint64(0); x < 1<<domain; x++ {
+		res := eval(t, keyA, Index{Lo: x}) ^ eval(t, keyB, Index{Lo: x})
+		if (x == alpha.Lo) != (res == 1) {
+			t.Fatalf("DPF evaluates to %v on %v (special point is %v)", res, x, alpha.Lo)
+		}
+	}
+}
+
+func TestVerifiable(t *testing.T) {
+
+	domain := uint(10)
+	alpha := Index{Lo: 777}
+	keyA, keyB := GenVerifiableKeys(alpha, domain)
+
+	xs := make([]Index, 1<<domain)
+	for x := range xs {
+		xs[x] = Index{Lo: uint64(x)}
+	}
+
+	resA, piA, errA := BatchVerEval(keyA, xs)
+	resB, piB, errB := BatchVerEval(keyB, xs)
+	if errA != nil || errB != nil {
+		t.Fatalf("BatchVerEval failed: %v %v", errA, errB)
+	}
+
+	for x := range xs {
+		if (uint64(x) == alpha.Lo) != (resA[x]^resB[x] == 1) {
+			t.Fatalf("DPF evaluates to %v on %v (special point is %v)", resA[x]^resB[x], x, alpha.Lo)
+		}
+	}
+
+	if !bytes.Equal(piA, piB) {
+		t.Fatalf("Proofs of a point function differ")
+	}
+
+	// keys of a function that is not a point function: flipping a
+	// correction bit of the last level changes the control bits of B on
+	// one more leaf, which the proofs catch
+	bad := *keyB
+	bad.CWBitsL = append([]byte{}, keyB.CWBitsL...)
+	bad.CWBitsR = append([]byte{}, keyB.CWBitsR...)
+	bad.CWBitsL[domain-1] ^= 1
+	bad.CWBitsR[domain-1] ^= 1
+
+	resBad, piBad, err := BatchVerEval(&bad, xs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ones := 0
+	for x := range xs {
+		ones += int(resA[x] ^ resBad[x])
+	}
+
+	if ones < 2 {
+		t.Fatalf("Malformed keys select %v points", ones)
+	}
+
+	if bytes.Equal(piA, piBad) {
+		t.Fatalf("Proofs of keys selecting %v points are equal", ones)
+	}
+
+	// keys that are not verifiable
+	keyA, _ = GenKeys(alpha, domain)
+	if _, _, err := BatchVerEval(keyA, xs); err == nil {
+		t.Fatalf("Evaluated a key that is not verifiable")
+	}
+}
+
+func TestPayload(t *testing.T) {
+
+	domain := uint(10)
+	alpha := Index{Lo: 777}
+	payload := []byte("pacl payload")
+	keyA, keyB := GenPayloadKeys(alpha, domain, payload)
+
+	xs := make([]Index, 1<<domain)
+	for x := range xs {
+		xs[x] = Index{Lo: uint64(x)}
+	}
+
+	resA, outA, piA, errA := BatchVerEvalPayload(keyA, xs)
+	resB, outB, piB, errB := BatchVerEvalPayload(keyB, xs)
+	if errA != nil || errB != nil {
+		t.Fatalf("BatchVerEvalPayload failed: %v %v", errA, errB)
+	}
+
+	for x := range xs {
+		expected := make([]byte, len(payload))
+		if uint64(x) == alpha.Lo {
+			expected = payload
+		}
+
+		if (uint64(x) == alpha.Lo) != (resA[x]^resB[x] == 1) {
+			t.Fatalf("DPF evaluates to %v on %v (special point is %v)", resA[x]^resB[x], x, alpha.Lo)
+		}
+
+		out := make([]byte, len(payload))
+		for j := range out {
+			out[j] = outA[x][j] ^ outB[x][j]
+		}
+
+		if !bytes.Equal(out, expected) {
+			t.Fatalf("DPF outputs %v on %v (expected %v)", out, x, expected)
+		}
+	}
+
+	if !bytes.Equal(piA, piB) {
+		t.Fatalf("Proofs of a payload function differ")
+	}
+
+	// keys with different payload corrections would write
+	// to every point where their control bits are set
+	bad := *keyB
+	bad.CWPayload = append([]byte{}, keyB.CWPayload...)
+	bad.CWPayload[0] ^= 1
+
+	_, _, piBad, err := BatchVerEvalPayload(&bad, xs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Equal(piA, piBad) {
+		t.Fatalf("Proofs of keys with different payload corrections are equal")
+	}
+
+	// keys without a payload
+	keyA, _ = GenVerifiableKeys(alpha, domain)
+	if _, _, _, err := BatchVerEvalPayload(keyA, xs); err == nil {
+		t.Fatalf("Evaluated the payload of a key without one")
+	}
+}
+
+func TestMalformedKey(t *testing.T) {
+
+	keyA, _ := GenKeys(Index{Lo: 1}, 10)
+
+	// a larger domain than the key has correction words for
+	bad := *keyA
+	bad.Domain = MaxDomain
+	if _, err := Eval(&bad, Index{}); err == nil {
+		t.Fatalf("Evaluated a key with missing correction words")
+	}
+
+	bad = *keyA
+	bad.CWBitsR = bad.CWBitsR[:5]
+	if _, err := BatchEval(&bad, []Index{{}}); err == nil {
+		t.Fatalf("Evaluated a key with missing correction bits")
+	}
+
+	bad = *keyA
+	bad.Domain = 0
+	if _, err := Eval(&bad, Index{}); err == nil {
+		t.Fatalf("Evaluated a key with an empty domain")
+	}
+
+	if _, err := Eval(nil, Index{}); err == nil {
+		t.Fatalf("Evaluated a missing key")
+	}
+}
+
+func TestMarshalKey(t *testing.T) {
+
+	alpha := randomIndex()
+	plainA, _ := GenKeys(alpha, 13)
+	verA, verB := GenVerifiableKeys(alpha, MaxDomain)
+	payA, _ := GenPayloadKeys(alpha, 10, []byte("pacl payload"))
+
+	for _, key := range []*Key{plainA, verA, verB, payA} {
+		b, err := key.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(b) != key.Size()+2 {
+			t.Fatalf("Encoding of key has %v bytes instead of %v", len(b), key.Size()+2)
+		}
+
+		decoded := &Key{}
+		if err := decoded.UnmarshalBinary(b); err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(decoded, key) {
+			t.Fatalf("Decoded key differs from the key")
+		}
+
+		if err := decoded.UnmarshalBinary(b[:len(b)/2]); err == nil {
+			t.Fatalf("Decoded a truncated key")
+		}
+	}
+
+	// nonzero padding bits of the control bit correction words
+	b, _ := plainA.MarshalBinary()
+	b[2+16*14+3] |= 0x80
+	if err := (&Key{}).UnmarshalBinary(b); err == nil {
+		t.Fatalf("Decoded a key with nonzero padding bits")
+	}
+}
+
+func TestHashToIndex(t *testing.T) {
+
+	if HashToIndex([]byte("a")) != HashToIndex([]byte("a")) {
+		t.Fatalf("HashToIndex is not deterministic")
+	}
+
+	if HashToIndex([]byte("a")) == HashToIndex([]byte("b")) {
+		t.Fatalf("HashToIndex maps distinct inputs to the same index")
+	}
+}
+
+func eval(t *testing.T, key *Key, x Index) byte {
+	t.Helper()
+
+	res, err := Eval(key, x)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return res
+}
+
+func BenchmarkBatchEval(b *testing.B) {
+
+	keyA, _ := GenKeys(randomIndex(), MaxDomain)
+	xs := make([]Index, TestNumPoints)
+	for i := range xs {
+		xs[i] = randomIndex()
+	}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		BatchEval(keyA, xs)
+	}
+}
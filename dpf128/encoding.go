@@ -0,0 +1,102 @@
+package dpf128
+
+import (
+	"crypto/aes"
+	"errors"
+	"fmt"
+)
+
+// Keys are encoded as the share number and the domain (one byte each),
+// the seed, the seed correction words, the left and right control bit
+// correction words (packed four levels per byte), the length of the proof
+// correction word (one byte) and the proof and payload correction words:
+// two bytes more than Size (which leaves out the share number and the
+// length of the proof correction word).
+
+// MarshalBinary returns the encoding of the key
+func (key *Key) MarshalBinary() ([]byte, error) {
+
+	if err := key.Validate(); err != nil {
+		return nil, err
+	}
+
+	if len(key.CWProof) > 0xff {
+		return nil, errors.New("proof correction word is too long")
+	}
+
+	n := int(key.Domain)
+	b := make([]byte, 0, key.Size()+2)
+	b = append(b, byte(key.ShareNumber), byte(n))
+	b = append(b, key.Seed[:]...)
+	for i := range key.CWSeeds {
+		b = append(b, key.CWSeeds[i][:]...)
+	}
+
+	packed := make([]byte, (2*n+7)/8)
+	for i := 0; i < n; i++ {
+		if key.CWBitsL[i] > 1 || key.CWBitsR[i] > 1 {
+			return nil, errors.New("control bit correction word is not a bit")
+		}
+		packed[i/4] |= (key.CWBitsL[i] | key.CWBitsR[i]<<1) << uint(2*(i%4))
+	}
+	b = append(b, packed...)
+
+	b = append(b, byte(len(key.CWProof)))
+	b = append(b, key.CWProof...)
+	b = append(b, key.CWPayload...)
+
+	return b, nil
+}
+
+// UnmarshalBinary sets the key to the key of the encoding b
+func (key *Key) UnmarshalBinary(b []byte) error {
+
+	if len(b) < 2 || b[1] == 0 || b[1] > MaxDomain {
+		return errors.New("malformed DPF key")
+	}
+
+	n := int(b[1])
+	size := 2 + aes.BlockSize*(1+n) + (2*n+7)/8 + 1
+	if len(b) < size || len(b) < size+int(b[size-1]) {
+		return fmt.Errorf("DPF key over %v bits has %v bytes", n, len(b))
+	}
+
+	k := &Key{ShareNumber: uint(b[0]), Domain: uint(n)}
+	b = b[2:]
+	copy(k.Seed[:], b)
+	b = b[aes.BlockSize:]
+
+	k.CWSeeds = make([][aes.BlockSize]byte, n)
+	for i := range k.CWSeeds {
+		copy(k.CWSeeds[i][:], b)
+		b = b[aes.BlockSize:]
+	}
+
+	k.CWBitsL = make([]byte, n)
+	k.CWBitsR = make([]byte, n)
+	for i := 0; i < n; i++ {
+		bits := b[i/4] >> uint(2*(i%4))
+		k.CWBitsL[i], k.CWBitsR[i] = bits&1, (bits>>1)&1
+	}
+
+	// the padding bits must be zero (so that the encoding is unique)
+	if n%4 != 0 && b[n/4]>>uint(2*(n%4)) != 0 {
+		return errors.New("malformed DPF key")
+	}
+	b = b[(2*n+7)/8:]
+
+	proofLen := int(b[0])
+	if proofLen > 0 {
+		k.CWProof = append([]byte{}, b[1:1+proofLen]...)
+	}
+	if len(b) > 1+proofLen {
+		k.CWPayload = append([]byte{}, b[1+proofLen:]...)
+	}
+
+	if err := k.Validate(); err != nil {
+		return err
+	}
+
+	*key = *k
+	return nil
+}
@@ -0,0 +1,72 @@
+package dpf128
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+)
+
+// A payload key outputs, on every point x, a share of the payload on alpha
+// and a share of zero everywhere else (Boyle, Gilboa, Ishai 2016): the share
+// of x is the leaf seed expanded to the size of the payload, corrected by
+// CWPayload when the control bit of the leaf is set. CWPayload is the payload
+// xor the expanded seeds of both keys on alpha, where exactly one of the keys
+// has its control bit set. Payload keys are verifiable and their proofs also
+// cover CWPayload, so two keys that share a point function and have the same
+// proof also share the payload function.
+
+// GenPayloadKeys is the same as GenVerifiableKeys but the keys also
+// share the payload on alpha (see BatchVerEvalPayload)
+func GenPayloadKeys(alpha Index, domain uint, payload []byte) (*Key, *Key) {
+
+	keyA, keyB, seeds, bits := genKeys(alpha, domain)
+
+	cw := leafProof(alpha, &seeds[0], bits[0])
+	hB := leafProof(alpha, &seeds[1], bits[1])
+	for i := range cw {
+		cw[i] ^= hB[i]
+	}
+
+	keyA.CWProof = cw[:]
+	keyB.CWProof = keyA.CWProof
+
+	out := make([]byte, len(payload))
+	outB := make([]byte, len(payload))
+	convert(out, &seeds[0])
+	convert(outB, &seeds[1])
+	for i := range out {
+		out[i] ^= outB[i] ^ payload[i]
+	}
+
+	keyA.CWPayload = out
+	keyB.CWPayload = keyA.CWPayload
+
+	return keyA, keyB
+}
+
+// BatchVerEvalPayload is the same as BatchVerEval but also returns the
+// key's share of the payload function on every point in xs
+func BatchVerEvalPayload(key *Key, xs []Index) ([]byte, [][]byte, []byte, error) {
+
+	if key != nil && len(key.CWPayload) == 0 {
+		return nil, nil, nil, errors.New("key has no payload")
+	}
+
+	return batchVerEval(key, xs, true)
+}
+
+// expands a leaf seed into len(dst) bytes (AES-CTR keyed by the seed)
+func convert(dst []byte, seed *[aes.BlockSize]byte) {
+
+	block, err := aes.NewCipher(seed[:])
+	if err != nil {
+		panic(err)
+	}
+
+	for i := range dst {
+		dst[i] = 0
+	}
+
+	var iv [aes.BlockSize]byte
+	cipher.NewCTR(block, iv[:]).XORKeyStream(dst, dst)
+}
@@ -0,0 +1,116 @@
+package dpf128
+
+import (
+	"crypto/aes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+)
+
+// Verifiable DPF (de Castro and Polychroniadou, 2022): evaluating a key on
+// a batch of points also returns a proof, and the proofs of the two keys
+// are equal if and only if the leaves of the keys (seed and control bit)
+// are the same on every point but at most one, where the control bits
+// differ (unless the hash is broken). The keys then share a point function
+// (or the zero function) on the batch, whichever way they were generated.
+//
+// The proof of a leaf is a hash of the point, seed and control bit, which
+// the key corrects by CWProof when its control bit is set; CWProof is the
+// xor of the leaf hashes of both keys on alpha, so that the corrected hashes
+// of the two keys are equal everywhere. The proof of a batch is the hash of
+// the corrected leaf hashes (and of the payload correction word, if any).
+
+// ProofSize is the size (in bytes) of the proofs returned by BatchVerEval
+const ProofSize = sha256.Size
+
+// GenVerifiableKeys is the same as GenKeys but returns verifiable keys
+// (see BatchVerEval)
+func GenVerifiableKeys(alpha Index, domain uint) (*Key, *Key) {
+
+	keyA, keyB, seeds, bits := genKeys(alpha, domain)
+
+	cw := leafProof(alpha, &seeds[0], bits[0])
+	hB := leafProof(alpha, &seeds[1], bits[1])
+	for i := range cw {
+		cw[i] ^= hB[i]
+	}
+
+	keyA.CWProof = cw[:]
+	keyB.CWProof = keyA.CWProof
+
+	return keyA, keyB
+}
+
+// BatchVerEval is the same as BatchEval but also returns the proof
+// of the batch; the key must be verifiable
+func BatchVerEval(key *Key, xs []Index) ([]byte, []byte, error) {
+	res, _, proof, err := batchVerEval(key, xs, false)
+	return res, proof, err
+}
+
+// evaluates a verifiable key on every point in xs and returns the control
+// bits, the payload shares (if requested) and the proof of the batch
+func batchVerEval(key *Key, xs []Index, withPayload bool) ([]byte, [][]byte, []byte, error) {
+
+	if err := key.Validate(); err != nil {
+		return nil, nil, nil, err
+	}
+
+	if len(key.CWProof) != ProofSize {
+		return nil, nil, nil, errors.New("key is not verifiable")
+	}
+
+	res := make([]byte, len(xs))
+	proof := sha256.New()
+	proof.Write([]byte("pacl-dpf128-batch"))
+
+	// the proofs differ unless both keys have the same payload correction
+	var size [4]byte
+	binary.BigEndian.PutUint32(size[:], uint32(len(key.CWPayload)))
+	proof.Write(size[:])
+	proof.Write(key.CWPayload)
+
+	var payloads [][]byte
+	if withPayload {
+		payloads = make([][]byte, len(xs))
+		buf := make([]byte, len(xs)*len(key.CWPayload))
+		for i := range payloads {
+			payloads[i] = buf[i*len(key.CWPayload) : (i+1)*len(key.CWPayload)]
+		}
+	}
+
+	for i, x := range xs {
+		var seed [aes.BlockSize]byte
+		seed, res[i] = evalLeaf(key, x)
+
+		// corrected without branching on the control bit
+		h := leafProof(x, &seed, res[i])
+		mask := -res[i]
+		for j := range h {
+			h[j] ^= key.CWProof[j] & mask
+		}
+
+		proof.Write(h[:])
+
+		if withPayload {
+			convert(payloads[i], &seed)
+			for j := range payloads[i] {
+				payloads[i][j] ^= key.CWPayload[j] & mask
+			}
+		}
+	}
+
+	return res, payloads, proof.Sum(nil), nil
+}
+
+// hash of the leaf of x with the given seed and control bit
+func leafProof(x Index, seed *[aes.BlockSize]byte, bit byte) [sha256.Size]byte {
+	var data [17 + 16 + aes.BlockSize + 1]byte
+	copy(data[:], "pacl-dpf128-leaf")
+	binary.BigEndian.PutUint64(data[17:], x.Hi)
+	binary.BigEndian.PutUint64(data[25:], x.Lo)
+	copy(data[33:], seed[:])
+	data[33+aes.BlockSize] = bit
+
+	return sha256.Sum256(data[:])
+}
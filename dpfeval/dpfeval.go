@@ -0,0 +1,100 @@
+// Package dpfeval chooses how the verifiers of a key list expand a DPF key:
+// on the index of every key (a batch evaluation) or over the whole domain,
+// from which the output of every key is then looked up. The PACL packages
+// (pacl-sk, pacl-pk and pacl-sposs) share it.
+package dpfeval
+
+// Strategy selects how the verifiers expand the DPF over the key list
+type Strategy int
+
+const (
+	// Auto picks the cheaper strategy using the cost model below
+	Auto Strategy = iota
+	// Batch evaluates the DPF on the index of every key
+	Batch
+	// FullDomain evaluates the DPF on the whole domain and then looks up
+	// the output at the index of every key (for domains of at most
+	// MaxFullDomainBits, batch evaluation is used otherwise)
+	FullDomain
+)
+
+// Cost model (in nanoseconds) of the two strategies: a batch evaluation
+// walks one root-to-leaf path per key, whereas a full-domain evaluation
+// expands every node of the tree once and packs 128 leaves into each PRG
+// output, so its cost is dominated by writing one output per leaf.
+//
+// The constants are the ns/level and ns/leaf metrics of BenchmarkExpandDPF
+// in pacl-sk (domain of 2^20, rounded); they put the crossover at about
+// 2^12.5 keys for that domain. Only their ratio matters, so re-measure
+// both when the (V)DPF library or the hardware changes.
+const (
+	batchCostPerLevel     = 45.0
+	fullDomainCostPerLeaf = 5.0
+)
+
+// MaxFullDomainBits bounds the domain of full-domain evaluations,
+// which allocate one byte per point of the domain
+const MaxFullDomainBits = 26
+
+// UseFullDomain returns true if the DPF should be expanded over the whole
+// domain (of fssDomain bits) for a list of numKeys keys; a list whose keys
+// are ordered by index and fill the domain (fullDomain) always is
+func UseFullDomain(strategy Strategy, fullDomain bool, numKeys uint64, fssDomain uint) bool {
+	if fullDomain {
+		return true
+	}
+
+	switch strategy {
+	case Batch:
+		return false
+	case FullDomain:
+		return fssDomain <= MaxFullDomainBits
+	default:
+		return fullDomainIsCheaper(numKeys, fssDomain)
+	}
+}
+
+// Crossover returns the smallest number of keys for which Auto expands the
+// DPF over a domain of fssDomain bits (or 0 if it never does)
+func Crossover(fssDomain uint) uint64 {
+	if fssDomain == 0 || fssDomain > MaxFullDomainBits {
+		return 0
+	}
+
+	full := fullDomainCostPerLeaf * float64(uint64(1)<<fssDomain)
+	perKey := batchCostPerLevel * float64(fssDomain)
+
+	return uint64(full/perKey) + 1
+}
+
+func fullDomainIsCheaper(numKeys uint64, fssDomain uint) bool {
+	if fssDomain > MaxFullDomainBits {
+		return false
+	}
+
+	full := fullDomainCostPerLeaf * float64(uint64(1)<<fssDomain)
+	batch := batchCostPerLevel * float64(numKeys) * float64(fssDomain)
+
+	return full < batch
+}
+
+// Lookup maps the full-domain expansion of the DPF (bits) to the output of
+// every key. If the keys fill the domain (fullDomain), key i has index i.
+//
+// Key indices need not be reduced to the domain (GenerateRandomKeyList in
+// pacl-sposs draws them with rand.Uint64): the (V)DPF library only reads
+// the low fssDomain bits of an index, in BatchEval as in GenVDPFKeys, so
+// Lookup masks them the same way (see TestFullDomainLookup in pacl-sposs).
+func Lookup(bits []byte, keyIndices []uint64, fssDomain uint, fullDomain bool) []byte {
+	if fullDomain {
+		return bits
+	}
+
+	mask := uint64(1)<<fssDomain - 1
+	res := make([]byte, len(keyIndices))
+	for i, idx := range keyIndices {
+		res[i] = bits[idx&mask]
+	}
+
+	return res
+}
@@ -0,0 +1,66 @@
+package dpfeval
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestUseFullDomain(t *testing.T) {
+
+	// dense and sparse lists in a domain of 2^20
+	if !UseFullDomain(Auto, false, 1<<17, 20) {
+		t.Fatalf("Expected the full-domain strategy for a dense list")
+	}
+
+	if UseFullDomain(Auto, false, 1<<8, 20) {
+		t.Fatalf("Expected the batch strategy for a sparse list")
+	}
+
+	if !UseFullDomain(FullDomain, false, 1, 20) || UseFullDomain(Batch, false, 1<<20, 20) {
+		t.Fatalf("The strategy of the list was not followed")
+	}
+
+	// a domain too large to expand, even when forced
+	for _, strategy := range []Strategy{Auto, FullDomain} {
+		if UseFullDomain(strategy, false, 1<<40, MaxFullDomainBits+1) {
+			t.Fatalf("Expanded a domain of %v bits with strategy %v", MaxFullDomainBits+1, strategy)
+		}
+	}
+
+	if !UseFullDomain(Batch, true, 1<<10, 10) {
+		t.Fatalf("Expected the full-domain strategy for keys that fill the domain")
+	}
+}
+
+func TestLookup(t *testing.T) {
+
+	bits := []byte{0, 1, 0, 0}
+
+	// indices only matter modulo the domain
+	res := Lookup(bits, []uint64{3, 1, 5, 0}, 2, false)
+	if !bytes.Equal(res, []byte{0, 1, 1, 0}) {
+		t.Fatalf("Wrong outputs %v", res)
+	}
+
+	if !bytes.Equal(Lookup(bits, nil, 2, true), bits) {
+		t.Fatalf("Wrong outputs for keys that fill the domain")
+	}
+}
+
+func TestCrossover(t *testing.T) {
+
+	for _, fssDomain := range []uint{1, 10, 20, MaxFullDomainBits} {
+		c := Crossover(fssDomain)
+		if c == 0 {
+			t.Fatalf("No crossover for a domain of %v bits", fssDomain)
+		}
+
+		if UseFullDomain(Auto, false, c-1, fssDomain) || !UseFullDomain(Auto, false, c, fssDomain) {
+			t.Fatalf("Wrong crossover %v for a domain of %v bits", c, fssDomain)
+		}
+	}
+
+	if Crossover(MaxFullDomainBits+1) != 0 {
+		t.Fatalf("Crossover for a domain too large to expand")
+	}
+}
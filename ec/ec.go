@@ -5,6 +5,11 @@ package ec
 import (
 	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
 	"io"
 	"math/big"
 
@@ -26,6 +31,15 @@ func (point *Point) Copy() *Point {
 		Y: new(big.Int).SetBytes(point.Y.Bytes())}
 }
 
+// Size returns the number of bytes of the (uncompressed) encoding of the point
+func (point *Point) Size() int {
+	if point == nil {
+		return 0
+	}
+
+	return (point.X.BitLen()+7)/8 + (point.Y.BitLen()+7)/8
+}
+
 // This is just a bitmask with the number of ones starting at 8 then
 // incrementing by index. To account for fields with bitsizes that are not a whole
 // number of bytes, we mask off the unnecessary bits. h/t agl
@@ -95,6 +109,42 @@ func (ec *EC) ScalarMult(scalar *big.Int) *Point {
 	return &Point{X: x, Y: y}
 }
 
+// PointScalarMult: computes scalar*point for an arbitrary point on the curve
+func (ec *EC) PointScalarMult(point *Point, scalar *big.Int) *Point {
+	x, y := ec.Curve.ScalarMult(point.X, point.Y, scalar.Bytes())
+	return &Point{X: x, Y: y}
+}
+
+// HashToPoint: maps data to a curve point with unknown discrete log
+// using try-and-increment (assumes a short Weierstrass curve with a = -3)
+func (ec *EC) HashToPoint(data []byte) *Point {
+	params := ec.Curve.Params()
+	three := big.NewInt(3)
+	ctr := make([]byte, 4)
+
+	for i := uint32(0); ; i++ {
+		binary.BigEndian.PutUint32(ctr, i)
+		h := sha256.Sum256(append(ctr, data...))
+		x := new(big.Int).SetBytes(h[:])
+		x.Mod(x, params.P)
+
+		// y^2 = x^3 - 3x + b
+		y2 := new(big.Int).Exp(x, three, params.P)
+		y2.Sub(y2, new(big.Int).Mul(x, three))
+		y2.Add(y2, params.B)
+		y2.Mod(y2, params.P)
+
+		y := new(big.Int).ModSqrt(y2, params.P)
+		if y == nil {
+			continue
+		}
+
+		if ec.Curve.IsOnCurve(x, y) {
+			return &Point{X: x, Y: y}
+		}
+	}
+}
+
 func (ec *EC) Add(pointA, pointB *Point) *Point {
 	x, y := ec.Curve.Add(pointA.X, pointA.Y, pointB.X, pointB.Y)
 	return &Point{X: x, Y: y}
@@ -107,8 +157,86 @@ func (ec *EC) Inverse(pointA *Point) *Point {
 	return newPoint
 }
 
+// CondAdd returns pointA + pointB if bit is 1 and pointA if bit is 0; the
+// sum is computed either way and the result is then chosen with a
+// constant-time conditional move (only the least significant bit is used)
+func (ec *EC) CondAdd(pointA, pointB *Point, bit byte) *Point {
+	pointA, pointB = ec.reduce(pointA), ec.reduce(pointB)
+	sum := ec.Add(pointA, pointB)
+
+	bufA := ec.encode(pointA)
+	subtle.ConstantTimeCopy(int(bit&1), bufA, ec.encode(sum))
+
+	n := len(bufA) / 2
+	return &Point{X: new(big.Int).SetBytes(bufA[:n]), Y: new(big.Int).SetBytes(bufA[n:])}
+}
+
+// fixed-width encoding of the coordinates of the point
+func (ec *EC) encode(point *Point) []byte {
+	point = ec.reduce(point)
+	n := (ec.Curve.Params().P.BitLen() + 7) / 8
+	buf := make([]byte, 2*n)
+	point.X.FillBytes(buf[:n])
+	point.Y.FillBytes(buf[n:])
+	return buf
+}
+
+// ByteLen returns the number of bytes of the encoding of a point
+func (ec *EC) ByteLen() int {
+	return 2 * ((ec.Curve.Params().P.BitLen() + 7) / 8)
+}
+
+// Encode returns the fixed-width encoding of the coordinates of the point
+// (or an error if they are not reduced mod P)
+func (ec *EC) Encode(point *Point) ([]byte, error) {
+	if point == nil || point.X == nil || point.Y == nil {
+		return nil, errors.New("cannot encode point: missing coordinates")
+	}
+
+	if ec.reduce(point) != point {
+		return nil, errors.New("cannot encode point: coordinates are not reduced mod P")
+	}
+
+	return ec.encode(point), nil
+}
+
+// Decode returns the point of the fixed-width encoding b, which must be on
+// the curve or the identity (whose coordinates are zero)
+func (ec *EC) Decode(b []byte) (*Point, error) {
+
+	if len(b) != ec.ByteLen() {
+		return nil, fmt.Errorf("encoding has %v bytes instead of %v", len(b), ec.ByteLen())
+	}
+
+	n := len(b) / 2
+	point := &Point{X: new(big.Int).SetBytes(b[:n]), Y: new(big.Int).SetBytes(b[n:])}
+	if point.X.Sign() == 0 && point.Y.Sign() == 0 {
+		return point, nil
+	}
+
+	if !ec.Curve.IsOnCurve(point.X, point.Y) {
+		return nil, errors.New("encoded point is not on the curve")
+	}
+
+	return point, nil
+}
+
+// returns the point with its coordinates reduced mod P; coordinates that
+// are out of range (e.g., of a point received from a client) would not
+// fit the fixed-width encoding
+func (ec *EC) reduce(point *Point) *Point {
+	p := ec.Curve.Params().P
+	if point.X.Sign() >= 0 && point.X.Cmp(p) < 0 && point.Y.Sign() >= 0 && point.Y.Cmp(p) < 0 {
+		return point
+	}
+
+	return &Point{X: new(big.Int).Mod(point.X, p), Y: new(big.Int).Mod(point.Y, p)}
+}
+
+// IsEqual compares the fixed-width encodings of the points (with
+// coordinates mod P) in constant time
 func (ec *EC) IsEqual(pointA, pointB *Point) bool {
-	return pointA.X.Cmp(pointB.X) == 0 && pointA.Y.Cmp(pointB.Y) == 0
+	return subtle.ConstantTimeCompare(ec.encode(pointA), ec.encode(pointB)) == 1
 }
 
 func (ec *EC) IsIdentity(pointA *Point) bool {
@@ -2,6 +2,7 @@ package ec
 
 import (
 	"crypto/elliptic"
+	"math/big"
 	"testing"
 
 	"github.com/sachaservan/pacl/algebra"
@@ -38,6 +39,41 @@ func TestAdd(t *testing.T) {
 	}
 }
 
+func TestCondAdd(t *testing.T) {
+
+	ec := &EC{elliptic.P224(), algebra.NewField(elliptic.P224().Params().P)}
+	_, r1, _ := ec.NewRandomPoint()
+	_, r2, _ := ec.NewRandomPoint()
+
+	if !ec.IsEqual(ec.CondAdd(r1, r2, 1), ec.Add(r1, r2)) {
+		t.Fatalf("CondAdd with bit 1 is not the sum")
+	}
+
+	if !ec.IsEqual(ec.CondAdd(r1, r2, 0), r1) {
+		t.Fatalf("CondAdd with bit 0 changed the point")
+	}
+
+	id, _ := ec.IdentityPoint()
+	if !ec.IsIdentity(ec.CondAdd(id, r1, 0)) || !ec.IsEqual(ec.CondAdd(id, r1, 1), r1) {
+		t.Fatalf("CondAdd to the identity is wrong")
+	}
+
+	// coordinates that are not reduced mod P
+	p := ec.Curve.Params().P
+	wide := &Point{X: new(big.Int).Add(r1.X, p), Y: new(big.Int).Add(r1.Y, new(big.Int).Lsh(p, 8))}
+	if !ec.IsEqual(wide, r1) || ec.IsEqual(wide, r2) {
+		t.Fatalf("IsEqual is wrong on coordinates that are not reduced")
+	}
+
+	if !ec.IsEqual(ec.CondAdd(wide, r2, 0), r1) {
+		t.Fatalf("CondAdd with bit 0 changed a point with coordinates that are not reduced")
+	}
+
+	if !ec.IsEqual(ec.CondAdd(wide, r2, 1), ec.Add(r1, r2)) {
+		t.Fatalf("CondAdd with bit 1 is not the sum for coordinates that are not reduced")
+	}
+}
+
 func TestInverse(t *testing.T) {
 
 	ec := &EC{elliptic.P224(), algebra.NewField(elliptic.P224().Params().P)}
@@ -62,6 +98,7 @@ func BenchmarkCurveAddition(b *testing.B) {
 		list[i] = r
 	}
 
+	b.ReportAllocs()
 	b.ResetTimer()
 
 	next := 0
@@ -74,3 +111,55 @@ func BenchmarkCurveAddition(b *testing.B) {
 		next++
 	}
 }
+
+func TestHashToPoint(t *testing.T) {
+
+	ec := &EC{elliptic.P256(), algebra.NewField(elliptic.P256().Params().N)}
+	p1 := ec.HashToPoint([]byte("epoch"))
+	p2 := ec.HashToPoint([]byte("epoch"))
+	p3 := ec.HashToPoint([]byte("another epoch"))
+
+	if !ec.Curve.IsOnCurve(p1.X, p1.Y) {
+		t.Fatalf("Hashed point is not on the curve")
+	}
+
+	if !ec.IsEqual(p1, p2) || ec.IsEqual(p1, p3) {
+		t.Fatalf("HashToPoint is not deterministic or collides")
+	}
+}
+
+func TestEncode(t *testing.T) {
+
+	ec := &EC{elliptic.P224(), algebra.NewField(elliptic.P224().Params().P)}
+	id, _ := ec.IdentityPoint()
+	_, r, _ := ec.NewRandomPoint()
+
+	for _, point := range []*Point{id, r} {
+		b, err := ec.Encode(point)
+		if err != nil || len(b) != ec.ByteLen() {
+			t.Fatalf("Encoding of point has %v bytes: %v", len(b), err)
+		}
+
+		decoded, err := ec.Decode(b)
+		if err != nil || !ec.IsEqual(decoded, point) {
+			t.Fatalf("Decoding of point failed: %v", err)
+		}
+	}
+
+	// points off the curve, encodings of the wrong length
+	// and coordinates that are not reduced are rejected
+	b, _ := ec.Encode(r)
+	b[len(b)-1] ^= 1
+	if _, err := ec.Decode(b); err == nil {
+		t.Fatalf("Decoded a point that is not on the curve")
+	}
+
+	if _, err := ec.Decode(b[1:]); err == nil {
+		t.Fatalf("Decoded a truncated point")
+	}
+
+	unreduced := &Point{X: new(big.Int).Add(r.X, ec.Curve.Params().P), Y: r.Y}
+	if _, err := ec.Encode(unreduced); err == nil {
+		t.Fatalf("Encoded a point with coordinates that are not reduced")
+	}
+}
@@ -0,0 +1,31 @@
+// Package benchsweep runs the per-operation benchmarks of the PACL
+// constructions over the same key-list sizes and predicates, so that the
+// results of the packages can be compared (e.g., with benchstat).
+package benchsweep
+
+import (
+	"fmt"
+	"testing"
+)
+
+// Sizes are the key-list sizes over which the benchmarks run
+var Sizes = []uint64{1 << 8, 1 << 12}
+
+// Run runs bench as a sub-benchmark (e.g., "keys=256/Inclusion") for every
+// key-list size, with the equality and then the inclusion predicate; bench
+// should reset the timer after setting up the key list
+func Run(b *testing.B, bench func(b *testing.B, numKeys uint64, inclusion bool)) {
+	for _, inclusion := range []bool{false, true} {
+		for _, numKeys := range Sizes {
+			name := fmt.Sprintf("keys=%v/Equality", numKeys)
+			if inclusion {
+				name = fmt.Sprintf("keys=%v/Inclusion", numKeys)
+			}
+
+			b.Run(name, func(b *testing.B) {
+				b.ReportAllocs()
+				bench(b, numKeys, inclusion)
+			})
+		}
+	}
+}
@@ -0,0 +1,139 @@
+// Package keystore stores key lists as flat, fixed-width records (the 8-byte
+// big-endian key index followed by the key) in a memory-mapped file, so that
+// lists of millions of keys are neither resident nor one heap object per key.
+//
+// The records are laid out as written by the WriteKeyFile method of the
+// pacl-sk (slots), pacl-pk (point coordinates) and pacl-sposs (group
+// elements) key lists.
+package keystore
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Store is a key list in a memory-mapped file
+type Store struct {
+	KeySize  int    // bytes per key
+	NumKeys  uint64 // number of records
+	Writable bool
+
+	file       *os.File
+	data       []byte // the mapped records
+	recordSize int
+}
+
+// Open maps the key file at path (read-only)
+func Open(path string, keySize int) (*Store, error) {
+	return open(path, keySize, os.O_RDONLY, 0)
+}
+
+// Create creates (or truncates) the key file at path with room for
+// numKeys records and maps it for writing
+func Create(path string, keySize int, numKeys uint64) (*Store, error) {
+	return open(path, keySize, os.O_RDWR|os.O_CREATE|os.O_TRUNC, numKeys)
+}
+
+func open(path string, keySize int, flag int, numKeys uint64) (*Store, error) {
+
+	if keySize <= 0 {
+		return nil, errors.New("key size must be positive")
+	}
+
+	file, err := os.OpenFile(path, flag, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Store{
+		KeySize:    keySize,
+		Writable:   flag&os.O_RDWR != 0,
+		file:       file,
+		recordSize: 8 + keySize,
+	}
+
+	if s.Writable {
+		if err := file.Truncate(int64(numKeys) * int64(s.recordSize)); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	if info.Size()%int64(s.recordSize) != 0 {
+		file.Close()
+		return nil, fmt.Errorf("%v is not a list of %v-byte keys", path, keySize)
+	}
+
+	s.NumKeys = uint64(info.Size()) / uint64(s.recordSize)
+	if s.NumKeys > 0 {
+		if err := s.mmap(int(info.Size())); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// Index returns the key index of the i-th record
+func (s *Store) Index(i uint64) uint64 {
+	off := i * uint64(s.recordSize)
+	return binary.BigEndian.Uint64(s.data[off : off+8])
+}
+
+// Key returns the key of the i-th record; the slice points into the
+// mapping and is only valid until the store is closed
+func (s *Store) Key(i uint64) []byte {
+	off := i*uint64(s.recordSize) + 8
+	return s.data[off : off+uint64(s.KeySize) : off+uint64(s.KeySize)]
+}
+
+// Indices returns the key indices of all records
+func (s *Store) Indices() []uint64 {
+	indices := make([]uint64, s.NumKeys)
+	for i := range indices {
+		indices[i] = s.Index(uint64(i))
+	}
+
+	return indices
+}
+
+// Set writes the i-th record (the key must be KeySize bytes)
+func (s *Store) Set(i uint64, index uint64, key []byte) error {
+	if !s.Writable {
+		return errors.New("key store is read-only")
+	}
+
+	if len(key) != s.KeySize {
+		return fmt.Errorf("key has %v bytes instead of %v", len(key), s.KeySize)
+	}
+
+	off := i * uint64(s.recordSize)
+	binary.BigEndian.PutUint64(s.data[off:off+8], index)
+	copy(s.data[off+8:off+uint64(s.recordSize)], key)
+
+	return nil
+}
+
+// Close flushes the records (if writable) and unmaps the file
+func (s *Store) Close() error {
+	var err error
+	if s.data != nil {
+		err = s.munmap()
+		s.data = nil
+	}
+
+	if cerr := s.file.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
@@ -0,0 +1,57 @@
+package keystore
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+)
+
+func TestCreateOpen(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "keys")
+	numKeys := uint64(100)
+
+	s, err := Create(path, 16, numKeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := uint64(0); i < numKeys; i++ {
+		if err := s.Set(i, i*7, bytes.Repeat([]byte{byte(i)}, 16)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if s.Set(0, 0, make([]byte, 15)) == nil {
+		t.Fatalf("Accepted a key of the wrong size")
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = Open(path, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if s.NumKeys != numKeys {
+		t.Fatalf("Wrong number of keys. expected: %v, got: %v", numKeys, s.NumKeys)
+	}
+
+	indices := s.Indices()
+	for i := uint64(0); i < numKeys; i++ {
+		if s.Index(i) != i*7 || indices[i] != i*7 || !bytes.Equal(s.Key(i), bytes.Repeat([]byte{byte(i)}, 16)) {
+			t.Fatalf("Wrong record %v", i)
+		}
+	}
+
+	if s.Set(0, 0, make([]byte, 16)) == nil {
+		t.Fatalf("Wrote to a read-only store")
+	}
+
+	if _, err := Open(path, 20); err == nil {
+		t.Fatalf("Opened a store with the wrong key size")
+	}
+}
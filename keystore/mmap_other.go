@@ -0,0 +1,28 @@
+//go:build windows || plan9 || js
+// +build windows plan9 js
+
+package keystore
+
+import "io"
+
+// platforms without mmap read the whole file into memory instead
+// (and write it back when the store is closed)
+
+func (s *Store) mmap(size int) error {
+	data := make([]byte, size)
+	if _, err := io.ReadFull(io.NewSectionReader(s.file, 0, int64(size)), data); err != nil {
+		return err
+	}
+
+	s.data = data
+	return nil
+}
+
+func (s *Store) munmap() error {
+	if s.Writable {
+		_, err := s.file.WriteAt(s.data, 0)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,26 @@
+//go:build !windows && !plan9 && !js
+// +build !windows,!plan9,!js
+
+package keystore
+
+import "syscall"
+
+func (s *Store) mmap(size int) error {
+	prot := syscall.PROT_READ
+	if s.Writable {
+		prot |= syscall.PROT_WRITE
+	}
+
+	data, err := syscall.Mmap(int(s.file.Fd()), 0, size, prot, syscall.MAP_SHARED)
+	if err != nil {
+		return err
+	}
+
+	s.data = data
+	return nil
+}
+
+// the kernel writes the shared pages back to the file
+func (s *Store) munmap() error {
+	return syscall.Munmap(s.data)
+}
@@ -0,0 +1,145 @@
+package mailbox
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+
+	paclsk "github.com/sachaservan/pacl/pacl-sk"
+	"github.com/sachaservan/pacl/slot"
+)
+
+const nonceSize = 12 // AES-GCM nonce
+const tagSize = 16   // AES-GCM authentication tag
+
+// Client writes to and reads from mailboxes held by two servers
+type Client struct {
+	NumMailboxes uint64
+	MessageSize  int
+	params       *paclsk.KeyListParams
+}
+
+func NewClient(numMailboxes uint64, messageSize int) *Client {
+	params := &paclsk.KeyListParams{}
+	params.NumKeys = numMailboxes
+	params.FSSDomain = domainSize(numMailboxes)
+	params.PredicateType = paclsk.Equality
+
+	return &Client{
+		NumMailboxes: numMailboxes,
+		MessageSize:  messageSize,
+		params:       params,
+	}
+}
+
+// SlotSize returns the number of bytes used to store a message of messageSize bytes
+func SlotSize(messageSize int) int {
+	return nonceSize + messageSize + tagSize
+}
+
+// NewMailboxKey returns a fresh mailbox key, shared by the owner of the
+// mailbox and the clients allowed to write to it. Only the access key derived
+// from it is registered with the servers (see AccessKey): messages are
+// encrypted under another key derived from it, which the servers cannot compute.
+func NewMailboxKey() *slot.Slot {
+	return slot.NewRandom(16)
+}
+
+// AccessKey returns the PACL key of the mailbox (registered with both servers)
+func AccessKey(key *slot.Slot) *slot.Slot {
+	return slot.New(deriveKey(key, "pacl-mailbox-access"))
+}
+
+// WriteRequests encrypts msg under the mailbox key and returns the
+// write requests for both servers (in order)
+func (c *Client) WriteRequests(id uint64, key *slot.Slot, msg []byte) ([]*WriteRequest, error) {
+	if id >= c.NumMailboxes {
+		return nil, errors.New("mailbox does not exist")
+	}
+
+	if len(msg) > c.MessageSize {
+		return nil, errors.New("message is too long")
+	}
+
+	ct, err := encrypt(key, msg, c.MessageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	proofs := c.params.NewProof(id, AccessKey(key))
+
+	reqs := make([]*WriteRequest, 2)
+	for i := range reqs {
+		reqs[i] = &WriteRequest{Proof: proofs[i], Message: ct}
+	}
+
+	return reqs, nil
+}
+
+// Read combines the shares of the mailbox obtained from both servers
+// and decrypts the message stored in it (padded with zeros to MessageSize)
+func (c *Client) Read(key *slot.Slot, shares ...*slot.Slot) ([]byte, error) {
+	mailbox := slot.NewEmpty(SlotSize(c.MessageSize))
+	for _, share := range shares {
+		slot.Xor(mailbox, share)
+	}
+
+	if mailbox.IsZero() {
+		return nil, errors.New("mailbox is empty")
+	}
+
+	return decrypt(key, mailbox.Data)
+}
+
+// the servers hold the access key of every mailbox: were messages encrypted
+// under a key they can derive, they could trial-decrypt every write (GCM
+// authenticates) and learn both the message and the mailbox written to
+func encryptionKey(key *slot.Slot) []byte {
+	return deriveKey(key, "pacl-mailbox-encryption")
+}
+
+func deriveKey(key *slot.Slot, label string) []byte {
+	mac := hmac.New(sha256.New, key.Data)
+	mac.Write([]byte(label))
+	return mac.Sum(nil)[:16]
+}
+
+func encrypt(key *slot.Slot, msg []byte, messageSize int) ([]byte, error) {
+	block, err := aes.NewCipher(encryptionKey(key))
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, nonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	// pad the message so that all writes have the same size
+	padded := make([]byte, messageSize)
+	copy(padded, msg)
+
+	return aead.Seal(nonce, nonce, padded, nil), nil
+}
+
+func decrypt(key *slot.Slot, data []byte) ([]byte, error) {
+	block, err := aes.NewCipher(encryptionKey(key))
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return aead.Open(nil, data[:nonceSize], data[nonceSize:], nil)
+}
@@ -0,0 +1,139 @@
+package mailbox
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"math"
+
+	paclsk "github.com/sachaservan/pacl/pacl-sk"
+	"github.com/sachaservan/pacl/slot"
+)
+
+// Server holds one share of every mailbox along with the key list
+// used to authorize writes (one symmetric key per mailbox)
+type Server struct {
+	ServerNumber int
+	KeyList      *paclsk.KeyList
+	Mailboxes    []*slot.Slot
+	SlotSize     int // size of a mailbox in bytes (see Client.SlotSize)
+}
+
+// WriteRequest is the request sent by a client to one of the servers
+type WriteRequest struct {
+	Proof   *paclsk.ProofShare // DPF write key (also used for the PACL proof)
+	Message []byte             // encrypted message of size SlotSize
+}
+
+// PendingWrite is a write request that has been audited by the server
+// but not yet applied to the mailboxes
+type PendingWrite struct {
+	Request *WriteRequest
+	Bits    []byte             // expanded DPF write key
+	Audit   *paclsk.AuditShare // this server's audit share
+}
+
+// NewServer returns a server with numMailboxes mailboxes each holding messages
+// of messageSize bytes. Mailboxes that are not registered are protected by a key
+// derived from secret, which must be shared by (and only known to) both servers
+// since the audit requires both servers to hold the exact same key list.
+func NewServer(serverNumber int, numMailboxes uint64, messageSize int, secret []byte) *Server {
+
+	kl := &paclsk.KeyList{}
+	kl.NumKeys = numMailboxes
+	kl.FSSDomain = domainSize(numMailboxes)
+	kl.PredicateType = paclsk.Equality
+	kl.StatSecurity = 128
+	kl.FullDomain = (1<<kl.FSSDomain == numMailboxes) // only applies when domain = #keys
+	kl.Keys = make([]*slot.Slot, numMailboxes)
+	kl.KeyIndices = make([]uint64, numMailboxes)
+
+	slotSize := SlotSize(messageSize)
+	mailboxes := make([]*slot.Slot, numMailboxes)
+	for i := uint64(0); i < numMailboxes; i++ {
+		kl.KeyIndices[i] = i
+		kl.Keys[i] = unregisteredKey(secret, i, kl.StatSecurity/8)
+		mailboxes[i] = slot.NewEmpty(slotSize)
+	}
+
+	return &Server{
+		ServerNumber: serverNumber,
+		KeyList:      kl,
+		Mailboxes:    mailboxes,
+		SlotSize:     slotSize,
+	}
+}
+
+// Register sets the access key of a mailbox (see AccessKey); it must be
+// done on both servers
+func (s *Server) Register(id uint64, key *slot.Slot) error {
+	if id >= s.KeyList.NumKeys {
+		return errors.New("mailbox does not exist")
+	}
+
+	if len(key.Data) != s.KeyList.StatSecurity/8 {
+		return errors.New("mailbox key has the wrong size")
+	}
+
+	s.KeyList.Keys[id] = slot.New(append([]byte{}, key.Data...))
+	return nil
+}
+
+// Audit expands the DPF write key and computes the server's audit share.
+// The audit share must be sent to the other server before the write is applied.
+func (s *Server) Audit(req *WriteRequest) (*PendingWrite, error) {
+	if len(req.Message) != s.SlotSize {
+		return nil, errors.New("message has the wrong size")
+	}
+
+	bits := s.KeyList.ExpandDPF(req.Proof)
+	audit := s.KeyList.AuditExpanded(req.Proof, bits)
+
+	return &PendingWrite{Request: req, Bits: bits, Audit: audit}, nil
+}
+
+// Apply writes the message to the mailboxes if the audit shares of both
+// servers check out; returns false (and drops the write) otherwise
+func (s *Server) Apply(pw *PendingWrite, other *paclsk.AuditShare) bool {
+	if !s.KeyList.CheckAudit(pw.Audit, other) {
+		return false
+	}
+
+	msg := slot.New(pw.Request.Message)
+	for i := uint64(0); i < s.KeyList.NumKeys; i++ {
+		if pw.Bits[i] == 1 {
+			slot.Xor(s.Mailboxes[i], msg)
+		}
+	}
+
+	return true
+}
+
+// Read returns the server's share of the mailbox contents
+func (s *Server) Read(id uint64) (*slot.Slot, error) {
+	if id >= s.KeyList.NumKeys {
+		return nil, errors.New("mailbox does not exist")
+	}
+
+	return slot.New(append([]byte{}, s.Mailboxes[id].Data...)), nil
+}
+
+// number of DPF input bits required to address every mailbox
+func domainSize(numMailboxes uint64) uint {
+	if numMailboxes <= 2 {
+		return 1
+	}
+
+	return uint(math.Ceil(math.Log2(float64(numMailboxes))))
+}
+
+// key of a mailbox that has not been registered
+func unregisteredKey(secret []byte, id uint64, numBytes int) *slot.Slot {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, id)
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(data)
+	return slot.New(mac.Sum(nil)[:numBytes])
+}
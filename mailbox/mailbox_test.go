@@ -0,0 +1,138 @@
+package mailbox
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+
+	"github.com/sachaservan/pacl/slot"
+)
+
+// test configuration parameters
+const TestNumMailboxes = 64
+const TestMessageSize = 32
+
+func setupServers() (*Client, []*Server) {
+	secret := make([]byte, 32)
+	rand.Read(secret)
+
+	client := NewClient(TestNumMailboxes, TestMessageSize)
+	servers := []*Server{
+		NewServer(0, TestNumMailboxes, TestMessageSize, secret),
+		NewServer(1, TestNumMailboxes, TestMessageSize, secret),
+	}
+
+	return client, servers
+}
+
+// runs the two-server write protocol and returns true if the write was applied
+func processWrite(t *testing.T, servers []*Server, reqs []*WriteRequest) bool {
+
+	pendingA, err := servers[0].Audit(reqs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pendingB, err := servers[1].Audit(reqs[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the servers exchange audit shares
+	okA := servers[0].Apply(pendingA, pendingB.Audit)
+	okB := servers[1].Apply(pendingB, pendingA.Audit)
+
+	if okA != okB {
+		t.Fatalf("Servers disagree on the audit")
+	}
+
+	return okA
+}
+
+func readMailbox(t *testing.T, client *Client, servers []*Server, id uint64, key *slot.Slot) []byte {
+	shareA, _ := servers[0].Read(id)
+	shareB, _ := servers[1].Read(id)
+
+	msg, err := client.Read(key, shareA, shareB)
+	if err != nil {
+		t.Fatalf("Reading mailbox %v failed: %v", id, err)
+	}
+
+	return msg
+}
+
+func TestWriteRead(t *testing.T) {
+
+	client, servers := setupServers()
+
+	id := uint64(5)
+	key := NewMailboxKey()
+	for _, s := range servers {
+		if err := s.Register(id, AccessKey(key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	msg := []byte("hello mailbox")
+	reqs, err := client.WriteRequests(id, key, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !processWrite(t, servers, reqs) {
+		t.Fatalf("Authorized write was dropped")
+	}
+
+	res := readMailbox(t, client, servers, id, key)
+	if !bytes.Equal(bytes.TrimRight(res, "\x00"), msg) {
+		t.Fatalf("Read the wrong message. expected: %v, got: %v", msg, res)
+	}
+
+	// the servers only hold the access key, which does not decrypt the message
+	shareA, _ := servers[0].Read(id)
+	shareB, _ := servers[1].Read(id)
+	if _, err := client.Read(servers[0].KeyList.Keys[id], shareA, shareB); err == nil {
+		t.Fatalf("Decrypted the message with the access key")
+	}
+
+	// all other mailboxes must be untouched
+	for i := uint64(0); i < TestNumMailboxes; i++ {
+		if i == id {
+			continue
+		}
+
+		shareA, _ := servers[0].Read(i)
+		shareB, _ := servers[1].Read(i)
+		if _, err := client.Read(key, shareA, shareB); err == nil {
+			t.Fatalf("Mailbox %v should be empty", i)
+		}
+	}
+}
+
+func TestUnauthorizedWrite(t *testing.T) {
+
+	client, servers := setupServers()
+
+	id := uint64(17)
+	key := NewMailboxKey()
+	for _, s := range servers {
+		s.Register(id, AccessKey(key))
+	}
+
+	msg := []byte("legitimate message")
+	reqs, _ := client.WriteRequests(id, key, msg)
+	if !processWrite(t, servers, reqs) {
+		t.Fatalf("Authorized write was dropped")
+	}
+
+	// an attacker without the mailbox key tries to overwrite the message
+	reqs, _ = client.WriteRequests(id, NewMailboxKey(), []byte("malicious message"))
+	if processWrite(t, servers, reqs) {
+		t.Fatalf("Unauthorized write was applied")
+	}
+
+	res := readMailbox(t, client, servers, id, key)
+	if !bytes.Equal(bytes.TrimRight(res, "\x00"), msg) {
+		t.Fatalf("Mailbox was modified by an unauthorized write")
+	}
+}
@@ -0,0 +1,34 @@
+package paclpk
+
+import "github.com/sachaservan/pacl/ec"
+
+// Audits combine every key of the list with its DPF bit (a conditional
+// move) rather than branching on the bit, so that the time taken does not
+// leak the Hamming weight or the positions of the set bits of the
+// verifier's DPF share. The keys are combined through a keyAccumulator
+// (see the same file in pacl-sk).
+
+// keyAccumulator adds together the keys selected by the DPF bits
+type keyAccumulator interface {
+	// adds key to the sum if bit is 1
+	condAdd(key *ec.Point, bit byte)
+	sum() *ec.Point
+}
+
+type pointAccumulator struct {
+	curve *ec.EC
+	acc   *ec.Point
+}
+
+func (kl *KeyList) newKeyAccumulator() keyAccumulator {
+	identity, _ := kl.Curve.IdentityPoint()
+	return &pointAccumulator{curve: kl.Curve, acc: identity}
+}
+
+func (a *pointAccumulator) condAdd(key *ec.Point, bit byte) {
+	a.acc = a.curve.CondAdd(a.acc, key, bit)
+}
+
+func (a *pointAccumulator) sum() *ec.Point {
+	return a.acc
+}
@@ -0,0 +1,78 @@
+package paclpk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/big"
+
+	"github.com/sachaservan/pacl/algebra"
+	"github.com/sachaservan/pacl/ec"
+)
+
+// NewEpochProof is the same as NewProof but the proof additionally carries
+// a deterministic per-key, per-epoch tag which the verifiers can use to
+// enforce access quotas without learning the index of the key.
+//
+// The tag is the x-coordinate of h^x where h is hashed from the epoch.
+// The x-coordinate is invariant under the sign flip applied to x in NewProof,
+// which allows the verifiers to check the tag against h^[x].
+func (kl *KeyListParams) NewEpochProof(idx uint64, x *algebra.FieldElement, epoch uint64) []*ProofShare {
+
+	tag := kl.EpochTag(x, epoch)
+
+	shares := kl.NewProof(idx, x)
+	for _, share := range shares {
+		share.Epoch = epoch
+		share.Tag = tag
+	}
+
+	return shares
+}
+
+// EpochTag returns the rate-limiting tag of the key x for the epoch
+func (kl *KeyListParams) EpochTag(x *algebra.FieldElement, epoch uint64) []byte {
+	tag := kl.Curve.PointScalarMult(kl.epochBase(epoch), x.Int)
+	return tag.X.Bytes()
+}
+
+// hashes the epoch to a point h with unknown discrete log
+func (kl *KeyListParams) epochBase(epoch uint64) *ec.Point {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, epoch)
+	return kl.Curve.HashToPoint(append([]byte("pacl-epoch"), data...))
+}
+
+func (kl *KeyList) computeEpochTagShare(proof *ProofShare, audit *AuditShare) {
+	audit.TagShare = kl.Curve.PointScalarMult(kl.epochBase(proof.Epoch), proof.KeyShare.Int)
+	audit.Epoch = proof.Epoch
+	audit.Tag = proof.Tag
+}
+
+// checks that h^[x] reconstructs to the tag and that all verifiers
+// audited the same epoch and tag (trivially true when not in epoch mode)
+func (kl *KeyList) checkEpochTag(auditShares ...*AuditShare) bool {
+
+	if auditShares[0].Tag == nil {
+		for i := 1; i < len(auditShares); i++ {
+			if auditShares[i].Tag != nil {
+				return false
+			}
+		}
+		return true
+	}
+
+	accumulator := auditShares[0].TagShare
+	for i := 1; i < len(auditShares); i++ {
+		if auditShares[i].Epoch != auditShares[0].Epoch {
+			return false
+		}
+
+		if !bytes.Equal(auditShares[i].Tag, auditShares[0].Tag) {
+			return false
+		}
+
+		accumulator = kl.Curve.Add(accumulator, auditShares[i].TagShare)
+	}
+
+	return accumulator.X.Cmp(new(big.Int).SetBytes(auditShares[0].Tag)) == 0
+}
@@ -0,0 +1,24 @@
+package paclpk
+
+import "github.com/sachaservan/pacl/dpfeval"
+
+// EvalStrategy selects how the verifiers expand the DPF over the key list
+// (see dpfeval)
+type EvalStrategy = dpfeval.Strategy
+
+const (
+	AutoStrategy       = dpfeval.Auto
+	BatchStrategy      = dpfeval.Batch
+	FullDomainStrategy = dpfeval.FullDomain
+)
+
+// UseFullDomain returns true if the DPF is expanded over the whole
+// domain (rather than only on the key indices) when auditing
+func (kl *KeyListParams) UseFullDomain() bool {
+	return dpfeval.UseFullDomain(kl.EvalStrategy, kl.FullDomain, kl.NumKeys, kl.FSSDomain)
+}
+
+// maps the full-domain expansion of the DPF to the output of every key
+func (kl *KeyListParams) lookupFullDomain(bits []byte) []byte {
+	return dpfeval.Lookup(bits, kl.KeyIndices, kl.FSSDomain, kl.FullDomain)
+}
@@ -7,7 +7,9 @@ import (
 	"math/rand"
 
 	"github.com/sachaservan/pacl/algebra"
+	"github.com/sachaservan/pacl/dpf128"
 	"github.com/sachaservan/pacl/ec"
+	"github.com/sachaservan/pacl/keystore"
 )
 
 type PredicateType int
@@ -18,17 +20,30 @@ const (
 )
 
 type KeyListParams struct {
-	FullDomain    bool
+	FullDomain    bool         // keys are ordered by index and fill the domain
+	EvalStrategy  EvalStrategy // how the DPF is expanded (see UseFullDomain)
 	NumKeys       uint64
 	FSSDomain     uint
 	KeyIndices    []uint64
 	Curve         *ec.EC
 	PredicateType PredicateType
+
+	// 128-bit key indices used instead of KeyIndices when the FSS domain
+	// is larger than 64 bits (or to prove with dpf128 keys in a smaller one)
+	WideKeyIndices []dpf128.Index
+}
+
+// IsWide returns true if the key indices are 128 bits
+func (kl *KeyListParams) IsWide() bool {
+	return kl.FSSDomain > 64 || kl.WideKeyIndices != nil
 }
 
 type KeyList struct {
 	KeyListParams
 	PublicKeys []*ec.Point
+
+	// memory-mapped keys used instead of PublicKeys (see UseKeyStore)
+	Store *keystore.Store `json:"-"`
 }
 
 func (kl *KeyList) CloneKeyList() *KeyList {
@@ -38,8 +53,16 @@ func (kl *KeyList) CloneKeyList() *KeyList {
 	clone.NumKeys = kl.NumKeys
 	clone.FSSDomain = kl.FSSDomain
 	clone.FullDomain = kl.FullDomain
+	clone.EvalStrategy = kl.EvalStrategy
 	clone.KeyIndices = kl.KeyIndices
+	clone.WideKeyIndices = kl.WideKeyIndices
 	clone.PredicateType = kl.PredicateType
+	clone.Store = kl.Store
+
+	if kl.Store != nil {
+		clone.PublicKeys = nil
+		return &clone
+	}
 
 	for i := uint64(0); i < kl.NumKeys; i++ {
 		clone.PublicKeys[i] = kl.PublicKeys[i].Copy()
@@ -132,6 +155,10 @@ func GenerateBenchmarkKeyList(
 
 // sets g^x to g^-x
 func (kl *KeyList) FlipSignOfKeys() {
+	if kl.Store != nil {
+		panic("the keys of a key store cannot be flipped; store the flipped list instead")
+	}
+
 	for i := range kl.PublicKeys {
 		kl.PublicKeys[i] = kl.Curve.Inverse(kl.PublicKeys[i])
 	}
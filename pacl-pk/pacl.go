@@ -1,7 +1,10 @@
 package paclpk
 
 import (
+	"math/big"
+
 	"github.com/sachaservan/pacl/algebra"
+	"github.com/sachaservan/pacl/dpf128"
 	"github.com/sachaservan/pacl/ec"
 	dpf "github.com/sachaservan/vdpf"
 )
@@ -9,12 +12,51 @@ import (
 type ProofShare struct {
 	DPFKey      *dpf.DPFKey // DPF key
 	PrfKey      dpf.PrfKey  // PRF key used for the PRG in DPF construction
+	WideDPFKey  *dpf128.Key // DPF key over 128-bit indices (used instead of DPFKey)
 	ShareNumber uint
 	KeyShare    *algebra.FieldElement
+	Epoch       uint64 // epoch of the rate-limiting tag (epoch mode only)
+	Tag         []byte // per-key, per-epoch tag (epoch mode only)
 }
 
 type AuditShare struct {
-	Share *ec.Point
+	Share    *ec.Point
+	TagShare *ec.Point // h^[x] for h derived from the epoch (epoch mode only)
+	Epoch    uint64
+	Tag      []byte
+}
+
+// Size returns the number of bytes the prover sends to one verifier
+func (share *ProofShare) Size() int {
+	size := dpfKeySize(share.DPFKey) + share.WideDPFKey.Size() + share.KeyShare.Size()
+	if share.DPFKey != nil {
+		size += len(share.PrfKey)
+	}
+
+	if share.Tag != nil {
+		size += 8 + len(share.Tag) // epoch and tag
+	}
+
+	return size
+}
+
+// Size returns the number of bytes a verifier sends to the other verifier
+func (share *AuditShare) Size() int {
+	size := share.Share.Size() + share.TagShare.Size()
+	if share.Tag != nil {
+		size += 8 + len(share.Tag) // epoch and tag
+	}
+
+	return size
+}
+
+// size of the key material of a DPF key
+func dpfKeySize(key *dpf.DPFKey) int {
+	if key == nil {
+		return 0
+	}
+
+	return len(key.Bytes)
 }
 
 func (kl *KeyListParams) NewProof(idx uint64, x *algebra.FieldElement) []*ProofShare {
@@ -32,7 +74,38 @@ func (kl *KeyListParams) NewProof(idx uint64, x *algebra.FieldElement) []*ProofS
 
 	// flip x based on which server the key is "retrieved" from
 	resB := pf.BatchEval(keyB, []uint64{idx})
-	if resB[0] == 0 {
+
+	shares := kl.newProofShares(x, resB[0])
+	shares[0].PrfKey = pf.PrfKey
+	shares[0].DPFKey = keyA
+	shares[1].PrfKey = pf.PrfKey
+	shares[1].DPFKey = keyB
+
+	return shares
+}
+
+// NewWideProof is the same as NewProof but for key lists with 128-bit indices
+func (kl *KeyListParams) NewWideProof(idx dpf128.Index, x *algebra.FieldElement) []*ProofShare {
+
+	if kl.NumKeys == 0 {
+		panic("list size is set to zero; something is wrong")
+	}
+
+	keyA, keyB := dpf128.GenKeys(idx, kl.FSSDomain)
+	resB, _ := dpf128.Eval(keyB, idx)
+
+	shares := kl.newProofShares(x, resB)
+	shares[0].WideDPFKey = keyA
+	shares[1].WideDPFKey = keyB
+
+	return shares
+}
+
+// returns the proof shares of both verifiers (without DPF keys) given
+// the output of the second verifier's DPF key on the index
+func (kl *KeyListParams) newProofShares(x *algebra.FieldElement, resB byte) []*ProofShare {
+
+	if resB == 0 {
 		x = kl.Curve.Field.Negate(x)
 	}
 
@@ -45,15 +118,11 @@ func (kl *KeyListParams) NewProof(idx uint64, x *algebra.FieldElement) []*ProofS
 	// share for verifier A
 	shares[0] = &ProofShare{}
 	shares[0].ShareNumber = 0
-	shares[0].PrfKey = pf.PrfKey
-	shares[0].DPFKey = keyA
 	shares[0].KeyShare = keyShares[0]
 
 	// share for verifier B
 	shares[1] = &ProofShare{}
 	shares[1].ShareNumber = 1
-	shares[1].PrfKey = pf.PrfKey
-	shares[1].DPFKey = keyB
 	shares[1].KeyShare = keyShares[1]
 
 	return shares
@@ -70,16 +139,36 @@ func (kl *KeyList) CheckAudit(auditShares ...*AuditShare) bool {
 		accumulator = kl.Curve.Add(accumulator, auditShares[i].Share)
 	}
 
-	return kl.Curve.IsIdentity(accumulator)
+	return kl.Curve.IsIdentity(accumulator) && kl.checkEpochTag(auditShares...)
 }
 
 func (kl *KeyList) ExpandDPF(proof *ProofShare) []byte {
 
+	if kl.IsWide() != (proof.WideDPFKey != nil) {
+		// the proof does not match the index size of the list; selecting
+		// no key guarantees that the audit fails
+		return make([]byte, kl.NumKeys)
+	}
+
+	if kl.IsWide() {
+		if proof.WideDPFKey.Domain != kl.FSSDomain {
+			return make([]byte, kl.NumKeys)
+		}
+
+		bits, err := dpf128.BatchEval(proof.WideDPFKey, kl.WideKeyIndices)
+		if err != nil {
+			// malformed key
+			return make([]byte, kl.NumKeys)
+		}
+
+		return bits
+	}
+
 	pf := dpf.ServerDPFInitialize(proof.PrfKey)
 
-	if kl.FullDomain {
+	if kl.UseFullDomain() {
 		// run the optimized full-domain evaluation strategy
-		return pf.FullDomainEval(proof.DPFKey)
+		return kl.lookupFullDomain(pf.FullDomainEval(proof.DPFKey))
 	} else {
 		return pf.BatchEval(proof.DPFKey, kl.KeyIndices)
 	}
@@ -88,19 +177,27 @@ func (kl *KeyList) ExpandDPF(proof *ProofShare) []byte {
 // uses the expanded DPF bits to "select" the public key in the keylist
 // over which the audit is going to be performed
 func (kl *KeyList) computeAudit(proof *ProofShare, bits []byte) *AuditShare {
+	return kl.accumulateAudit(kl.newKeyAccumulator(), proof, bits)
+}
 
-	// final result
-	accumulator, _ := kl.Curve.IdentityPoint()
+// computeAudit with the keys combined by acc
+func (kl *KeyList) accumulateAudit(acc keyAccumulator, proof *ProofShare, bits []byte) *AuditShare {
 
+	key := &ec.Point{X: new(big.Int), Y: new(big.Int)}
 	for i := uint64(0); i < kl.NumKeys; i++ {
-		if bits[i] == 1 {
-			// add result to running sum (mod q)
-			accumulator = kl.Curve.Add(accumulator, kl.PublicKeys[i])
-		}
+		// add result to running sum (mod q) with a conditional move
+		// so that the time taken does not depend on the DPF bits
+		acc.condAdd(kl.keyAt(i, key), bits[i])
 	}
 
+	// final result
 	share, _ := kl.Curve.NewPoint(proof.KeyShare.Int)
-	accumulator = kl.Curve.Add(accumulator, share)
+	accumulator := kl.Curve.Add(acc.sum(), share)
+
+	audit := &AuditShare{Share: accumulator}
+	if proof.Tag != nil {
+		kl.computeEpochTagShare(proof, audit)
+	}
 
-	return &AuditShare{Share: accumulator}
+	return audit
 }
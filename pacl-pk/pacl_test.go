@@ -1,8 +1,21 @@
 package paclpk
 
 import (
+	"bytes"
 	"crypto/elliptic"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
 	"testing"
+
+	"github.com/sachaservan/pacl/algebra"
+	"github.com/sachaservan/pacl/dpf128"
+	"github.com/sachaservan/pacl/ec"
+	"github.com/sachaservan/pacl/internal/benchsweep"
+	"github.com/sachaservan/pacl/keystore"
 )
 
 // test configuration parameters
@@ -38,6 +51,357 @@ func TestProveAuditVerify(t *testing.T) {
 	}
 }
 
+func TestProveAuditVerifyWide(t *testing.T) {
+
+	kl, key, _ := GenerateTestingKeyList(TestNumKeys, TestFSSDomain, elliptic.P256(), Equality, 0)
+	kl.FSSDomain = dpf128.MaxDomain
+	kl.KeyIndices = nil
+	kl.WideKeyIndices = make([]dpf128.Index, kl.NumKeys)
+	for i := range kl.WideKeyIndices {
+		kl.WideKeyIndices[i] = dpf128.HashToIndex([]byte{byte(i), byte(i >> 8)})
+	}
+
+	klB := kl.CloneKeyList()
+	klB.FlipSignOfKeys()
+
+	idx := kl.WideKeyIndices[42]
+	proofShares := kl.NewWideProof(idx, key)
+	if !kl.CheckAudit(kl.Audit(proofShares[0]), klB.Audit(proofShares[1])) {
+		t.Fatalf("CheckAudit failed")
+	}
+
+	// the wrong key must be rejected
+	proofShares = kl.NewWideProof(idx, kl.Curve.Field.Add(key, kl.Curve.Field.MulIdentity()))
+	if kl.CheckAudit(kl.Audit(proofShares[0]), klB.Audit(proofShares[1])) {
+		t.Fatalf("CheckAudit accepted the wrong key")
+	}
+
+	// malformed keys must be rejected
+	proofShares = kl.NewWideProof(idx, key)
+	for _, share := range proofShares {
+		share.WideDPFKey.CWBitsL = nil
+	}
+
+	if kl.CheckAudit(kl.Audit(proofShares[0]), klB.Audit(proofShares[1])) {
+		t.Fatalf("CheckAudit accepted a malformed DPF key")
+	}
+}
+
+func TestEvalStrategies(t *testing.T) {
+
+	// dense list: 512 keys in a domain of 2^10
+	kl, key, idx := GenerateTestingKeyList(TestNumKeys, 10, elliptic.P256(), Equality, 0)
+	distinctIndices(kl.KeyIndices, 10)
+	idx = kl.KeyIndices[0]
+	if !kl.UseFullDomain() {
+		t.Fatalf("Expected the full-domain strategy for a dense list")
+	}
+
+	proofShares := kl.NewProof(idx, key)
+
+	for _, strategy := range []EvalStrategy{AutoStrategy, BatchStrategy, FullDomainStrategy} {
+		kl.EvalStrategy = strategy
+
+		klB := kl.CloneKeyList()
+		klB.FlipSignOfKeys()
+
+		auditA := kl.Audit(proofShares[0])
+		auditB := klB.Audit(proofShares[1])
+
+		if !kl.CheckAudit(auditA, auditB) {
+			t.Fatalf("CheckAudit failed with strategy %v", strategy)
+		}
+	}
+}
+
+func TestStreamAudit(t *testing.T) {
+
+	kl, key, idx := GenerateTestingKeyList(
+		TestNumKeys, TestFSSDomain, elliptic.P256(), TestPredicate, TestNumSubkeys)
+
+	klB := kl.CloneKeyList()
+	klB.FlipSignOfKeys()
+
+	var file bytes.Buffer
+	if err := kl.WriteKeyFile(&file); err != nil {
+		t.Fatal(err)
+	}
+
+	src := NewFileKeySource(bytes.NewReader(file.Bytes()), kl.Curve)
+
+	for _, epoch := range []bool{false, true} {
+		proofShares := kl.NewProof(idx, key)
+		if epoch {
+			proofShares = kl.NewEpochProof(idx, key, 1)
+		}
+
+		// the chunk size does not divide the list size
+		auditA, err := kl.StreamAudit(proofShares[0], src, 1000)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		auditB, err := klB.StreamAudit(proofShares[1], klB, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !kl.CheckAudit(auditA, auditB) {
+			t.Fatalf("CheckAudit failed on streamed audits (epoch mode: %v)", epoch)
+		}
+	}
+}
+
+func TestDataIndependentAudit(t *testing.T) {
+
+	kl, key, idx := GenerateTestingKeyList(
+		TestNumKeys, TestFSSDomain, elliptic.P256(), TestPredicate, TestNumSubkeys)
+	proofShares := kl.NewProof(idx, key)
+
+	var traces [][]string
+	for _, bits := range testBitPatterns(kl.NumKeys) {
+		acc := &recordingAccumulator{keyAccumulator: kl.newKeyAccumulator()}
+		kl.accumulateAudit(acc, proofShares[0], bits)
+		traces = append(traces, acc.ops)
+	}
+	checkSameOps(t, traces)
+
+	// streamed audits, with the DPF outputs replaced by the patterns
+	traces = nil
+	for _, bits := range testBitPatterns(kl.NumKeys) {
+		acc := &recordingAccumulator{keyAccumulator: kl.newKeyAccumulator()}
+		if _, err := kl.streamAudit(acc, proofShares[0], kl, 100, patternEval(bits)); err != nil {
+			t.Fatal(err)
+		}
+		traces = append(traces, acc.ops)
+	}
+	checkSameOps(t, traces)
+
+	// the recorded operations are those of Audit
+	acc := &recordingAccumulator{keyAccumulator: kl.newKeyAccumulator()}
+	audit := kl.accumulateAudit(acc, proofShares[0], kl.ExpandDPF(proofShares[0]))
+	if !kl.Curve.IsEqual(audit.Share, kl.Audit(proofShares[0]).Share) {
+		t.Fatalf("Recorded audit does not match Audit")
+	}
+}
+
+func TestKeyStore(t *testing.T) {
+
+	kl, key, idx := GenerateTestingKeyList(
+		TestNumKeys, TestFSSDomain, elliptic.P256(), TestPredicate, TestNumSubkeys)
+
+	klB := kl.CloneKeyList()
+	klB.FlipSignOfKeys()
+
+	// each verifier maps its own list
+	dir := t.TempDir()
+	writeKeyFile(t, filepath.Join(dir, "a"), kl.WriteKeyFile)
+	writeKeyFile(t, filepath.Join(dir, "b"), klB.WriteKeyFile)
+
+	stored := make([]*KeyList, 2)
+	for i, name := range []string{"a", "b"} {
+		store, err := keystore.Open(filepath.Join(dir, name), 2*coordinateSize(kl.Curve))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer store.Close()
+
+		stored[i] = &KeyList{KeyListParams: kl.KeyListParams}
+		if err := stored[i].UseKeyStore(store); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	proofShares := kl.NewEpochProof(idx, key, 1)
+	auditA := stored[0].Audit(proofShares[0])
+	auditB := stored[1].CloneKeyList().Audit(proofShares[1])
+
+	if !kl.CheckAudit(auditA, auditB) {
+		t.Fatalf("CheckAudit failed with key stores")
+	}
+}
+
+func TestEpochTags(t *testing.T) {
+
+	kl, key, idx := GenerateTestingKeyList(
+		TestNumKeys,
+		TestFSSDomain,
+		elliptic.P256(),
+		TestPredicate,
+		TestNumSubkeys)
+
+	klB := kl.CloneKeyList()
+	klB.FlipSignOfKeys()
+
+	tags := make(map[string]int)
+	for epoch := uint64(0); epoch < 2; epoch++ {
+		for i := 0; i < 5; i++ {
+			proofShares := kl.NewEpochProof(idx, key, epoch)
+
+			auditA := kl.Audit(proofShares[0])
+			auditB := klB.Audit(proofShares[1])
+
+			if !kl.CheckAudit(auditA, auditB) {
+				t.Fatalf("CheckAudit failed")
+			}
+
+			tags[string(auditA.Tag)]++
+		}
+	}
+
+	if len(tags) != 2 {
+		t.Fatalf("Expected one tag per epoch, got %v distinct tags", len(tags))
+	}
+
+	// a tag for the wrong key must be rejected
+	proofShares := kl.NewEpochProof(idx, key, 0)
+	proofShares[0].Tag = kl.EpochTag(kl.Curve.Field.RandomElement(), 0)
+	proofShares[1].Tag = proofShares[0].Tag
+
+	auditA := kl.Audit(proofShares[0])
+	auditB := klB.Audit(proofShares[1])
+	if kl.CheckAudit(auditA, auditB) {
+		t.Fatalf("CheckAudit accepted an invalid tag")
+	}
+}
+
+func TestThresholdAudit(t *testing.T) {
+
+	kl, key, idx := GenerateTestingKeyList(
+		TestNumKeys,
+		TestFSSDomain,
+		elliptic.P256(),
+		TestPredicate,
+		TestNumSubkeys)
+
+	// 3 verifiers of which any 2 can audit
+	proofs, err := kl.NewThresholdProof(idx, key, 2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, online := range [][]int{{0, 1}, {0, 2}, {2, 1}, {0, 1, 2}} {
+		if !runThresholdAudit(t, kl, proofs, online) {
+			t.Fatalf("CheckThresholdAudit failed for verifiers %v", online)
+		}
+	}
+
+	// 4 verifiers of which any 3 can audit
+	proofs, err = kl.NewThresholdProof(idx, key, 3, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, online := range [][]int{{0, 1, 2}, {0, 1, 3}, {0, 2, 3}, {1, 2, 3}, {3, 1, 0, 2}} {
+		if !runThresholdAudit(t, kl, proofs, online) {
+			t.Fatalf("CheckThresholdAudit failed for verifiers %v", online)
+		}
+	}
+
+	// a proof for the wrong key
+	wrong, _ := kl.NewThresholdProof(idx, kl.Curve.Field.RandomElement(), 3, 4)
+	if runThresholdAudit(t, kl, wrong, []int{1, 2, 3}) {
+		t.Fatalf("CheckThresholdAudit accepted the wrong key")
+	}
+
+	if _, err := kl.ThresholdAudit(proofs[0], []int{0, 1}); err == nil {
+		t.Fatalf("Audited with fewer verifiers than the threshold")
+	}
+
+	if _, err := kl.ThresholdAudit(proofs[1], []int{0, 2, 3}); err == nil {
+		t.Fatalf("Audited for an offline verifier")
+	}
+
+	if _, err := kl.ThresholdAudit(proofs[1], []int{0, 1, 1}); err == nil {
+		t.Fatalf("Audited with a repeated verifier")
+	}
+
+	if _, err := kl.NewThresholdProof(idx, key, 1, 3); err == nil {
+		t.Fatalf("Generated a proof for a threshold of 1")
+	}
+
+	if _, err := kl.NewThresholdProof(idx, key, 4, 3); err == nil {
+		t.Fatalf("Generated a proof for a threshold above the number of verifiers")
+	}
+}
+
+func TestEncoding(t *testing.T) {
+
+	kl, key, idx := GenerateTestingKeyList(TestNumKeys, TestFSSDomain, elliptic.P256(), Equality, 0)
+	klB := kl.CloneKeyList()
+	klB.FlipSignOfKeys()
+
+	// the same list with 128-bit indices
+	wide := kl.CloneKeyList()
+	wide.FSSDomain = dpf128.MaxDomain
+	wide.KeyIndices = nil
+	wide.WideKeyIndices = make([]dpf128.Index, wide.NumKeys)
+	for i := range wide.WideKeyIndices {
+		wide.WideKeyIndices[i] = dpf128.Index{Lo: kl.KeyIndices[i]}
+	}
+	wideB := wide.CloneKeyList()
+	wideB.FlipSignOfKeys()
+
+	lists := [][2]*KeyList{{kl, klB}, {kl, klB}, {wide, wideB}}
+	proofs := [][]*ProofShare{
+		kl.NewProof(idx, key),
+		kl.NewEpochProof(idx, key, 7),
+		wide.NewWideProof(dpf128.Index{Lo: idx}, key),
+	}
+
+	for i, proofShares := range proofs {
+		var audits [2]*AuditShare
+		for j, proof := range proofShares {
+			list := lists[i][j]
+			b, err := list.EncodeProof(proof)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			decoded, err := list.DecodeProof(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := list.DecodeProof(b[:len(b)-1]); err == nil {
+				t.Fatalf("Decoded a truncated proof share")
+			}
+
+			if _, err := list.DecodeProof(append(b, 0)); err == nil {
+				t.Fatalf("Decoded a proof share with trailing bytes")
+			}
+
+			audit := list.Audit(decoded)
+			if b, err = list.EncodeAudit(audit); err != nil {
+				t.Fatal(err)
+			}
+
+			if audits[j], err = list.DecodeAudit(b); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := list.DecodeAudit(b[:len(b)-1]); err == nil {
+				t.Fatalf("Decoded a truncated audit share")
+			}
+		}
+
+		if !kl.CheckAudit(audits[0], audits[1]) {
+			t.Fatalf("CheckAudit of decoded shares failed (proof %v)", i)
+		}
+	}
+
+	// points that are not on the curve must be rejected
+	b, err := kl.EncodeAudit(kl.Audit(proofs[0][0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b[len(b)-1] ^= 1
+	if _, err := kl.DecodeAudit(b); err == nil {
+		t.Fatalf("Decoded a point that is not on the curve")
+	}
+}
+
 func BenchmarkBaseline(b *testing.B) {
 
 	numKeys := uint64(1000)
@@ -96,3 +460,142 @@ func BenchmarkPACLMany(b *testing.B) {
 		kl.CheckAudit(audit, audit)
 	}
 }
+
+// returns the key lists of both verifiers over which the per-operation
+// benchmarks run (see benchsweep.Run)
+func benchKeyLists(numKeys uint64, inclusion bool) (*KeyList, *KeyList, *algebra.FieldElement, uint64) {
+	pred := Equality
+	if inclusion {
+		pred = Inclusion
+	}
+
+	kl, key, idx := GenerateTestingKeyList(numKeys, TestFSSDomain, elliptic.P256(), pred, TestNumSubkeys)
+	klB := kl.CloneKeyList()
+	klB.FlipSignOfKeys()
+
+	return kl, klB, key, idx
+}
+
+func BenchmarkNewProof(b *testing.B) {
+	benchsweep.Run(b, func(b *testing.B, numKeys uint64, inclusion bool) {
+		kl, _, key, idx := benchKeyLists(numKeys, inclusion)
+
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			kl.NewProof(idx, key)
+		}
+	})
+}
+
+func BenchmarkAudit(b *testing.B) {
+	benchsweep.Run(b, func(b *testing.B, numKeys uint64, inclusion bool) {
+		kl, _, key, idx := benchKeyLists(numKeys, inclusion)
+		shares := kl.NewProof(idx, key)
+
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			kl.Audit(shares[0])
+		}
+	})
+}
+
+func BenchmarkCheckAudit(b *testing.B) {
+	benchsweep.Run(b, func(b *testing.B, numKeys uint64, inclusion bool) {
+		kl, klB, key, idx := benchKeyLists(numKeys, inclusion)
+		shares := kl.NewProof(idx, key)
+		auditA := kl.Audit(shares[0])
+		auditB := klB.Audit(shares[1])
+
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			if !kl.CheckAudit(auditA, auditB) {
+				b.Fatalf("CheckAudit failed")
+			}
+		}
+	})
+}
+
+// records the operations an audit performs on the keys
+// (and forwards them to the accumulator of audits)
+type recordingAccumulator struct {
+	keyAccumulator
+	ops []string
+}
+
+func (acc *recordingAccumulator) condAdd(key *ec.Point, bit byte) {
+	acc.ops = append(acc.ops, "add")
+	acc.keyAccumulator.condAdd(key, bit)
+}
+
+// DPF output bits with no, every and random bits set
+func testBitPatterns(n uint64) [][]byte {
+	zeros := make([]byte, n)
+	ones := make([]byte, n)
+	random := make([]byte, n)
+	for i := range ones {
+		ones[i] = 1
+		random[i] = byte(rand.Intn(2))
+	}
+
+	return [][]byte{zeros, ones, random}
+}
+
+// returns the bits in order, in place of the evaluations of a DPF
+func patternEval(bits []byte) func(indices []uint64) []byte {
+	return func(indices []uint64) []byte {
+		res := bits[:len(indices)]
+		bits = bits[len(indices):]
+		return res
+	}
+}
+
+// checks that the audits performed the same sequence of operations
+func checkSameOps(t *testing.T, traces [][]string) {
+	for _, ops := range traces[1:] {
+		if len(ops) == 0 || !reflect.DeepEqual(ops, traces[0]) {
+			t.Fatalf("Sequence of audit operations depends on the DPF bits")
+		}
+	}
+}
+
+// replaces the indices by distinct random indices in a domain of 2^fssDomain
+func distinctIndices(indices []uint64, fssDomain uint) {
+	for i, idx := range rand.Perm(1 << fssDomain)[:len(indices)] {
+		indices[i] = uint64(idx)
+	}
+}
+
+func writeKeyFile(t *testing.T, path string, write func(io.Writer) error) {
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := write(file); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := file.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// runs a threshold audit with the online verifiers and checks it
+func runThresholdAudit(t *testing.T, kl *KeyList, proofs []*ThresholdProofShare, online []int) bool {
+	sorted := append([]int{}, online...)
+	sort.Ints(sorted)
+
+	audits := make([]*ThresholdAuditShare, len(sorted))
+	for i, v := range sorted {
+		audit, err := kl.ThresholdAudit(proofs[v], online)
+		if err != nil {
+			t.Fatal(err)
+		}
+		audits[i] = audit
+	}
+
+	return kl.CheckThresholdAudit(audits...)
+}
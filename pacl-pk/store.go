@@ -0,0 +1,45 @@
+package paclpk
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/sachaservan/pacl/ec"
+	"github.com/sachaservan/pacl/keystore"
+)
+
+// UseKeyStore replaces the public keys of the list by the records of the
+// store (e.g., a file written by WriteKeyFile), which audits then read in
+// place. The second verifier stores its flipped list (see FlipSignOfKeys).
+func (kl *KeyList) UseKeyStore(store *keystore.Store) error {
+	if kl.IsWide() {
+		return errors.New("key stores hold 64-bit key indices")
+	}
+
+	size := 2 * coordinateSize(kl.Curve)
+	if store.KeySize != size {
+		return fmt.Errorf("store has %v-byte keys instead of %v", store.KeySize, size)
+	}
+
+	kl.Store = store
+	kl.PublicKeys = nil
+	kl.NumKeys = store.NumKeys
+	kl.KeyIndices = store.Indices()
+
+	return nil
+}
+
+// returns the i-th public key of the list; keys in a store
+// are decoded into point (which is returned)
+func (kl *KeyList) keyAt(i uint64, point *ec.Point) *ec.Point {
+	if kl.Store == nil {
+		return kl.PublicKeys[i]
+	}
+
+	key := kl.Store.Key(i)
+	size := len(key) / 2
+	point.X.SetBytes(key[:size])
+	point.Y.SetBytes(key[size:])
+
+	return point
+}
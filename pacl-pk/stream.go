@@ -0,0 +1,180 @@
+package paclpk
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/big"
+
+	"github.com/sachaservan/pacl/ec"
+	dpf "github.com/sachaservan/vdpf"
+)
+
+// DefaultChunkSize is the number of keys that StreamAudit reads
+// and evaluates the DPF on at a time
+const DefaultChunkSize = 1 << 16
+
+// KeySource provides the entries (key index and public key) of a key list
+// in order, so that audits can stream over lists that are not in memory
+type KeySource interface {
+	// ReadEntries reads the entries [start, start+len(indices)) of the list;
+	// the points in keys may be reused if they are not nil
+	ReadEntries(start uint64, indices []uint64, keys []*ec.Point) error
+}
+
+// ReadEntries implements KeySource for a key list that is in memory
+func (kl *KeyList) ReadEntries(start uint64, indices []uint64, keys []*ec.Point) error {
+	if start+uint64(len(indices)) > kl.NumKeys {
+		return io.ErrUnexpectedEOF
+	}
+
+	copy(indices, kl.KeyIndices[start:])
+	if kl.Store == nil {
+		copy(keys, kl.PublicKeys[start:])
+		return nil
+	}
+
+	for i := range keys {
+		if keys[i] == nil {
+			keys[i] = &ec.Point{X: new(big.Int), Y: new(big.Int)}
+		}
+		kl.keyAt(start+uint64(i), keys[i])
+	}
+
+	return nil
+}
+
+// StreamAudit is the same as Audit but reads the entries of the list from
+// src and evaluates the DPF on chunkSize entries at a time, so that its memory
+// does not depend on the size of the list. Only the parameters of kl are used
+// (its keys and indices may be nil). Chunks are always batch evaluated.
+func (kl *KeyList) StreamAudit(proof *ProofShare, src KeySource, chunkSize int) (*AuditShare, error) {
+
+	if kl.IsWide() || proof.DPFKey == nil {
+		return nil, errors.New("streaming audits require 64-bit key indices")
+	}
+
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+
+	pf := dpf.ServerDPFInitialize(proof.PrfKey)
+	eval := func(indices []uint64) []byte {
+		return pf.BatchEval(proof.DPFKey, indices)
+	}
+
+	return kl.streamAudit(kl.newKeyAccumulator(), proof, src, chunkSize, eval)
+}
+
+// StreamAudit with the keys combined by acc and the DPF evaluated by eval
+func (kl *KeyList) streamAudit(
+	acc keyAccumulator,
+	proof *ProofShare,
+	src KeySource,
+	chunkSize int,
+	eval func(indices []uint64) []byte,
+) (*AuditShare, error) {
+
+	indices := make([]uint64, chunkSize)
+	keys := make([]*ec.Point, chunkSize)
+
+	for start := uint64(0); start < kl.NumKeys; start += uint64(chunkSize) {
+		n := kl.NumKeys - start
+		if n > uint64(chunkSize) {
+			n = uint64(chunkSize)
+		}
+
+		if err := src.ReadEntries(start, indices[:n], keys[:n]); err != nil {
+			return nil, err
+		}
+
+		bits := eval(indices[:n])
+		for i := uint64(0); i < n; i++ {
+			acc.condAdd(keys[i], bits[i])
+		}
+	}
+
+	share, _ := kl.Curve.NewPoint(proof.KeyShare.Int)
+	accumulator := kl.Curve.Add(acc.sum(), share)
+
+	audit := &AuditShare{Share: accumulator}
+	if proof.Tag != nil {
+		kl.computeEpochTagShare(proof, audit)
+	}
+
+	return audit, nil
+}
+
+// size of each coordinate of a point in a key file
+func coordinateSize(curve *ec.EC) int {
+	return (curve.Curve.Params().BitSize + 7) / 8
+}
+
+// WriteKeyFile writes the entries of the list as fixed-width records
+// (the 8-byte big-endian key index followed by the x and y coordinates
+// of the public key) that can be read back with a FileKeySource
+func (kl *KeyList) WriteKeyFile(w io.Writer) error {
+
+	size := coordinateSize(kl.Curve)
+	record := make([]byte, 8+2*size)
+
+	point := &ec.Point{X: new(big.Int), Y: new(big.Int)}
+	for i := uint64(0); i < kl.NumKeys; i++ {
+		key := kl.keyAt(i, point)
+		if key.X.BitLen() > 8*size || key.Y.BitLen() > 8*size {
+			return errors.New("public key is not a point of the curve")
+		}
+
+		binary.BigEndian.PutUint64(record, kl.KeyIndices[i])
+		key.X.FillBytes(record[8 : 8+size])
+		key.Y.FillBytes(record[8+size:])
+
+		if _, err := w.Write(record); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// FileKeySource reads the entries of a key list from the fixed-width
+// records written by WriteKeyFile (e.g., from an *os.File)
+type FileKeySource struct {
+	r    io.ReaderAt
+	size int // size of each coordinate
+	buf  []byte
+}
+
+// NewFileKeySource returns a source that reads points of the curve from r
+func NewFileKeySource(r io.ReaderAt, curve *ec.EC) *FileKeySource {
+	return &FileKeySource{r: r, size: coordinateSize(curve)}
+}
+
+// ReadEntries implements KeySource
+func (src *FileKeySource) ReadEntries(start uint64, indices []uint64, keys []*ec.Point) error {
+
+	recordSize := 8 + 2*src.size
+	size := recordSize * len(indices)
+	if len(src.buf) < size {
+		src.buf = make([]byte, size)
+	}
+
+	// ReadAt may return io.EOF along with the last records
+	n, err := src.r.ReadAt(src.buf[:size], int64(start)*int64(recordSize))
+	if n < size {
+		return err
+	}
+
+	for i := range indices {
+		record := src.buf[i*recordSize : (i+1)*recordSize]
+		indices[i] = binary.BigEndian.Uint64(record)
+
+		if keys[i] == nil {
+			keys[i] = &ec.Point{X: new(big.Int), Y: new(big.Int)}
+		}
+		keys[i].X.SetBytes(record[8 : 8+src.size])
+		keys[i].Y.SetBytes(record[8+src.size:])
+	}
+
+	return nil
+}
@@ -0,0 +1,271 @@
+package paclpk
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/sachaservan/pacl/algebra"
+	"github.com/sachaservan/pacl/ec"
+)
+
+// Threshold mode: the proof is split among n verifiers (numbered 0, ..., n-1)
+// so that any t >= 2 of them can audit it while the others are offline.
+//
+// DPFs only have two keys, so the two lowest-numbered online verifiers audit
+// with the DPF keys of their pair. With t online verifiers, the higher one is
+// at most verifier n-t+1, so the prover generates DPF keys (see NewProof) for
+// every pair of the verifiers 0, ..., n-t+1 only. The key x, negated according
+// to the pair's DPF keys as in NewProof, is Shamir-shared among all n
+// verifiers with threshold t (independently for every pair), and the online
+// verifiers turn their shares into additive shares of x with Lagrange
+// coefficients. The verifiers outside the pair send g^(λ[x]); the higher
+// verifier of the pair audits the list as is (it must not be flipped) and
+// negates the result itself.
+//
+// Privacy, when fewer than t verifiers collude:
+//   - the colluding verifiers learn nothing about x from their Shamir shares
+//     (t verifiers can recover x and prove in the prover's name);
+//   - the index stays hidden unless both verifiers of a pair collude: they
+//     hold the two DPF keys of their pair and learn the index, as the two
+//     verifiers of NewProof do if they collude;
+//   - audit shares are masked by the DPF selection or are points g^(λ[x]);
+//     together they reveal at most the public key of the prover.
+//
+// Epoch tags are not supported in threshold mode.
+
+// ThresholdProofShare is the share of a threshold proof of one verifier
+type ThresholdProofShare struct {
+	Verifier     int // number of the verifier (0, ..., n-1)
+	NumVerifiers int
+	Threshold    int
+
+	// the DPF keys of every pair the verifier belongs to (nil for other
+	// pairs); the key shares are not set
+	Pairs []*ProofShare
+
+	// the Shamir share of the (signed) key of every pair
+	KeyShares []*algebra.ShamirShare
+}
+
+// ThresholdAuditShare is the audit share of one of the online verifiers
+type ThresholdAuditShare struct {
+	Verifier int
+	Online   []int // the verifiers taking part in the audit
+	Share    *ec.Point
+}
+
+// Size returns the number of bytes the prover sends to one verifier
+func (share *ThresholdProofShare) Size() int {
+	size := 0
+	for _, pair := range share.Pairs {
+		if pair != nil {
+			size += pair.Size()
+		}
+	}
+
+	for _, keyShare := range share.KeyShares {
+		size += keyShare.Y.Size()
+	}
+
+	return size
+}
+
+// number of verifiers that can be in the auditing pair
+func numPairVerifiers(t, n int) int {
+	return n - t + 2
+}
+
+// number of the pair of verifiers i < j of m in lexicographic order
+func pairNumber(i, j, m int) int {
+	return i*m - i*(i+1)/2 + (j - i - 1)
+}
+
+// NewThresholdProof returns the proof shares of the n verifiers of which any t can audit
+func (kl *KeyListParams) NewThresholdProof(idx uint64, x *algebra.FieldElement, t, n int) ([]*ThresholdProofShare, error) {
+
+	if kl.NumKeys == 0 {
+		panic("list size is set to zero; something is wrong")
+	}
+
+	if kl.IsWide() {
+		return nil, errors.New("threshold proofs require 64-bit key indices")
+	}
+
+	if t < 2 || t > n {
+		return nil, fmt.Errorf("cannot audit with %v of %v verifiers", t, n)
+	}
+
+	m := numPairVerifiers(t, n)
+	numPairs := m * (m - 1) / 2
+	shares := make([]*ThresholdProofShare, n)
+	for v := range shares {
+		shares[v] = &ThresholdProofShare{
+			Verifier:     v,
+			NumVerifiers: n,
+			Threshold:    t,
+			Pairs:        make([]*ProofShare, numPairs),
+			KeyShares:    make([]*algebra.ShamirShare, numPairs),
+		}
+	}
+
+	for i := 0; i < m; i++ {
+		for j := i + 1; j < m; j++ {
+			pair := pairNumber(i, j, m)
+			proof := kl.NewProof(idx, x)
+
+			// the key shares of the pair add up to x, negated according to the DPF keys
+			pairX := kl.Curve.Field.Add(proof[0].KeyShare, proof[1].KeyShare)
+			keyShares, err := kl.Curve.Field.ShamirShares(pairX, t, n)
+			if err != nil {
+				return nil, err
+			}
+
+			proof[0].KeyShare, proof[1].KeyShare = nil, nil
+			shares[i].Pairs[pair], shares[j].Pairs[pair] = proof[0], proof[1]
+			for v := range shares {
+				shares[v].KeyShares[pair] = keyShares[v]
+			}
+		}
+	}
+
+	return shares, nil
+}
+
+// ThresholdAudit returns the verifier's audit share when the online verifiers
+// audit the proof; the audit shares are checked with CheckThresholdAudit. The
+// key list must not be flipped (the verifier negates the selected key itself
+// when it is the higher verifier of the pair).
+func (kl *KeyList) ThresholdAudit(proof *ThresholdProofShare, online []int) (*ThresholdAuditShare, error) {
+
+	online, pos, err := checkOnline(proof, online)
+	if err != nil {
+		return nil, err
+	}
+
+	m := numPairVerifiers(proof.Threshold, proof.NumVerifiers)
+	pair := pairNumber(online[0], online[1], m)
+	if len(proof.KeyShares) != m*(m-1)/2 || proof.KeyShares[pair] == nil || proof.KeyShares[pair].Y == nil {
+		return nil, errors.New("missing key share")
+	}
+
+	// additive share of the pair's key
+	field := kl.Curve.Field
+	xs := make([]*algebra.FieldElement, len(online))
+	for i, v := range online {
+		xs[i] = field.NewElement(big.NewInt(int64(v + 1)))
+	}
+
+	lagrange, err := field.LagrangeCoefficients(xs, field.AddIdentity())
+	if err != nil {
+		return nil, err
+	}
+	keyShare := field.Mul(lagrange[pos], proof.KeyShares[pair].Y)
+
+	audit := &ThresholdAuditShare{Verifier: proof.Verifier, Online: online}
+	if pos > 1 {
+		// not in the pair: only the share of the key
+		audit.Share, _ = kl.Curve.NewPoint(keyShare.Int)
+		return audit, nil
+	}
+
+	if len(proof.Pairs) != m*(m-1)/2 || proof.Pairs[pair] == nil || proof.Pairs[pair].DPFKey == nil {
+		return nil, errors.New("missing DPF key of the pair")
+	}
+
+	if proof.Pairs[pair].ShareNumber != uint(pos) {
+		return nil, fmt.Errorf("proof share of the pair has share number %v", proof.Pairs[pair].ShareNumber)
+	}
+
+	pairProof := *proof.Pairs[pair]
+	pairProof.KeyShare = keyShare
+	if pos == 0 {
+		audit.Share = kl.Audit(&pairProof).Share
+		return audit, nil
+	}
+
+	// with the negated key share, the audit over the list is the inverse of
+	// the audit over the flipped list: -(sum of keys + g^-[x]) = sum of -keys + g^[x]
+	pairProof.KeyShare = field.Negate(keyShare)
+	audit.Share = kl.Curve.Inverse(kl.Audit(&pairProof).Share)
+
+	return audit, nil
+}
+
+// CheckThresholdAudit returns true if the audit shares of all the online
+// verifiers of the same audit (in increasing order of the verifiers) add
+// up to the identity
+func (kl *KeyList) CheckThresholdAudit(auditShares ...*ThresholdAuditShare) bool {
+
+	if len(auditShares) < 2 || auditShares[0] == nil {
+		return false
+	}
+
+	online := auditShares[0].Online
+	if len(auditShares) != len(online) {
+		return false
+	}
+
+	accumulator, _ := kl.Curve.IdentityPoint()
+	for i, audit := range auditShares {
+		if audit == nil || audit.Share == nil || !sameVerifiers(audit.Online, online) || audit.Verifier != online[i] {
+			return false
+		}
+		accumulator = kl.Curve.Add(accumulator, audit.Share)
+	}
+
+	return kl.Curve.IsIdentity(accumulator)
+}
+
+// returns the sorted online verifiers and the position of the verifier
+// among them after checking that they are distinct verifiers of the proof,
+// that there are at least t of them and that they include the verifier
+func checkOnline(proof *ThresholdProofShare, online []int) ([]int, int, error) {
+
+	if proof == nil {
+		return nil, 0, errors.New("missing proof share")
+	}
+
+	if proof.Threshold < 2 || proof.Threshold > proof.NumVerifiers {
+		return nil, 0, fmt.Errorf("invalid threshold %v of %v verifiers", proof.Threshold, proof.NumVerifiers)
+	}
+
+	if len(online) < proof.Threshold {
+		return nil, 0, fmt.Errorf("%v verifiers online for a threshold of %v", len(online), proof.Threshold)
+	}
+
+	sorted := append([]int{}, online...)
+	sort.Ints(sorted)
+
+	pos := -1
+	for i, v := range sorted {
+		if v < 0 || v >= proof.NumVerifiers || (i > 0 && v == sorted[i-1]) {
+			return nil, 0, fmt.Errorf("invalid online verifiers %v", online)
+		}
+
+		if v == proof.Verifier {
+			pos = i
+		}
+	}
+
+	if pos < 0 {
+		return nil, 0, fmt.Errorf("verifier %v is not online", proof.Verifier)
+	}
+
+	return sorted, pos, nil
+}
+
+func sameVerifiers(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
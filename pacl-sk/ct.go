@@ -0,0 +1,39 @@
+package paclsk
+
+import "github.com/sachaservan/pacl/slot"
+
+// Audits combine every key of the list with its DPF bit (a masked xor)
+// rather than branching on the bit, so that the time taken does not leak
+// the Hamming weight or the positions of the set bits of the verifier's
+// DPF share. The keys are combined through a keyAccumulator, which tests
+// replace to check that the sequence of operations does not depend on the
+// bits (see TestDataIndependentAudit).
+
+// keyAccumulator xors together the keys selected by the DPF bits
+type keyAccumulator interface {
+	// xors key into the sum if bit is 1
+	xorMasked(key *slot.Slot, bit byte)
+	// xors every key of the vector selected by the packed bits into the sum
+	xorMaskedPacked(keys *slot.Vector, words []uint64)
+	sum() *slot.Slot
+}
+
+type slotAccumulator struct {
+	acc *slot.Slot
+}
+
+func (kl *KeyList) newKeyAccumulator() keyAccumulator {
+	return &slotAccumulator{acc: slot.NewEmpty(kl.StatSecurity / 8)}
+}
+
+func (a *slotAccumulator) xorMasked(key *slot.Slot, bit byte) {
+	slot.XorMasked(a.acc, key, bit)
+}
+
+func (a *slotAccumulator) xorMaskedPacked(keys *slot.Vector, words []uint64) {
+	keys.XorMaskedPacked(a.acc, words)
+}
+
+func (a *slotAccumulator) sum() *slot.Slot {
+	return a.acc
+}
@@ -0,0 +1,179 @@
+package paclsk
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/sachaservan/pacl/dpf128"
+	"github.com/sachaservan/pacl/slot"
+	dpf "github.com/sachaservan/vdpf"
+)
+
+// Proof and audit shares are encoded in binary (e.g., to be sent to the
+// verifiers or written to files):
+//
+//	proof: flags, share number, DPF key, key share
+//	audit: share
+//
+// where the flags (one byte) record the kind of DPF key, byte strings
+// (and slots) are prefixed with their 4-byte big-endian length and the
+// 64-bit values are big-endian.
+
+const flagWide = 1 // proof: the DPF key is a dpf128.Key
+
+// EncodeProof returns the encoding of the proof share
+func (kl *KeyListParams) EncodeProof(proof *ProofShare) ([]byte, error) {
+
+	if proof == nil || (proof.DPFKey == nil) == (proof.WideDPFKey == nil) {
+		return nil, errors.New("proof share must have exactly one DPF key")
+	}
+
+	if proof.ShareNumber > 1 || proof.KeyShare == nil {
+		return nil, errors.New("malformed proof share")
+	}
+
+	flags := byte(0)
+	if proof.WideDPFKey != nil {
+		flags |= flagWide
+	}
+
+	b := []byte{flags, byte(proof.ShareNumber)}
+	if proof.WideDPFKey != nil {
+		key, err := proof.WideDPFKey.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		b = appendBytes(b, key)
+	} else {
+		b = append(b, proof.PrfKey[:]...)
+		b = appendUint64(b, uint64(proof.DPFKey.RangeSize))
+		b = appendUint64(b, proof.DPFKey.Index)
+		b = appendBytes(b, proof.DPFKey.Bytes)
+	}
+
+	return appendBytes(b, proof.KeyShare.Data), nil
+}
+
+// DecodeProof returns the proof share of the encoding b
+func (kl *KeyListParams) DecodeProof(b []byte) (*ProofShare, error) {
+
+	r := &reader{b: b}
+	header := r.next(2)
+	if r.err != nil || header[0]&^flagWide != 0 || header[1] > 1 {
+		return nil, errors.New("malformed proof share header")
+	}
+
+	proof := &ProofShare{ShareNumber: uint(header[1])}
+	if header[0]&flagWide != 0 {
+		proof.WideDPFKey = &dpf128.Key{}
+		if key := r.bytes(); r.err == nil {
+			if err := proof.WideDPFKey.UnmarshalBinary(key); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		copy(proof.PrfKey[:], r.next(len(proof.PrfKey)))
+		proof.DPFKey = &dpf.DPFKey{}
+		proof.DPFKey.RangeSize = uint(r.uint64())
+		proof.DPFKey.Index = r.uint64()
+		proof.DPFKey.Bytes = r.bytes()
+	}
+
+	proof.KeyShare = slot.New(r.bytes())
+
+	if err := r.done(); err != nil {
+		return nil, err
+	}
+
+	return proof, nil
+}
+
+// EncodeAudit returns the encoding of the audit share
+func (kl *KeyListParams) EncodeAudit(audit *AuditShare) ([]byte, error) {
+
+	if audit == nil || audit.Share == nil {
+		return nil, errors.New("missing audit share")
+	}
+
+	return appendBytes(nil, audit.Share.Data), nil
+}
+
+// DecodeAudit returns the audit share of the encoding b
+func (kl *KeyListParams) DecodeAudit(b []byte) (*AuditShare, error) {
+
+	r := &reader{b: b}
+	audit := &AuditShare{Share: slot.New(r.bytes())}
+
+	if err := r.done(); err != nil {
+		return nil, err
+	}
+
+	return audit, nil
+}
+
+func appendUint64(b []byte, v uint64) []byte {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+// appends v prefixed with its 4-byte length
+func appendBytes(b, v []byte) []byte {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], uint32(len(v)))
+	return append(append(b, buf[:]...), v...)
+}
+
+// reads the values of an encoding in order; after the first error (e.g.,
+// a truncated encoding) every read returns zero values and err is kept
+type reader struct {
+	b   []byte
+	err error
+}
+
+func (r *reader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+
+	if n < 0 || len(r.b) < n {
+		r.err = errors.New("truncated encoding")
+		return nil
+	}
+
+	v := r.b[:n]
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *reader) uint64() uint64 {
+	if v := r.next(8); v != nil {
+		return binary.BigEndian.Uint64(v)
+	}
+
+	return 0
+}
+
+// reads a length-prefixed byte string (and returns a copy)
+func (r *reader) bytes() []byte {
+	n := r.next(4)
+	if n == nil {
+		return nil
+	}
+
+	v := r.next(int(binary.BigEndian.Uint32(n)))
+	if v == nil {
+		return nil
+	}
+
+	return append([]byte{}, v...)
+}
+
+// returns the first error, or an error if there are bytes left
+func (r *reader) done() error {
+	if r.err == nil && len(r.b) != 0 {
+		return errors.New("trailing bytes after the encoding")
+	}
+
+	return r.err
+}
@@ -1,8 +1,13 @@
 package paclsk
 
 import (
+	crand "crypto/rand"
 	"math"
 	"math/rand"
+
+	"github.com/sachaservan/pacl/dpf128"
+	"github.com/sachaservan/pacl/keystore"
+	"github.com/sachaservan/pacl/slot"
 )
 
 type PredicateType int
@@ -13,17 +18,33 @@ const (
 )
 
 type KeyListParams struct {
-	FullDomain    bool
+	FullDomain    bool         // keys are ordered by index and fill the domain
+	EvalStrategy  EvalStrategy // how the DPF is expanded (see UseFullDomain)
 	NumKeys       uint64
 	FSSDomain     uint
 	KeyIndices    []uint64
 	PredicateType PredicateType
+
+	// 128-bit key indices used instead of KeyIndices when the FSS domain
+	// is larger than 64 bits (or to prove with dpf128 keys in a smaller one)
+	WideKeyIndices []dpf128.Index
+}
+
+// IsWide returns true if the key indices are 128 bits
+func (kl *KeyListParams) IsWide() bool {
+	return kl.FSSDomain > 64 || kl.WideKeyIndices != nil
 }
 
 type KeyList struct {
 	KeyListParams
-	Keys         []*Slot
+	Keys         []*slot.Slot
 	StatSecurity int // key statistical security (e.g., 128)
+
+	// contiguous storage of the keys (see UseArena)
+	Arena *slot.Vector `json:"-"`
+
+	// memory-mapped keys used instead of Keys (see UseKeyStore)
+	Store *keystore.Store `json:"-"`
 }
 
 func GenerateTestingKeyList(
@@ -31,7 +52,7 @@ func GenerateTestingKeyList(
 	fssDomain uint,
 	pred PredicateType,
 	numSubkeys uint64,
-) (*KeyList, *Slot, uint64, uint64) {
+) (*KeyList, *slot.Slot, uint64, uint64) {
 
 	if pred == Inclusion {
 		// increase the domain of the DPF to account for the extra
@@ -44,7 +65,6 @@ func GenerateTestingKeyList(
 	}
 
 	kl := KeyList{}
-	kl.Keys = make([]*Slot, numKeys)
 	kl.NumKeys = numKeys
 	kl.StatSecurity = 128
 	kl.FSSDomain = fssDomain
@@ -52,13 +72,16 @@ func GenerateTestingKeyList(
 	kl.KeyIndices = make([]uint64, numKeys)
 	kl.FullDomain = (1<<fssDomain == numKeys) // only applies when domain = #keys
 
-	slot := NewRandomSlot(kl.StatSecurity / 8)
+	key := slot.NewRandom(kl.StatSecurity / 8)
+	arena := slot.NewVector(numKeys, kl.StatSecurity/8)
 
 	for i := uint64(0); i < numKeys; i++ {
 		kl.KeyIndices[i] = rand.Uint64()
-		kl.Keys[i] = NewSlot(slot.Data)
+		copy(arena.Slot(i).Data, key.Data)
 	}
 
+	kl.UseArena(arena)
+
 	idx := rand.Uint64() % numKeys
 
 	return &kl, kl.Keys[idx], idx, kl.KeyIndices[idx]
@@ -69,7 +92,7 @@ func GenerateBenchmarkKeyList(
 	fssDomain uint,
 	pred PredicateType,
 	numSubkeys uint64,
-) (*KeyList, *Slot, uint64) {
+) (*KeyList, *slot.Slot, uint64) {
 
 	if pred == Inclusion {
 		// increase the domain of the DPF to account for the extra
@@ -82,34 +105,46 @@ func GenerateBenchmarkKeyList(
 	}
 
 	kl := KeyList{}
-	kl.Keys = make([]*Slot, numKeys)
 	kl.NumKeys = numKeys
 	kl.StatSecurity = 128
 	kl.FSSDomain = fssDomain
 	kl.KeyIndices = make([]uint64, numKeys)
 	kl.FullDomain = (1<<fssDomain == numKeys) // only applies when domain = #keys
 
+	arena := slot.NewVector(numKeys, kl.StatSecurity/8)
+	if err := arena.Fill(crand.Reader); err != nil {
+		panic(err)
+	}
+
 	for i := uint64(0); i < numKeys; i++ {
 		kl.KeyIndices[i] = rand.Uint64() % (1 << fssDomain)
-		slot := NewRandomSlot(kl.StatSecurity / 8)
-		kl.Keys[i] = NewSlot(slot.Data)
 	}
 
+	kl.UseArena(arena)
+
 	idx := rand.Uint64() % numKeys
 
 	return &kl, kl.Keys[idx], kl.KeyIndices[idx]
 }
 
 // computes an additive shares in a field that sum to z
-func ComputeMaskingShares(z *Slot) []*Slot {
-	s1 := NewRandomSlot(len(z.Data))
-	s2 := NewEmptySlot(len(z.Data))
-	XorSlots(s2, s1)
-	XorSlots(s2, z)
+func ComputeMaskingShares(z *slot.Slot) []*slot.Slot {
+	s1 := slot.NewRandom(len(z.Data))
+	s2 := slot.NewEmpty(len(z.Data))
+	slot.Xor(s2, s1)
+	slot.Xor(s2, z)
 
-	res := make([]*Slot, 2)
+	res := make([]*slot.Slot, 2)
 	res[0] = s1
 	res[1] = s2
 
 	return res
 }
+
+// UseArena sets the keys of the list to the slots of the arena; audits
+// then xor the selected keys directly out of the arena
+func (kl *KeyList) UseArena(arena *slot.Vector) {
+	kl.Arena = arena
+	kl.Keys = arena.Slots()
+	kl.NumKeys = arena.Len()
+}
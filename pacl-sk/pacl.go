@@ -1,21 +1,52 @@
 package paclsk
 
 import (
+	"github.com/sachaservan/pacl/dpf128"
+	"github.com/sachaservan/pacl/slot"
 	dpf "github.com/sachaservan/vdpf"
 )
 
+// Secret-key lists have no epoch mode (see NewEpochProof in pacl-pk and
+// pacl-sposs): the verifiers hold every key, so they could compute the tag
+// of every key for the epoch and match the tag of a proof to its index.
+
 type ProofShare struct {
 	DPFKey      *dpf.DPFKey // DPF key
 	PrfKey      dpf.PrfKey  // PRF key used for the PRG in DPF construction
+	WideDPFKey  *dpf128.Key // DPF key over 128-bit indices (used instead of DPFKey)
 	ShareNumber uint
-	KeyShare    *Slot
+	KeyShare    *slot.Slot
 }
 
 type AuditShare struct {
-	Share *Slot
+	Share *slot.Slot
+}
+
+// Size returns the number of bytes the prover sends to one verifier
+func (share *ProofShare) Size() int {
+	size := dpfKeySize(share.DPFKey) + share.WideDPFKey.Size() + share.KeyShare.Size()
+	if share.DPFKey != nil {
+		size += len(share.PrfKey)
+	}
+
+	return size
+}
+
+// Size returns the number of bytes a verifier sends to the other verifier
+func (share *AuditShare) Size() int {
+	return share.Share.Size()
 }
 
-func (kl *KeyListParams) NewProof(idx uint64, x *Slot) []*ProofShare {
+// size of the key material of a DPF key
+func dpfKeySize(key *dpf.DPFKey) int {
+	if key == nil {
+		return 0
+	}
+
+	return len(key.Bytes)
+}
+
+func (kl *KeyListParams) NewProof(idx uint64, x *slot.Slot) []*ProofShare {
 	if kl.NumKeys == 0 {
 		panic("list size is set to zero; something is wrong")
 	}
@@ -27,6 +58,46 @@ func (kl *KeyListParams) NewProof(idx uint64, x *Slot) []*ProofShare {
 	// gen the dpf keys
 	keyA, keyB := pf.GenDPFKeys(idx, kl.FSSDomain)
 
+	return kl.NewProofWithDPFKeys(pf.PrfKey, keyA, keyB, x)
+}
+
+// NewProofWithDPFKeys is the same as NewProof but uses the given DPF keys
+// (e.g., keys that also serve as a PIR query) instead of generating new ones
+func (kl *KeyListParams) NewProofWithDPFKeys(prfKey dpf.PrfKey, keyA, keyB *dpf.DPFKey, x *slot.Slot) []*ProofShare {
+
+	shares := newProofShares(x)
+	shares[0].PrfKey = prfKey
+	shares[0].DPFKey = keyA
+	shares[1].PrfKey = prfKey
+	shares[1].DPFKey = keyB
+
+	return shares
+}
+
+// NewWideProof is the same as NewProof but for key lists with 128-bit indices
+func (kl *KeyListParams) NewWideProof(idx dpf128.Index, x *slot.Slot) []*ProofShare {
+	if kl.NumKeys == 0 {
+		panic("list size is set to zero; something is wrong")
+	}
+
+	keyA, keyB := dpf128.GenKeys(idx, kl.FSSDomain)
+
+	return kl.NewWideProofWithDPFKeys(keyA, keyB, x)
+}
+
+// NewWideProofWithDPFKeys is the same as NewProofWithDPFKeys but for key lists with 128-bit indices
+func (kl *KeyListParams) NewWideProofWithDPFKeys(keyA, keyB *dpf128.Key, x *slot.Slot) []*ProofShare {
+
+	shares := newProofShares(x)
+	shares[0].WideDPFKey = keyA
+	shares[1].WideDPFKey = keyB
+
+	return shares
+}
+
+// returns the proof shares of both verifiers (without DPF keys)
+func newProofShares(x *slot.Slot) []*ProofShare {
+
 	// secret share the access key x
 	keyShares := ComputeMaskingShares(x)
 
@@ -36,15 +107,11 @@ func (kl *KeyListParams) NewProof(idx uint64, x *Slot) []*ProofShare {
 	// share for verifier A
 	shares[0] = &ProofShare{}
 	shares[0].ShareNumber = 0
-	shares[0].PrfKey = pf.PrfKey
-	shares[0].DPFKey = keyA
 	shares[0].KeyShare = keyShares[0]
 
 	// share for verifier B
 	shares[1] = &ProofShare{}
 	shares[1].ShareNumber = 1
-	shares[1].PrfKey = pf.PrfKey
-	shares[1].DPFKey = keyB
 	shares[1].KeyShare = keyShares[1]
 
 	return shares
@@ -56,21 +123,47 @@ func (kl *KeyList) Audit(proof *ProofShare) *AuditShare {
 }
 
 func (kl *KeyList) CheckAudit(auditShares ...*AuditShare) bool {
-	accumulator := auditShares[0].Share
+	accumulator := slot.New(append([]byte{}, auditShares[0].Share.Data...))
 	for i := 1; i < len(auditShares); i++ {
-		XorSlots(accumulator, auditShares[i].Share)
+		slot.Xor(accumulator, auditShares[i].Share)
 	}
 
-	return accumulator.Equal(NewEmptySlot(len(accumulator.Data)))
+	return accumulator.IsZero()
+}
+
+// AuditExpanded is the same as Audit but re-uses DPF bits that were already
+// expanded from the proof's DPF key (e.g., when the same key is also used to write)
+func (kl *KeyList) AuditExpanded(proof *ProofShare, bits []byte) *AuditShare {
+	return kl.computeAudit(proof, bits)
 }
 
 func (kl *KeyList) ExpandDPF(proof *ProofShare) []byte {
 
+	if kl.IsWide() != (proof.WideDPFKey != nil) {
+		// the proof does not match the index size of the list; selecting
+		// no key guarantees that the audit fails
+		return make([]byte, kl.NumKeys)
+	}
+
+	if kl.IsWide() {
+		if proof.WideDPFKey.Domain != kl.FSSDomain {
+			return make([]byte, kl.NumKeys)
+		}
+
+		bits, err := dpf128.BatchEval(proof.WideDPFKey, kl.WideKeyIndices)
+		if err != nil {
+			// malformed key
+			return make([]byte, kl.NumKeys)
+		}
+
+		return bits
+	}
+
 	pf := dpf.ServerDPFInitialize(proof.PrfKey)
 
-	if kl.FullDomain {
+	if kl.UseFullDomain() {
 		// run the optimized full-domain evaluation strategy
-		return pf.FullDomainEval(proof.DPFKey)
+		return kl.lookupFullDomain(pf.FullDomainEval(proof.DPFKey))
 	} else {
 		return pf.BatchEval(proof.DPFKey, kl.KeyIndices)
 	}
@@ -79,18 +172,26 @@ func (kl *KeyList) ExpandDPF(proof *ProofShare) []byte {
 // uses the expanded DPF bits to "select" the public key in the keylist
 // over which the audit is going to be performed
 func (kl *KeyList) computeAudit(proof *ProofShare, bits []byte) *AuditShare {
+	return kl.accumulateAudit(kl.newKeyAccumulator(), proof, bits)
+}
 
-	// final result
-	accumulator := NewEmptySlot(kl.StatSecurity / 8)
+// computeAudit with the keys combined by acc
+func (kl *KeyList) accumulateAudit(acc keyAccumulator, proof *ProofShare, bits []byte) *AuditShare {
 
-	for i := uint64(0); i < kl.NumKeys; i++ {
-		if bits[i] == 1 {
-			// fmt.Println(kl.Keys[i])
-			XorSlots(accumulator, kl.Keys[i])
+	// every key is xor'ed in (masked by its bit) so that
+	// the time taken does not depend on the DPF bits
+	if kl.Arena != nil {
+		acc.xorMaskedPacked(kl.Arena, slot.PackBits(bits[:kl.NumKeys]))
+	} else {
+		key := &slot.Slot{}
+		for i := uint64(0); i < kl.NumKeys; i++ {
+			acc.xorMasked(kl.keyAt(i, key), bits[i])
 		}
 	}
 
-	XorSlots(accumulator, proof.KeyShare)
+	// final result
+	share := acc.sum()
+	slot.Xor(share, proof.KeyShare)
 
-	return &AuditShare{Share: accumulator}
+	return &AuditShare{Share: share}
 }
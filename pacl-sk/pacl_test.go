@@ -1,7 +1,21 @@
 package paclsk
 
 import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
+	"time"
+
+	"github.com/sachaservan/pacl/dpf128"
+	"github.com/sachaservan/pacl/dpfeval"
+	"github.com/sachaservan/pacl/internal/benchsweep"
+	"github.com/sachaservan/pacl/keystore"
+	"github.com/sachaservan/pacl/slot"
 )
 
 // test configuration parameters
@@ -29,6 +43,251 @@ func TestProveAuditVerify(t *testing.T) {
 	}
 }
 
+func TestProveAuditVerifyWide(t *testing.T) {
+
+	kl := &KeyList{}
+	kl.NumKeys = TestNumKeys
+	kl.FSSDomain = dpf128.MaxDomain
+	kl.StatSecurity = StatSecPar
+	kl.Keys = make([]*slot.Slot, TestNumKeys)
+	kl.WideKeyIndices = make([]dpf128.Index, TestNumKeys)
+	for i := 0; i < TestNumKeys; i++ {
+		kl.Keys[i] = slot.NewRandom(StatSecPar / 8)
+		kl.WideKeyIndices[i] = dpf128.HashToIndex([]byte{byte(i), byte(i >> 8)})
+	}
+
+	idx := 42
+	proofShares := kl.NewWideProof(kl.WideKeyIndices[idx], kl.Keys[idx])
+	if !kl.CheckAudit(kl.Audit(proofShares[0]), kl.Audit(proofShares[1])) {
+		t.Fatalf("CheckAudit failed")
+	}
+
+	// the key of another index must be rejected
+	proofShares = kl.NewWideProof(kl.WideKeyIndices[idx], kl.Keys[idx+1])
+	if kl.CheckAudit(kl.Audit(proofShares[0]), kl.Audit(proofShares[1])) {
+		t.Fatalf("CheckAudit accepted the wrong key")
+	}
+
+	// malformed keys and keys of another domain must be rejected
+	for _, domain := range []uint{kl.FSSDomain, kl.FSSDomain - 1} {
+		proofShares = kl.NewWideProof(kl.WideKeyIndices[idx], kl.Keys[idx])
+		for _, share := range proofShares {
+			share.WideDPFKey.Domain = domain
+			share.WideDPFKey.CWSeeds = share.WideDPFKey.CWSeeds[:domain-1]
+		}
+
+		if kl.CheckAudit(kl.Audit(proofShares[0]), kl.Audit(proofShares[1])) {
+			t.Fatalf("CheckAudit accepted a malformed DPF key")
+		}
+	}
+}
+
+func TestProofSize(t *testing.T) {
+
+	kl, key, _, keyIdx := GenerateTestingKeyList(
+		TestNumKeys, TestFSSDomain, TestPredicate, TestNumSubkeys)
+	proofShares := kl.NewProof(keyIdx, key)
+
+	// DPF key, PRF key and key share
+	expected := len(proofShares[0].DPFKey.Bytes) + 16 + StatSecPar/8
+	if size := proofShares[0].Size(); size != expected {
+		t.Fatalf("Wrong proof size. expected: %v, got: %v", expected, size)
+	}
+
+	if size := kl.Audit(proofShares[0]).Size(); size != StatSecPar/8 {
+		t.Fatalf("Wrong audit size. expected: %v, got: %v", StatSecPar/8, size)
+	}
+}
+
+func TestEvalStrategies(t *testing.T) {
+
+	// dense list: 512 keys in a domain of 2^10
+	kl, key, _, keyIdx := GenerateTestingKeyList(TestNumKeys, 10, Equality, 0)
+	distinctIndices(kl.KeyIndices, 10)
+	keyIdx = kl.KeyIndices[0]
+	if !kl.UseFullDomain() {
+		t.Fatalf("Expected the full-domain strategy for a dense list")
+	}
+
+	proofShares := kl.NewProof(keyIdx, key)
+	expected := kl.Audit(proofShares[0])
+
+	for _, strategy := range []EvalStrategy{BatchStrategy, FullDomainStrategy} {
+		kl.EvalStrategy = strategy
+
+		auditA := kl.Audit(proofShares[0])
+		auditB := kl.Audit(proofShares[1])
+
+		if !auditA.Share.Equal(expected.Share) || !kl.CheckAudit(auditA, auditB) {
+			t.Fatalf("CheckAudit failed with strategy %v", strategy)
+		}
+	}
+
+	// sparse list
+	kl, _, _, _ = GenerateTestingKeyList(TestNumKeys, TestFSSDomain, Equality, 0)
+	if kl.UseFullDomain() {
+		t.Fatalf("Expected the batch strategy for a sparse list")
+	}
+}
+
+func TestStreamAudit(t *testing.T) {
+
+	kl, key, _, keyIdx := GenerateTestingKeyList(
+		TestNumKeys, TestFSSDomain, TestPredicate, TestNumSubkeys)
+
+	var file bytes.Buffer
+	if err := kl.WriteKeyFile(&file); err != nil {
+		t.Fatal(err)
+	}
+
+	src := NewFileKeySource(bytes.NewReader(file.Bytes()), StatSecPar/8)
+
+	proofShares := kl.NewProof(keyIdx, key)
+
+	// the chunk size does not divide the list size
+	auditA, err := kl.StreamAudit(proofShares[0], src, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	auditB, err := kl.StreamAudit(proofShares[1], kl, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !auditA.Share.Equal(kl.Audit(proofShares[0]).Share) || !kl.CheckAudit(auditA, auditB) {
+		t.Fatalf("CheckAudit failed on streamed audits")
+	}
+}
+
+func TestDataIndependentAudit(t *testing.T) {
+
+	kl, key, _, keyIdx := GenerateTestingKeyList(
+		TestNumKeys, TestFSSDomain, TestPredicate, TestNumSubkeys)
+	proofShares := kl.NewProof(keyIdx, key)
+	expected := kl.Audit(proofShares[0])
+
+	// with and without an arena
+	for _, arena := range []*slot.Vector{kl.Arena, nil} {
+		kl.Arena = arena
+
+		var traces [][]string
+		for _, bits := range testBitPatterns(kl.NumKeys) {
+			acc := &recordingAccumulator{keyAccumulator: kl.newKeyAccumulator()}
+			kl.accumulateAudit(acc, proofShares[0], bits)
+			traces = append(traces, acc.ops)
+		}
+		checkSameOps(t, traces)
+	}
+
+	// streamed audits, with the DPF outputs replaced by the patterns
+	var traces [][]string
+	for _, bits := range testBitPatterns(kl.NumKeys) {
+		acc := &recordingAccumulator{keyAccumulator: kl.newKeyAccumulator()}
+		if _, err := kl.streamAudit(acc, proofShares[0], kl, 100, patternEval(bits)); err != nil {
+			t.Fatal(err)
+		}
+		traces = append(traces, acc.ops)
+	}
+	checkSameOps(t, traces)
+
+	// the recorded operations are those of Audit
+	acc := &recordingAccumulator{keyAccumulator: kl.newKeyAccumulator()}
+	audit := kl.accumulateAudit(acc, proofShares[0], kl.ExpandDPF(proofShares[0]))
+	if !audit.Share.Equal(expected.Share) {
+		t.Fatalf("Recorded audit does not match Audit")
+	}
+}
+
+func TestKeyStore(t *testing.T) {
+
+	kl, key, _, keyIdx := GenerateTestingKeyList(
+		TestNumKeys, TestFSSDomain, TestPredicate, TestNumSubkeys)
+
+	path := filepath.Join(t.TempDir(), "keys")
+	writeKeyFile(t, path, kl.WriteKeyFile)
+
+	store, err := keystore.Open(path, StatSecPar/8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	stored := &KeyList{KeyListParams: kl.KeyListParams, StatSecurity: kl.StatSecurity}
+	if err := stored.UseKeyStore(store); err != nil {
+		t.Fatal(err)
+	}
+
+	proofShares := kl.NewProof(keyIdx, key)
+	auditA := stored.Audit(proofShares[0])
+	auditB := kl.Audit(proofShares[1])
+
+	if !auditA.Share.Equal(kl.Audit(proofShares[0]).Share) || !kl.CheckAudit(auditA, auditB) {
+		t.Fatalf("CheckAudit failed with a key store")
+	}
+}
+
+func TestEncoding(t *testing.T) {
+
+	kl, key, _, keyIdx := GenerateTestingKeyList(
+		TestNumKeys, TestFSSDomain, Equality, 0)
+
+	// the same list with 128-bit indices
+	wide := &KeyList{}
+	*wide = *kl
+	wide.FSSDomain = dpf128.MaxDomain
+	wide.KeyIndices = nil
+	wide.WideKeyIndices = make([]dpf128.Index, wide.NumKeys)
+	for i := range wide.WideKeyIndices {
+		wide.WideKeyIndices[i] = dpf128.Index{Lo: kl.KeyIndices[i]}
+	}
+
+	lists := []*KeyList{kl, wide}
+	proofs := [][]*ProofShare{
+		kl.NewProof(keyIdx, key),
+		wide.NewWideProof(dpf128.Index{Lo: keyIdx}, key),
+	}
+
+	for i, proofShares := range proofs {
+		var audits [2]*AuditShare
+		for j, proof := range proofShares {
+			b, err := lists[i].EncodeProof(proof)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			decoded, err := lists[i].DecodeProof(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := lists[i].DecodeProof(b[:len(b)-1]); err == nil {
+				t.Fatalf("Decoded a truncated proof share")
+			}
+
+			if _, err := lists[i].DecodeProof(append(b, 0)); err == nil {
+				t.Fatalf("Decoded a proof share with trailing bytes")
+			}
+
+			if b, err = lists[i].EncodeAudit(lists[i].Audit(decoded)); err != nil {
+				t.Fatal(err)
+			}
+
+			if audits[j], err = lists[i].DecodeAudit(b); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := lists[i].DecodeAudit(b[:len(b)-1]); err == nil {
+				t.Fatalf("Decoded a truncated audit share")
+			}
+		}
+
+		if !kl.CheckAudit(audits[0], audits[1]) {
+			t.Fatalf("CheckAudit of decoded shares failed (proof %v)", i)
+		}
+	}
+}
+
 func BenchmarkBaseline(b *testing.B) {
 
 	numKeys := uint64(1000)
@@ -75,3 +334,211 @@ func BenchmarkPACLMany(b *testing.B) {
 		kl.CheckAudit(audit, audit)
 	}
 }
+
+// returns the key list over which the per-operation benchmarks run
+// (see benchsweep.Run)
+func benchKeyList(numKeys uint64, inclusion bool) (*KeyList, *slot.Slot, uint64) {
+	pred := Equality
+	if inclusion {
+		pred = Inclusion
+	}
+
+	kl, key, _, keyIdx := GenerateTestingKeyList(numKeys, TestFSSDomain, pred, TestNumSubkeys)
+	return kl, key, keyIdx
+}
+
+func BenchmarkNewProof(b *testing.B) {
+	benchsweep.Run(b, func(b *testing.B, numKeys uint64, inclusion bool) {
+		kl, key, keyIdx := benchKeyList(numKeys, inclusion)
+
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			kl.NewProof(keyIdx, key)
+		}
+	})
+}
+
+func BenchmarkAudit(b *testing.B) {
+	benchsweep.Run(b, func(b *testing.B, numKeys uint64, inclusion bool) {
+		kl, key, keyIdx := benchKeyList(numKeys, inclusion)
+		shares := kl.NewProof(keyIdx, key)
+
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			kl.Audit(shares[0])
+		}
+	})
+}
+
+func BenchmarkCheckAudit(b *testing.B) {
+	benchsweep.Run(b, func(b *testing.B, numKeys uint64, inclusion bool) {
+		kl, key, keyIdx := benchKeyList(numKeys, inclusion)
+		shares := kl.NewProof(keyIdx, key)
+		auditA := kl.Audit(shares[0])
+		auditB := kl.Audit(shares[1])
+
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			if !kl.CheckAudit(auditA, auditB) {
+				b.Fatalf("CheckAudit failed")
+			}
+		}
+	})
+}
+
+// compares both DPF evaluation strategies over a domain of 2^20 as the list
+// gets denser; the cost model predicts the crossover at about 2^14 keys
+func BenchmarkExpandDPF(b *testing.B) {
+
+	fssDomain := uint(20)
+	strategies := map[string]EvalStrategy{"batch": BatchStrategy, "full": FullDomainStrategy}
+
+	for _, numKeys := range []uint64{1 << 8, 1 << 11, 1 << 13, 1 << 15, 1 << 17} {
+		for _, name := range []string{"batch", "full"} {
+			b.Run(fmt.Sprintf("keys=%v/%v", numKeys, name), func(b *testing.B) {
+				benchExpandDPF(b, numKeys, fssDomain, strategies[name])
+			})
+		}
+	}
+}
+
+// both strategies on either side of the crossover of the cost model
+func BenchmarkCrossover(b *testing.B) {
+
+	fssDomain := uint(20)
+	strategies := map[string]EvalStrategy{"batch": BatchStrategy, "full": FullDomainStrategy}
+
+	for _, numKeys := range crossoverSizes(fssDomain) {
+		for _, name := range []string{"batch", "full"} {
+			b.Run(fmt.Sprintf("keys=%v/%v", numKeys, name), func(b *testing.B) {
+				benchExpandDPF(b, numKeys, fssDomain, strategies[name])
+			})
+		}
+	}
+}
+
+func TestStrategyChoice(t *testing.T) {
+	if testing.Short() {
+		t.Skip("times the DPF expansion")
+	}
+
+	fssDomain := uint(20)
+
+	for _, numKeys := range crossoverSizes(fssDomain) {
+		ns := make(map[EvalStrategy]int64)
+		for _, strategy := range []EvalStrategy{BatchStrategy, FullDomainStrategy} {
+			res := testing.Benchmark(func(b *testing.B) {
+				benchExpandDPF(b, numKeys, fssDomain, strategy)
+			})
+			ns[strategy] = res.NsPerOp()
+		}
+
+		kl, _, _, _ := GenerateTestingKeyList(numKeys, fssDomain, Equality, 0)
+		if kl.UseFullDomain() != (ns[FullDomainStrategy] < ns[BatchStrategy]) {
+			t.Fatalf("Auto picked the slower strategy for %v keys (batch %v ns, full domain %v ns)",
+				numKeys, ns[BatchStrategy], ns[FullDomainStrategy])
+		}
+	}
+}
+
+// list sizes a factor 4 below and above the crossover of the cost model
+func crossoverSizes(fssDomain uint) []uint64 {
+	c := dpfeval.Crossover(fssDomain)
+	return []uint64{c / 4, c * 4}
+}
+
+// times the expansion of a DPF over a list of numKeys keys in a domain of
+// fssDomain bits, reporting the per-unit costs of the model in dpfeval
+func benchExpandDPF(b *testing.B, numKeys uint64, fssDomain uint, strategy EvalStrategy) {
+	kl, key, _, keyIdx := GenerateTestingKeyList(numKeys, fssDomain, Equality, 0)
+	kl.EvalStrategy = strategy
+	shares := kl.NewProof(keyIdx, key)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	start := time.Now()
+
+	for i := 0; i < b.N; i++ {
+		kl.ExpandDPF(shares[0])
+	}
+
+	ns := float64(time.Since(start).Nanoseconds()) / float64(b.N)
+	if strategy == BatchStrategy {
+		b.ReportMetric(ns/float64(numKeys*uint64(fssDomain)), "ns/level")
+	} else {
+		b.ReportMetric(ns/float64(uint64(1)<<fssDomain), "ns/leaf")
+	}
+}
+
+// records the operations an audit performs on the keys
+// (and forwards them to the accumulator of audits)
+type recordingAccumulator struct {
+	keyAccumulator
+	ops []string
+}
+
+func (acc *recordingAccumulator) xorMasked(key *slot.Slot, bit byte) {
+	acc.ops = append(acc.ops, fmt.Sprintf("xor:%v", len(key.Data)))
+	acc.keyAccumulator.xorMasked(key, bit)
+}
+
+func (acc *recordingAccumulator) xorMaskedPacked(keys *slot.Vector, words []uint64) {
+	acc.ops = append(acc.ops, fmt.Sprintf("xor-vector:%v:%v", keys.Len(), len(words)))
+	acc.keyAccumulator.xorMaskedPacked(keys, words)
+}
+
+// DPF output bits with no, every and random bits set
+func testBitPatterns(n uint64) [][]byte {
+	zeros := make([]byte, n)
+	ones := make([]byte, n)
+	random := make([]byte, n)
+	for i := range ones {
+		ones[i] = 1
+		random[i] = byte(rand.Intn(2))
+	}
+
+	return [][]byte{zeros, ones, random}
+}
+
+// returns the bits in order, in place of the evaluations of a DPF
+func patternEval(bits []byte) func(indices []uint64) []byte {
+	return func(indices []uint64) []byte {
+		res := bits[:len(indices)]
+		bits = bits[len(indices):]
+		return res
+	}
+}
+
+// checks that the audits performed the same sequence of operations
+func checkSameOps(t *testing.T, traces [][]string) {
+	for _, ops := range traces[1:] {
+		if len(ops) == 0 || !reflect.DeepEqual(ops, traces[0]) {
+			t.Fatalf("Sequence of audit operations depends on the DPF bits")
+		}
+	}
+}
+
+// replaces the indices by distinct random indices in a domain of 2^fssDomain
+func distinctIndices(indices []uint64, fssDomain uint) {
+	for i, idx := range rand.Perm(1 << fssDomain)[:len(indices)] {
+		indices[i] = uint64(idx)
+	}
+}
+
+func writeKeyFile(t *testing.T, path string, write func(io.Writer) error) {
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := write(file); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := file.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
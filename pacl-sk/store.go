@@ -0,0 +1,40 @@
+package paclsk
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/sachaservan/pacl/keystore"
+	"github.com/sachaservan/pacl/slot"
+)
+
+// UseKeyStore replaces the keys of the list by the records of the store
+// (e.g., a file written by WriteKeyFile), which audits then read in place
+func (kl *KeyList) UseKeyStore(store *keystore.Store) error {
+	if kl.IsWide() {
+		return errors.New("key stores hold 64-bit key indices")
+	}
+
+	if store.KeySize != kl.StatSecurity/8 {
+		return fmt.Errorf("store has %v-byte keys instead of %v", store.KeySize, kl.StatSecurity/8)
+	}
+
+	kl.Store = store
+	kl.Arena = nil
+	kl.Keys = nil
+	kl.NumKeys = store.NumKeys
+	kl.KeyIndices = store.Indices()
+
+	return nil
+}
+
+// returns the i-th key of the list; keys in a store are
+// not copied but referenced by scratch (which is returned)
+func (kl *KeyList) keyAt(i uint64, scratch *slot.Slot) *slot.Slot {
+	if kl.Store == nil {
+		return kl.Keys[i]
+	}
+
+	scratch.Data = kl.Store.Key(i)
+	return scratch
+}
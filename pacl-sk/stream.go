@@ -0,0 +1,167 @@
+package paclsk
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/sachaservan/pacl/slot"
+	dpf "github.com/sachaservan/vdpf"
+)
+
+// DefaultChunkSize is the number of keys that StreamAudit reads
+// and evaluates the DPF on at a time
+const DefaultChunkSize = 1 << 16
+
+// KeySource provides the entries (key index and key) of a key list in
+// order, so that audits can stream over lists that are not in memory
+type KeySource interface {
+	// ReadEntries reads the entries [start, start+len(indices)) of the list;
+	// the slots in keys may be reused if they are not nil
+	ReadEntries(start uint64, indices []uint64, keys []*slot.Slot) error
+}
+
+// ReadEntries implements KeySource for a key list that is in memory
+func (kl *KeyList) ReadEntries(start uint64, indices []uint64, keys []*slot.Slot) error {
+	if start+uint64(len(indices)) > kl.NumKeys {
+		return io.ErrUnexpectedEOF
+	}
+
+	copy(indices, kl.KeyIndices[start:])
+	if kl.Store == nil {
+		copy(keys, kl.Keys[start:])
+		return nil
+	}
+
+	for i := range keys {
+		if keys[i] == nil {
+			keys[i] = &slot.Slot{}
+		}
+		kl.keyAt(start+uint64(i), keys[i])
+	}
+
+	return nil
+}
+
+// StreamAudit is the same as Audit but reads the entries of the list from
+// src and evaluates the DPF on chunkSize entries at a time, so that its memory
+// does not depend on the size of the list. Only the parameters of kl are used
+// (its keys and indices may be nil). Chunks are always batch evaluated.
+func (kl *KeyList) StreamAudit(proof *ProofShare, src KeySource, chunkSize int) (*AuditShare, error) {
+
+	if kl.IsWide() || proof.DPFKey == nil {
+		return nil, errors.New("streaming audits require 64-bit key indices")
+	}
+
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+
+	pf := dpf.ServerDPFInitialize(proof.PrfKey)
+	eval := func(indices []uint64) []byte {
+		return pf.BatchEval(proof.DPFKey, indices)
+	}
+
+	return kl.streamAudit(kl.newKeyAccumulator(), proof, src, chunkSize, eval)
+}
+
+// StreamAudit with the keys combined by acc and the DPF evaluated by eval
+func (kl *KeyList) streamAudit(
+	acc keyAccumulator,
+	proof *ProofShare,
+	src KeySource,
+	chunkSize int,
+	eval func(indices []uint64) []byte,
+) (*AuditShare, error) {
+
+	indices := make([]uint64, chunkSize)
+	keys := make([]*slot.Slot, chunkSize)
+
+	for start := uint64(0); start < kl.NumKeys; start += uint64(chunkSize) {
+		n := kl.NumKeys - start
+		if n > uint64(chunkSize) {
+			n = uint64(chunkSize)
+		}
+
+		if err := src.ReadEntries(start, indices[:n], keys[:n]); err != nil {
+			return nil, err
+		}
+
+		bits := eval(indices[:n])
+		for i := uint64(0); i < n; i++ {
+			acc.xorMasked(keys[i], bits[i])
+		}
+	}
+
+	share := acc.sum()
+	slot.Xor(share, proof.KeyShare)
+
+	return &AuditShare{Share: share}, nil
+}
+
+// WriteKeyFile writes the entries of the list as fixed-width records
+// (the 8-byte big-endian key index followed by the key) that can be
+// read back with a FileKeySource
+func (kl *KeyList) WriteKeyFile(w io.Writer) error {
+
+	keySize := kl.StatSecurity / 8
+	record := make([]byte, 8+keySize)
+
+	scratch := &slot.Slot{}
+	for i := uint64(0); i < kl.NumKeys; i++ {
+		key := kl.keyAt(i, scratch)
+		if len(key.Data) != keySize {
+			return errors.New("keys must all have the same size")
+		}
+
+		binary.BigEndian.PutUint64(record, kl.KeyIndices[i])
+		copy(record[8:], key.Data)
+
+		if _, err := w.Write(record); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// FileKeySource reads the entries of a key list from the fixed-width
+// records written by WriteKeyFile (e.g., from an *os.File)
+type FileKeySource struct {
+	r       io.ReaderAt
+	keySize int
+	buf     []byte
+}
+
+// NewFileKeySource returns a source that reads keys of keySize bytes from r
+func NewFileKeySource(r io.ReaderAt, keySize int) *FileKeySource {
+	return &FileKeySource{r: r, keySize: keySize}
+}
+
+// ReadEntries implements KeySource
+func (src *FileKeySource) ReadEntries(start uint64, indices []uint64, keys []*slot.Slot) error {
+
+	recordSize := 8 + src.keySize
+	size := recordSize * len(indices)
+	if len(src.buf) < size {
+		src.buf = make([]byte, size)
+	}
+
+	// ReadAt may return io.EOF along with the last records
+	n, err := src.r.ReadAt(src.buf[:size], int64(start)*int64(recordSize))
+	if n < size {
+		return err
+	}
+
+	for i := range indices {
+		record := src.buf[i*recordSize : (i+1)*recordSize]
+		indices[i] = binary.BigEndian.Uint64(record)
+
+		if keys[i] == nil || len(keys[i].Data) != src.keySize {
+			keys[i] = slot.NewEmpty(src.keySize)
+		}
+		copy(keys[i].Data, record[8:])
+	}
+
+	return nil
+}
@@ -0,0 +1,35 @@
+package paclsposs
+
+import "github.com/sachaservan/pacl/algebra"
+
+// Audits combine every key of the list with its DPF bit (a conditional
+// addition) rather than branching on the bit, so that the time taken does
+// not leak the Hamming weight or the positions of the set bits of the
+// verifier's DPF share. The keys are combined through a keyAccumulator
+// (see the same file in pacl-sk).
+
+// keyAccumulator adds together the keys selected by the DPF bits
+type keyAccumulator interface {
+	// adds key to the sum if bit is 1
+	condAdd(key *algebra.FieldElement, bit byte)
+	// returns the sum and the parity of the bits
+	sum() (*algebra.FieldElement, bool)
+}
+
+type fieldAccumulator struct {
+	acc    *algebra.Accumulator
+	parity byte
+}
+
+func (kl *KeyList) newKeyAccumulator() keyAccumulator {
+	return &fieldAccumulator{acc: kl.Field.NewAccumulator()}
+}
+
+func (a *fieldAccumulator) condAdd(key *algebra.FieldElement, bit byte) {
+	a.acc.CondAdd(key, bit)
+	a.parity ^= bit & 1
+}
+
+func (a *fieldAccumulator) sum() (*algebra.FieldElement, bool) {
+	return a.acc.Sum(), a.parity == 1
+}
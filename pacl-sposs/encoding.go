@@ -0,0 +1,293 @@
+package paclsposs
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/big"
+
+	"github.com/sachaservan/pacl/algebra"
+	"github.com/sachaservan/pacl/dpf128"
+	"github.com/sachaservan/pacl/sposs"
+	dpf "github.com/sachaservan/vdpf"
+)
+
+// Proof and audit shares are encoded in binary (e.g., to be sent to the
+// verifiers or written to files) with the field and group elements in their
+// canonical fixed-width encodings (see algebra.Field.Encode):
+//
+//	proof: flags, share number, DPF key, SPoSS proof share, epoch, tag
+//	audit: flags, SPoSS audit share, VDPF proof, key share, tag share, epoch, tag
+//
+// where the flags (one byte) record which of the optional values are present,
+// byte strings are prefixed with their 4-byte big-endian length and the
+// 64-bit values are big-endian.
+
+const (
+	flagWide     = 1 << iota // proof: the DPF key is a dpf128.Key
+	flagTag                  // proof and audit: epoch mode (the tag is set)
+	flagShare                // audit: the SPoSS audit share is set
+	flagBitSum               // audit: the share of the sum of the DPF bits
+	flagKeyShare             // audit: the key share is set
+)
+
+// EncodeProof returns the encoding of the proof share
+func (kl *KeyListParams) EncodeProof(proof *ProofShare) ([]byte, error) {
+
+	if proof == nil || (proof.DPFKey == nil) == (proof.WideDPFKey == nil) {
+		return nil, errors.New("proof share must have exactly one DPF key")
+	}
+
+	if proof.ShareNumber > 1 {
+		return nil, errors.New("invalid share number")
+	}
+
+	flags := byte(0)
+	if proof.WideDPFKey != nil {
+		flags |= flagWide
+	}
+	if proof.Tag != nil {
+		flags |= flagTag
+	}
+
+	b := []byte{flags, byte(proof.ShareNumber)}
+	if proof.WideDPFKey != nil {
+		key, err := proof.WideDPFKey.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		b = appendBytes(b, key)
+	} else {
+		b = append(b, proof.PrfKey[:]...)
+		b = appendUint64(b, uint64(proof.DPFKey.RangeSize))
+		b = appendUint64(b, proof.DPFKey.Index)
+		b = appendBytes(b, proof.DPFKey.Bytes)
+	}
+
+	share, err := kl.ProofPP.EncodeProofShare(proof.ProofShare)
+	if err != nil {
+		return nil, err
+	}
+	b = append(b, share...)
+
+	if proof.Tag != nil {
+		b = appendUint64(b, proof.Epoch)
+		b = appendBytes(b, proof.Tag)
+	}
+
+	return b, nil
+}
+
+// DecodeProof returns the proof share of the encoding b
+// (which still needs to be validated, see ValidateProof)
+func (kl *KeyListParams) DecodeProof(b []byte) (*ProofShare, error) {
+
+	r := &reader{b: b}
+	header := r.next(2)
+	if r.err != nil || header[0]&^(flagWide|flagTag) != 0 || header[1] > 1 {
+		return nil, errors.New("malformed proof share header")
+	}
+
+	flags := header[0]
+	proof := &ProofShare{ShareNumber: uint(header[1])}
+	if flags&flagWide != 0 {
+		proof.WideDPFKey = &dpf128.Key{}
+		if key := r.bytes(); r.err == nil {
+			if err := proof.WideDPFKey.UnmarshalBinary(key); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		copy(proof.PrfKey[:], r.next(len(proof.PrfKey)))
+		proof.DPFKey = &dpf.DPFKey{}
+		proof.DPFKey.RangeSize = uint(r.uint64())
+		proof.DPFKey.Index = r.uint64()
+		proof.DPFKey.Bytes = r.bytes()
+	}
+
+	share := r.next(kl.ProofPP.ProofShareLen())
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	var err error
+	if proof.ProofShare, err = kl.ProofPP.DecodeProofShare(share); err != nil {
+		return nil, err
+	}
+
+	if flags&flagTag != 0 {
+		proof.Epoch = r.uint64()
+		proof.Tag = r.bytes()
+	}
+
+	if err := r.done(); err != nil {
+		return nil, err
+	}
+
+	return proof, nil
+}
+
+// EncodeAudit returns the encoding of the audit share
+func (kl *KeyListParams) EncodeAudit(audit *AuditShare) ([]byte, error) {
+
+	if audit == nil {
+		return nil, errors.New("missing audit share")
+	}
+
+	if (audit.Tag == nil) != (audit.TagShare == nil) {
+		return nil, errors.New("audit share must have both or neither of the tag and its share")
+	}
+
+	flags := byte(0)
+	if audit.Tag != nil {
+		flags |= flagTag
+	}
+	if audit.Share != nil {
+		flags |= flagShare
+	}
+	if audit.BitSum {
+		flags |= flagBitSum
+	}
+	if audit.KeyShare != nil {
+		flags |= flagKeyShare
+	}
+
+	b := []byte{flags}
+	if audit.Share != nil {
+		b = append(b, audit.Share.HashedData[:]...)
+	}
+	b = appendBytes(b, audit.Pi)
+
+	var err error
+	if audit.KeyShare != nil {
+		if b, err = kl.Field.AppendEncoding(b, audit.KeyShare); err != nil {
+			return nil, err
+		}
+	}
+
+	if audit.Tag != nil {
+		if b, err = kl.Field.AppendEncoding(b, audit.TagShare); err != nil {
+			return nil, err
+		}
+		b = appendUint64(b, audit.Epoch)
+		b = appendBytes(b, audit.Tag)
+	}
+
+	return b, nil
+}
+
+// DecodeAudit returns the audit share of the encoding b
+func (kl *KeyListParams) DecodeAudit(b []byte) (*AuditShare, error) {
+
+	r := &reader{b: b}
+	header := r.next(1)
+	if r.err != nil || header[0]&^(flagTag|flagShare|flagBitSum|flagKeyShare) != 0 {
+		return nil, errors.New("malformed audit share header")
+	}
+
+	flags := header[0]
+	audit := &AuditShare{BitSum: flags&flagBitSum != 0}
+	if flags&flagShare != 0 {
+		audit.Share = &sposs.AuditShare{}
+		copy(audit.Share.HashedData[:], r.next(len(audit.Share.HashedData)))
+	}
+	audit.Pi = r.bytes()
+
+	if flags&flagKeyShare != 0 {
+		audit.KeyShare = r.element(kl.Field)
+	}
+
+	if flags&flagTag != 0 {
+		audit.TagShare = r.element(kl.Field)
+		audit.Epoch = r.uint64()
+		audit.Tag = r.bytes()
+	}
+
+	if err := r.done(); err != nil {
+		return nil, err
+	}
+
+	return audit, nil
+}
+
+func appendUint64(b []byte, v uint64) []byte {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+// appends v prefixed with its 4-byte length
+func appendBytes(b, v []byte) []byte {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], uint32(len(v)))
+	return append(append(b, buf[:]...), v...)
+}
+
+// reads the values of an encoding in order; after the first error (e.g.,
+// a truncated encoding) every read returns zero values and err is kept
+type reader struct {
+	b   []byte
+	err error
+}
+
+func (r *reader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+
+	if n < 0 || len(r.b) < n {
+		r.err = errors.New("truncated encoding")
+		return nil
+	}
+
+	v := r.b[:n]
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *reader) uint64() uint64 {
+	if v := r.next(8); v != nil {
+		return binary.BigEndian.Uint64(v)
+	}
+
+	return 0
+}
+
+// reads a length-prefixed byte string (and returns a copy)
+func (r *reader) bytes() []byte {
+	n := r.next(4)
+	if n == nil {
+		return nil
+	}
+
+	v := r.next(int(binary.BigEndian.Uint32(n)))
+	if v == nil {
+		return nil
+	}
+
+	return append([]byte{}, v...)
+}
+
+// reads the fixed-width encoding of an element of f
+func (r *reader) element(f *algebra.Field) *algebra.FieldElement {
+	v := r.next(f.ByteLen())
+	if v == nil {
+		return nil
+	}
+
+	e := &algebra.FieldElement{Int: new(big.Int)}
+	if err := f.DecodeInto(e, v); err != nil {
+		r.err = err
+		return nil
+	}
+
+	return e
+}
+
+// returns the first error, or an error if there are bytes left
+func (r *reader) done() error {
+	if r.err == nil && len(r.b) != 0 {
+		return errors.New("trailing bytes after the encoding")
+	}
+
+	return r.err
+}
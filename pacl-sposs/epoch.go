@@ -0,0 +1,106 @@
+package paclsposs
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"math/big"
+
+	"github.com/sachaservan/pacl/algebra"
+)
+
+// NewEpochProof is the same as NewProof but the proof additionally carries
+// a deterministic per-key, per-epoch tag which the verifiers can use to
+// enforce access quotas without learning the index of the key.
+//
+// The tag is h^x where h is an element of prime order q hashed from the
+// epoch. Since q divides the order of the group (mod which x is shared),
+// the verifiers can check the tag against h^[x].
+func (kl *KeyListParams) NewEpochProof(idx uint64, x *algebra.FieldElement, epoch uint64) []*ProofShare {
+
+	tag := kl.EpochTag(x, epoch)
+
+	shares := kl.NewProof(idx, x)
+	for _, share := range shares {
+		share.Epoch = epoch
+		share.Tag = tag
+	}
+
+	return shares
+}
+
+// EpochTag returns the rate-limiting tag of the key x for the epoch
+func (kl *KeyListParams) EpochTag(x *algebra.FieldElement, epoch uint64) []byte {
+	tag, err := kl.Field.Encode(kl.Field.Exp(kl.epochBase(epoch), x.Int))
+	if err != nil {
+		panic(err) // Exp returns reduced elements
+	}
+
+	return tag
+}
+
+// hashes the epoch to an element h of the subgroup of prime order q: the
+// quadratic residues of a safe prime P = 2q+1, or the group itself when
+// it is the subgroup of a Schnorr group
+func (kl *KeyListParams) epochBase(epoch uint64) *algebra.FieldElement {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, epoch)
+
+	// expand the hash to the length of p plus some extra bytes to avoid bias
+	numBytes := kl.Field.ByteLen() + 16
+	digest := []byte{}
+	for ctr := uint32(0); len(digest) < numBytes; ctr++ {
+		block := make([]byte, 4)
+		binary.BigEndian.PutUint32(block, ctr)
+		h := sha256.Sum256(append(append(block, []byte("pacl-epoch")...), data...))
+		digest = append(digest, h[:]...)
+	}
+
+	h := kl.Field.NewElement(new(big.Int).SetBytes(digest[:numBytes]))
+	return kl.Field.Exp(h, kl.epochCofactor())
+}
+
+// (P-1)/q for the prime order q of the epoch base
+func (kl *KeyListParams) epochCofactor() *big.Int {
+	pMinus1 := kl.Field.Pminus1()
+	if kl.Group.Order.Cmp(pMinus1) == 0 {
+		return big.NewInt(2)
+	}
+
+	return new(big.Int).Div(pMinus1, kl.Group.Order)
+}
+
+func (kl *KeyList) computeEpochTagShare(proof *ProofShare, audit *AuditShare) {
+	audit.TagShare = kl.Field.Exp(kl.epochBase(proof.Epoch), proof.ProofShare.ShareX.Int)
+	audit.Epoch = proof.Epoch
+	audit.Tag = proof.Tag
+}
+
+// checks that h^[x] reconstructs to the tag and that all verifiers
+// audited the same epoch and tag (trivially true when not in epoch mode)
+func (kl *KeyList) checkEpochTag(auditShares ...*AuditShare) bool {
+
+	if auditShares[0].Tag == nil {
+		for i := 1; i < len(auditShares); i++ {
+			if auditShares[i].Tag != nil {
+				return false
+			}
+		}
+		return true
+	}
+
+	accumulator := auditShares[0].TagShare
+	for i := 1; i < len(auditShares); i++ {
+		if auditShares[i].Epoch != auditShares[0].Epoch {
+			return false
+		}
+
+		if !bytes.Equal(auditShares[i].Tag, auditShares[0].Tag) {
+			return false
+		}
+
+		accumulator = kl.Field.Mul(accumulator, auditShares[i].TagShare)
+	}
+
+	return accumulator.Int.Cmp(new(big.Int).SetBytes(auditShares[0].Tag)) == 0
+}
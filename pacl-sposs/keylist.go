@@ -9,17 +9,12 @@ import (
 	"time"
 
 	"github.com/sachaservan/pacl/algebra"
+	"github.com/sachaservan/pacl/dpf128"
+	"github.com/sachaservan/pacl/keystore"
 	"github.com/sachaservan/pacl/sposs"
 	dpf "github.com/sachaservan/vdpf"
 )
 
-// https://datatracker.ietf.org/doc/html/rfc3526#page-3
-const primeHexP = "FFFFFFFFFFFFFFFFC90FDAA22168C234C4C6628B80DC1CD129024E088A67CC74020BBEA63B139B22514A08798E3404DDEF9519B3CD3A431B302B0A6DF25F14374FE1356D6D51C245E485B576625E7EC6F44C42E9A637ED6B0BFF5CB6F406B7EDEE386BFB5A899FA5AE9F24117C4B1FE649286651ECE45B3DC2007CB8A163BF0598DA48361C55D39A69163FA8FD24CF5F83655D23DCA3AD961C62F356208552BB9ED529077096966D670C354E4ABC9804F1746C08CA18217C32905E462E36CE3BE39E772C180E86039B2783A2EC07A28FB5C55DF06F4C52C9DE2BCBF6955817183995497CEA956AE515D2261898FA051015728E5A8AAAC42DAD33170D04507A33A85521ABDF1CBA64ECFB850458DBEF0A8AEA71575D060C7DB3970F85A6E1E4C7ABF5AE8CDB0933D71E8C94E04A25619DCEE3D2261AD2EE6BF12FFA06D98A0864D87602733EC86A64521F2B18177B200CBBE117577A615D6C770988C0BAD946E208E24FA074E5AB3143DB5BFCE0FD108E4B82D120A93AD2CAFFFFFFFFFFFFFFFF"
-
-// generator g should have a generating subgroup of order 2q
-// (NOT the group of quadratic residues as commonly done)
-const generatorG = "5"
-
 type PredicateType int
 
 const (
@@ -28,7 +23,8 @@ const (
 )
 
 type KeyListParams struct {
-	FullDomain    bool
+	FullDomain    bool         // keys are ordered by index and fill the domain
+	EvalStrategy  EvalStrategy // how the DPF is expanded (see UseFullDomain)
 	NumKeys       uint64
 	FSSDomain     uint
 	KeyIndices    []uint64
@@ -38,33 +34,34 @@ type KeyListParams struct {
 	Field         *algebra.Field // field of order p (elements of Group live in Field)
 	ProofPP       *sposs.PublicParams
 	PredicateType PredicateType
+	ParamSet      string // identifier of the parameter set of Group ("" if not a named set)
+
+	// 128-bit key indices used instead of KeyIndices when the FSS domain
+	// is larger than 64 bits (or to prove with dpf128 keys in a smaller one)
+	WideKeyIndices []dpf128.Index
+}
+
+// IsWide returns true if the key indices are 128 bits
+func (kl *KeyListParams) IsWide() bool {
+	return kl.FSSDomain > 64 || kl.WideKeyIndices != nil
 }
 
 type KeyList struct {
 	KeyListParams
 	PublicKeys []*algebra.GroupElement
+
+	// memory-mapped keys used instead of PublicKeys (see UseKeyStore)
+	Store *keystore.Store `json:"-"`
 }
 
+// DefaultGroup returns the group of the default parameter set
+// (the 3072-bit prime of RFC 3526 with a generator of order 2q)
 func DefaultGroup() *algebra.Group {
 	rand.Seed(time.Now().Unix())
 
-	p := FromSafeHex(primeHexP)
-	g := FromSafeHex(generatorG)
-
-	// Initialize field values
-	baseField := algebra.NewField(p)
-
-	group := algebra.NewGroup(baseField, baseField.NewElement(g))
-
-	if !p.ProbablyPrime(10) {
-		panic("p is not prime")
-	}
-
-	q := group.Field.Pminus1()
-	q.Div(q, big.NewInt(2))
-
-	if big.NewInt(0).Exp(g, q, p).Cmp(big.NewInt(1)) == 0 {
-		panic("g isn't a generator of order 2q")
+	group, err := NewGroup(DefaultParamSet)
+	if err != nil {
+		panic(err)
 	}
 
 	return group
@@ -94,6 +91,7 @@ func GenerateRandomKeyList(
 	kl.NumKeys = numKeys
 	kl.Group = group
 	kl.Field = group.Field
+	kl.ParamSet = IdentifyParamSet(group)
 	kl.FSSDomain = fssDomain
 	kl.PredicateType = pred
 	kl.KeyIndices = make([]uint64, numKeys)
@@ -133,6 +131,7 @@ func GenerateTestingKeyList(
 	kl.NumKeys = numKeys
 	kl.Group = group
 	kl.Field = group.Field
+	kl.ParamSet = IdentifyParamSet(group)
 	kl.FSSDomain = fssDomain
 	kl.KeyIndices = make([]uint64, numKeys)
 	kl.FullDomain = (1<<fssDomain == numKeys) // only applies when domain = #keys
@@ -174,6 +173,7 @@ func GenerateBenchmarkKeyList(
 	kl.NumKeys = numKeys
 	kl.Group = group
 	kl.Field = group.Field
+	kl.ParamSet = IdentifyParamSet(group)
 	kl.PredicateType = pred
 	kl.FSSDomain = fssDomain
 	kl.KeyIndices = make([]uint64, numKeys)
@@ -206,8 +206,17 @@ func (kl *KeyList) CloneKeyList() *KeyList {
 	clone.HKey2 = kl.HKey2
 	clone.FSSDomain = kl.FSSDomain
 	clone.FullDomain = kl.FullDomain
+	clone.EvalStrategy = kl.EvalStrategy
 	clone.KeyIndices = kl.KeyIndices
+	clone.WideKeyIndices = kl.WideKeyIndices
 	clone.PredicateType = kl.PredicateType
+	clone.ParamSet = kl.ParamSet
+	clone.Store = kl.Store
+
+	if kl.Store != nil {
+		clone.PublicKeys = nil
+		return &clone
+	}
 
 	for i := uint64(0); i < kl.NumKeys; i++ {
 		clone.PublicKeys[i] = kl.PublicKeys[i].Copy()
@@ -216,8 +225,12 @@ func (kl *KeyList) CloneKeyList() *KeyList {
 	return &clone
 }
 
-// sets g^x to -g^x = p-g^x
+// sets g^x to -g^x = p-g^x (for the second verifier, see NewProof)
 func (kl *KeyList) FlipSignOfKeys() {
+	if kl.Store != nil {
+		panic("the keys of a key store cannot be flipped; store the flipped list instead")
+	}
+
 	for i, k := range kl.PublicKeys {
 		newVal := kl.Field.Sub(kl.Field.NewElement(kl.Field.P), k.Value)
 		kl.PublicKeys[i] = &algebra.GroupElement{Value: newVal}
@@ -1,10 +1,11 @@
 package paclsposs
 
 import (
-	"bytes"
+	"crypto/subtle"
 	"math/big"
 
 	"github.com/sachaservan/pacl/algebra"
+	"github.com/sachaservan/pacl/dpf128"
 	"github.com/sachaservan/pacl/sposs"
 	dpf "github.com/sachaservan/vdpf"
 )
@@ -12,8 +13,11 @@ import (
 type ProofShare struct {
 	DPFKey      *dpf.DPFKey // DPF or VDPF key
 	PrfKey      dpf.PrfKey  // prf used for PRG
+	WideDPFKey  *dpf128.Key // verifiable DPF key over 128-bit indices (used instead of DPFKey)
 	ShareNumber uint
 	ProofShare  *sposs.ProofShare // public key (Schnorr) PACL for VDPFs
+	Epoch       uint64            // epoch of the rate-limiting tag (epoch mode only)
+	Tag         []byte            // per-key, per-epoch tag (epoch mode only)
 }
 
 type AuditShare struct {
@@ -21,6 +25,43 @@ type AuditShare struct {
 	BitSum   bool
 	Pi       []byte                // VDPF proof
 	KeyShare *algebra.FieldElement // for testing purposes
+	TagShare *algebra.FieldElement // h^[x] for h derived from the epoch (epoch mode only)
+	Epoch    uint64
+	Tag      []byte
+}
+
+// Size returns the number of bytes the prover sends to one verifier
+func (share *ProofShare) Size() int {
+	size := dpfKeySize(share.DPFKey) + share.WideDPFKey.Size() + share.ProofShare.Size()
+	if share.DPFKey != nil {
+		size += len(share.PrfKey)
+	}
+
+	if share.Tag != nil {
+		size += 8 + len(share.Tag) // epoch and tag
+	}
+
+	return size
+}
+
+// Size returns the number of bytes a verifier sends to the other verifier
+// (KeyShare is only used for testing and is not sent)
+func (share *AuditShare) Size() int {
+	size := share.Share.Size() + 1 + len(share.Pi) + share.TagShare.Size()
+	if share.Tag != nil {
+		size += 8 + len(share.Tag) // epoch and tag
+	}
+
+	return size
+}
+
+// size of the key material of a DPF key
+func dpfKeySize(key *dpf.DPFKey) int {
+	if key == nil {
+		return 0
+	}
+
+	return len(key.Bytes)
 }
 
 func (kl *KeyListParams) NewProof(idx uint64, x *algebra.FieldElement) []*ProofShare {
@@ -37,52 +78,105 @@ func (kl *KeyListParams) NewProof(idx uint64, x *algebra.FieldElement) []*ProofS
 	// gen the dpf keys
 	keyA, keyB := pf.GenVDPFKeys(idx, kl.FSSDomain)
 
-	proofX := new(big.Int).Set(x.Int)
-
-	// flip x based on which server the key is "retrieved" from
+	// the verifiers select -g^x when the key is "retrieved" from the second
+	// server (which holds the flipped list), so the proof is then of -g^x;
+	// the sign is shared so that it works in groups of prime order
+	// (where -g^x is not a power of g)
 	resB := pf.BatchEval(keyB, []uint64{idx})
-	if resB[0] == 1 {
-		// we need to compute x' such that g^x' = -g^x = p - g^x mod p = -1g^x mod p = g^q+x
-		// compute q + x mod 2q
-		q := kl.Field.Pminus1()
-		q.Div(q, big.NewInt(2))
-		proofX = new(big.Int).Add(proofX, q)
-		proofX.Mod(proofX, kl.Field.Pminus1())
+
+	shares := kl.newProofShares(x, resB[0] == 1)
+	shares[0].PrfKey = pf.PrfKey
+	shares[0].DPFKey = keyA
+	shares[1].PrfKey = pf.PrfKey
+	shares[1].DPFKey = keyB
+
+	return shares
+}
+
+// NewWideProof is the same as NewProof but for key lists with 128-bit indices
+func (kl *KeyListParams) NewWideProof(idx dpf128.Index, x *algebra.FieldElement) []*ProofShare {
+
+	if kl.NumKeys == 0 {
+		panic("list size is set to zero; something is wrong")
 	}
 
-	spossProofA, spossProofB := kl.ProofPP.GenProof(kl.ProofPP.ExpField.NewElement(proofX))
+	keyA, keyB := dpf128.GenVerifiableKeys(idx, kl.FSSDomain)
+
+	return kl.NewWideProofWithDPFKeys(idx, keyA, keyB, x)
+}
+
+// NewWideProofWithDPFKeys is the same as NewWideProof but uses the given
+// verifiable DPF keys for idx (e.g., keys that also carry a payload)
+// instead of generating new ones
+func (kl *KeyListParams) NewWideProofWithDPFKeys(idx dpf128.Index, keyA, keyB *dpf128.Key, x *algebra.FieldElement) []*ProofShare {
+
+	resB, _ := dpf128.Eval(keyB, idx)
+
+	shares := kl.newProofShares(x, resB == 1)
+	shares[0].WideDPFKey = keyA
+	shares[1].WideDPFKey = keyB
+
+	return shares
+}
+
+// returns the proof shares of both verifiers (without DPF keys) with the
+// SPoSS proof of g^x, negated if the key is "retrieved" from the second server
+func (kl *KeyListParams) newProofShares(x *algebra.FieldElement, negate bool) []*ProofShare {
+
+	spossProofA, spossProofB := kl.ProofPP.GenSignedProof(kl.ProofPP.ExpField.NewElement(x.Int), negate)
 
 	shares := make([]*ProofShare, 2)
 
 	// share for server A
 	shares[0] = &ProofShare{}
 	shares[0].ShareNumber = 0
-	shares[0].PrfKey = pf.PrfKey
-	shares[0].DPFKey = keyA
 	shares[0].ProofShare = spossProofA
 
 	// share for server B
 	shares[1] = &ProofShare{}
 	shares[1].ShareNumber = 1
-	shares[1].PrfKey = pf.PrfKey
-	shares[1].DPFKey = keyB
 	shares[1].ProofShare = spossProofB
 
 	return shares
 }
 
+// Audit returns the verifier's audit share for the proof share; malformed
+// proof shares (see ValidateProof) get an empty audit share that fails CheckAudit
 func (kl *KeyList) Audit(proof *ProofShare) *AuditShare {
+	if kl.ValidateProof(proof) != nil {
+		return &AuditShare{}
+	}
+
 	bits, pi := kl.ExpandVDPF(proof)
 	return kl.computePrepareAudit(proof, bits, pi)
 }
 
 func (kl *KeyList) CheckAudit(auditShares ...*AuditShare) bool {
 
-	vdpfOk := bytes.Equal(auditShares[0].Pi, auditShares[1].Pi)
+	if len(auditShares) != 2 || auditShares[0] == nil || auditShares[1] == nil {
+		return false
+	}
+
+	// the audit share of a malformed proof
+	if auditShares[0].Share == nil || auditShares[1].Share == nil {
+		return false
+	}
+
+	vdpfOk := len(auditShares[0].Pi) != 0 && subtle.ConstantTimeCompare(auditShares[0].Pi, auditShares[1].Pi) == 1
 	spossOk := kl.ProofPP.CheckAudit(auditShares[0].Share, auditShares[1].Share)
 	sumOk := auditShares[0].BitSum != auditShares[1].BitSum
 
-	return vdpfOk && spossOk && sumOk
+	return vdpfOk && spossOk && sumOk && kl.checkEpochTag(auditShares...)
+}
+
+// AuditExpanded is the same as Audit but re-uses the VDPF bits and proof that
+// were already expanded from the proof's DPF key (e.g., when the same key is also used to write)
+func (kl *KeyList) AuditExpanded(proof *ProofShare, bits []byte, pi []byte) *AuditShare {
+	if kl.ValidateProof(proof) != nil {
+		return &AuditShare{}
+	}
+
+	return kl.computePrepareAudit(proof, bits, pi)
 }
 
 func (kl *KeyList) ExpandVDPF(proof *ProofShare) ([]byte, []byte) {
@@ -90,11 +184,22 @@ func (kl *KeyList) ExpandVDPF(proof *ProofShare) ([]byte, []byte) {
 	var res []byte
 	var pi []byte
 
+	if kl.IsWide() {
+		res, pi, err := dpf128.BatchVerEval(proof.WideDPFKey, kl.WideKeyIndices)
+		if err != nil {
+			// malformed key (see ValidateProof): an empty proof fails the audit
+			return make([]byte, kl.NumKeys), nil
+		}
+
+		return res, pi
+	}
+
 	pf := dpf.ServerVDPFInitialize(proof.PrfKey, [2]dpf.HashKey{kl.HKey1, kl.HKey2})
 
-	if kl.FullDomain {
+	if kl.UseFullDomain() {
 		// run the optimized full-domain evaluation strategy
 		res, pi = pf.FullDomainVerEval(proof.DPFKey)
+		res = kl.lookupFullDomain(res)
 	} else {
 		res, pi = pf.BatchVerEval(proof.DPFKey, kl.KeyIndices)
 	}
@@ -104,18 +209,39 @@ func (kl *KeyList) ExpandVDPF(proof *ProofShare) ([]byte, []byte) {
 // uses the expanded DPF bits to "select" the public key in the keylist
 // over which the audit is going to be performed
 func (kl *KeyList) computePrepareAudit(proof *ProofShare, bits []byte, pi []byte) *AuditShare {
+	return kl.computeSignedAudit(kl.newKeyAccumulator(), proof, bits, pi, false)
+}
 
-	// final result
-	accumulator := kl.Field.AddIdentity()
-	bitSum := false
+// same as computePrepareAudit but with the keys combined by acc, and
+// negates the selected key if negate is set (i.e., audits over the flipped
+// list without flipping it, see ThresholdAudit)
+func (kl *KeyList) computeSignedAudit(
+	acc keyAccumulator,
+	proof *ProofShare,
+	bits []byte,
+	pi []byte,
+	negate bool,
+) *AuditShare {
+
+	key := &algebra.GroupElement{Value: &algebra.FieldElement{Int: new(big.Int)}}
 	for i := uint64(0); i < kl.NumKeys; i++ {
-		if bits[i] == 1 {
-			// add result to running sum (mod q)
-			kl.Field.AddInplace(accumulator, kl.PublicKeys[i].Value)
-			bitSum = !bitSum
-		}
+		// add result to running sum (mod q) with a conditional move
+		// so that the time taken does not depend on the DPF bits
+		acc.condAdd(kl.keyAt(i, key).Value, bits[i])
+	}
+
+	// final result
+	accumulator, bitSum := acc.sum()
+
+	if negate {
+		accumulator = kl.Field.Negate(accumulator)
 	}
 
 	spossAudit := kl.ProofPP.Audit(accumulator, proof.ProofShare)
-	return &AuditShare{Share: spossAudit, Pi: pi, KeyShare: accumulator, BitSum: bitSum}
+	audit := &AuditShare{Share: spossAudit, Pi: pi, KeyShare: accumulator, BitSum: bitSum}
+	if proof.Tag != nil {
+		kl.computeEpochTagShare(proof, audit)
+	}
+
+	return audit
 }
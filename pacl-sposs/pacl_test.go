@@ -1,8 +1,22 @@
 package paclsposs
 
 import (
+	"bytes"
 	"fmt"
+	"io"
+	"math/big"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
 	"testing"
+
+	"github.com/sachaservan/pacl/algebra"
+	"github.com/sachaservan/pacl/dpf128"
+	"github.com/sachaservan/pacl/internal/benchsweep"
+	"github.com/sachaservan/pacl/keystore"
+	dpf "github.com/sachaservan/vdpf"
 )
 
 // test configuration parameters
@@ -46,6 +60,668 @@ func TestProveAuditVerify(t *testing.T) {
 	}
 }
 
+func TestProveAuditVerifyWide(t *testing.T) {
+
+	kl, key, _, _ := GenerateTestingKeyList(TestNumKeys, TestFSSDomain, DefaultGroup(), Equality, 0)
+	kl.FSSDomain = dpf128.MaxDomain
+	kl.KeyIndices = nil
+	kl.WideKeyIndices = make([]dpf128.Index, kl.NumKeys)
+	for i := range kl.WideKeyIndices {
+		kl.WideKeyIndices[i] = dpf128.HashToIndex([]byte{byte(i), byte(i >> 8)})
+	}
+
+	if err := kl.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	klB := kl.CloneKeyList()
+	klB.FlipSignOfKeys()
+
+	idx := kl.WideKeyIndices[42]
+	proofShares := kl.NewWideProof(idx, key)
+	if !kl.CheckAudit(kl.Audit(proofShares[0]), klB.Audit(proofShares[1])) {
+		t.Fatalf("CheckAudit failed")
+	}
+
+	// the wrong key must be rejected
+	wrongKey := &algebra.FieldElement{Int: new(big.Int).Add(key.Int, big.NewInt(1))}
+	proofShares = kl.NewWideProof(idx, wrongKey)
+	if kl.CheckAudit(kl.Audit(proofShares[0]), klB.Audit(proofShares[1])) {
+		t.Fatalf("CheckAudit accepted the wrong key")
+	}
+
+	// malformed keys and 64-bit proofs must be rejected
+	proofShares = kl.NewWideProof(idx, key)
+	proofShares[0].WideDPFKey = &dpf128.Key{Domain: kl.FSSDomain}
+	if kl.ValidateProof(proofShares[0]) == nil {
+		t.Fatalf("Accepted a malformed DPF key")
+	}
+
+	proofShares = kl.NewWideProof(idx, key)
+	proofShares[0].WideDPFKey, proofShares[0].DPFKey = nil, &dpf.DPFKey{}
+	if kl.ValidateProof(proofShares[0]) == nil {
+		t.Fatalf("Accepted a 64-bit DPF key for a list with 128-bit indices")
+	}
+}
+
+func TestEvalStrategies(t *testing.T) {
+
+	// dense list: 512 keys in a domain of 2^10
+	kl, key, _, keyIdx := GenerateTestingKeyList(TestNumKeys, 10, DefaultGroup(), Equality, 0)
+	distinctIndices(kl.KeyIndices, 10)
+	keyIdx = kl.KeyIndices[0]
+	if !kl.UseFullDomain() {
+		t.Fatalf("Expected the full-domain strategy for a dense list")
+	}
+
+	proofShares := kl.NewProof(keyIdx, key)
+
+	for _, strategy := range []EvalStrategy{AutoStrategy, BatchStrategy, FullDomainStrategy} {
+		kl.EvalStrategy = strategy
+
+		klB := kl.CloneKeyList()
+		klB.FlipSignOfKeys()
+
+		auditA := kl.Audit(proofShares[0])
+		auditB := klB.Audit(proofShares[1])
+
+		if !kl.CheckAudit(auditA, auditB) {
+			t.Fatalf("CheckAudit failed with strategy %v", strategy)
+		}
+	}
+}
+
+func TestFullDomainLookup(t *testing.T) {
+
+	// unreduced indices (drawn with rand.Uint64) in a domain of 2^12
+	fssDomain := uint(12)
+	kl := GenerateRandomKeyList(64, fssDomain, DefaultGroup(), Equality, 0)
+	idx := kl.KeyIndices[3]
+
+	pf := dpf.ClientVDPFInitialize(dpf.GeneratePRFKey(), [2]dpf.HashKey{kl.HKey1, kl.HKey2})
+	keyA, keyB := pf.GenVDPFKeys(idx, fssDomain)
+
+	var bits [2][]byte
+	for i, key := range []*dpf.DPFKey{keyA, keyB} {
+		proof := &ProofShare{PrfKey: pf.PrfKey, DPFKey: key}
+
+		kl.EvalStrategy = BatchStrategy
+		bits[i], _ = kl.ExpandVDPF(proof)
+
+		kl.EvalStrategy = FullDomainStrategy
+		if res, _ := kl.ExpandVDPF(proof); !bytes.Equal(res, bits[i]) {
+			t.Fatalf("Full-domain lookup does not match BatchEval for share %v", i)
+		}
+	}
+
+	// the DPF selects the keys whose index matches idx on the low bits
+	mask := uint64(1)<<fssDomain - 1
+	for i, keyIdx := range kl.KeyIndices {
+		if (bits[0][i] != bits[1][i]) != (keyIdx&mask == idx&mask) {
+			t.Fatalf("Wrong output for key %v", i)
+		}
+	}
+}
+
+func TestStreamAudit(t *testing.T) {
+
+	kl, key, _, keyIdx := GenerateTestingKeyList(
+		TestNumKeys, TestFSSDomain, DefaultGroup(), TestPredicate, TestNumSubkeys)
+
+	klB := kl.CloneKeyList()
+	klB.FlipSignOfKeys()
+
+	var fileA, fileB bytes.Buffer
+	if err := kl.WriteKeyFile(&fileA); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := klB.WriteKeyFile(&fileB); err != nil {
+		t.Fatal(err)
+	}
+
+	srcA := NewFileKeySource(bytes.NewReader(fileA.Bytes()), kl.Field)
+	srcB := NewFileKeySource(bytes.NewReader(fileB.Bytes()), kl.Field)
+
+	for _, epoch := range []bool{false, true} {
+		proofShares := kl.NewProof(keyIdx, key)
+		if epoch {
+			proofShares = kl.NewEpochProof(keyIdx, key, 1)
+		}
+
+		// the chunk size does not divide the list size
+		auditA, err := kl.StreamAudit(proofShares[0], srcA, 1000)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		auditB, err := klB.StreamAudit(proofShares[1], srcB, 1000)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !kl.CheckAudit(auditA, auditB) {
+			t.Fatalf("CheckAudit failed on streamed audits (epoch mode: %v)", epoch)
+		}
+	}
+
+	// the VDPF proof covers the whole list, whatever the chunk size
+	proofShares := kl.NewProof(keyIdx, key)
+	expected := kl.Audit(proofShares[0]).Pi
+	for _, chunkSize := range []int{1000, int(kl.NumKeys)} {
+		audit, err := kl.StreamAudit(proofShares[0], kl, chunkSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(audit.Pi, expected) {
+			t.Fatalf("Streamed VDPF proof does not match with chunks of %v keys", chunkSize)
+		}
+	}
+
+	// the keys read from a file are reused
+	indices := make([]uint64, 10)
+	keys := make([]*algebra.GroupElement, 10)
+	if err := srcA.ReadEntries(0, indices, keys); err != nil {
+		t.Fatal(err)
+	}
+
+	first := keys[0]
+	if err := srcA.ReadEntries(10, indices, keys); err != nil || keys[0] != first {
+		t.Fatalf("FileKeySource did not reuse the keys (%v)", err)
+	}
+
+	if !kl.Field.Equal(keys[0].Value, kl.PublicKeys[10].Value) {
+		t.Fatalf("FileKeySource read the wrong key")
+	}
+
+	// records must hold canonical encodings (here P instead of a reduced key)
+	corrupt := append([]byte{}, fileA.Bytes()...)
+	kl.Field.P.FillBytes(corrupt[8 : 8+kl.Field.ByteLen()])
+	src := NewFileKeySource(bytes.NewReader(corrupt), kl.Field)
+	if _, err := kl.StreamAudit(proofShares[0], src, 1000); err == nil {
+		t.Fatalf("StreamAudit accepted a non-canonical key")
+	}
+}
+
+func TestDataIndependentAudit(t *testing.T) {
+
+	kl, key, _, keyIdx := GenerateTestingKeyList(
+		TestNumKeys, TestFSSDomain, DefaultGroup(), TestPredicate, TestNumSubkeys)
+	proofShares := kl.NewProof(keyIdx, key)
+
+	var traces [][]string
+	for _, bits := range testBitPatterns(kl.NumKeys) {
+		acc := &recordingAccumulator{keyAccumulator: kl.newKeyAccumulator()}
+		kl.computeSignedAudit(acc, proofShares[0], bits, nil, false)
+		traces = append(traces, acc.ops)
+	}
+	checkSameOps(t, traces)
+
+	// streamed audits, with the VDPF outputs replaced by the patterns
+	traces = nil
+	for _, bits := range testBitPatterns(kl.NumKeys) {
+		acc := &recordingAccumulator{keyAccumulator: kl.newKeyAccumulator()}
+		eval := func(indices []uint64) ([]byte, []byte) { return bits, nil }
+		if _, err := kl.streamAudit(acc, proofShares[0], kl, 100, eval); err != nil {
+			t.Fatal(err)
+		}
+		traces = append(traces, acc.ops)
+	}
+	checkSameOps(t, traces)
+
+	// the recorded operations are those of Audit
+	acc := &recordingAccumulator{keyAccumulator: kl.newKeyAccumulator()}
+	bits, pi := kl.ExpandVDPF(proofShares[0])
+	audit := kl.computeSignedAudit(acc, proofShares[0], bits, pi, false)
+	if audit.KeyShare.Int.Cmp(kl.Audit(proofShares[0]).KeyShare.Int) != 0 {
+		t.Fatalf("Recorded audit does not match Audit")
+	}
+}
+
+func TestKeyStore(t *testing.T) {
+
+	kl, key, _, keyIdx := GenerateTestingKeyList(
+		TestNumKeys, TestFSSDomain, DefaultGroup(), TestPredicate, TestNumSubkeys)
+
+	klB := kl.CloneKeyList()
+	klB.FlipSignOfKeys()
+
+	// each verifier maps its own list
+	dir := t.TempDir()
+	writeKeyFile(t, filepath.Join(dir, "a"), kl.WriteKeyFile)
+	writeKeyFile(t, filepath.Join(dir, "b"), klB.WriteKeyFile)
+
+	stored := make([]*KeyList, 2)
+	for i, name := range []string{"a", "b"} {
+		store, err := keystore.Open(filepath.Join(dir, name), kl.Field.ByteLen())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer store.Close()
+
+		stored[i] = &KeyList{KeyListParams: kl.KeyListParams}
+		if err := stored[i].UseKeyStore(store); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	proofShares := kl.NewEpochProof(keyIdx, key, 1)
+	auditA := stored[0].Audit(proofShares[0])
+	auditB := stored[1].CloneKeyList().Audit(proofShares[1])
+
+	if !kl.CheckAudit(auditA, auditB) {
+		t.Fatalf("CheckAudit failed with key stores")
+	}
+}
+
+func TestValidation(t *testing.T) {
+
+	group := DefaultGroup()
+	kl, key, _, keyIdx := GenerateTestingKeyList(
+		TestNumKeys, TestFSSDomain, group, TestPredicate, TestNumSubkeys)
+
+	klB := kl.CloneKeyList()
+	klB.FlipSignOfKeys()
+
+	if err := kl.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := klB.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	// small-order and out-of-range keys
+	p := group.Field.P
+	invalid := []*big.Int{big.NewInt(0), big.NewInt(1), new(big.Int).Sub(p, big.NewInt(1)), p, new(big.Int).Add(p, big.NewInt(5)), big.NewInt(-5)}
+	for _, value := range invalid {
+		bad := kl.CloneKeyList()
+		bad.PublicKeys[7] = &algebra.GroupElement{Value: &algebra.FieldElement{Int: value}}
+		if err := bad.Validate(); err == nil {
+			t.Fatalf("Validate accepted the key %v", value)
+		}
+	}
+
+	// a store with a small-order key
+	var file bytes.Buffer
+	if err := kl.WriteKeyFile(&file); err != nil {
+		t.Fatal(err)
+	}
+	records := file.Bytes()
+	big.NewInt(1).FillBytes(records[8 : 8+kl.Field.ByteLen()])
+
+	path := filepath.Join(t.TempDir(), "keys")
+	writeKeyFile(t, path, func(w io.Writer) error {
+		_, err := w.Write(records)
+		return err
+	})
+
+	store, err := keystore.Open(path, kl.Field.ByteLen())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	stored := &KeyList{KeyListParams: kl.KeyListParams}
+	if err := stored.UseKeyStore(store); err == nil {
+		t.Fatalf("UseKeyStore accepted a small-order key")
+	}
+
+	// proof values out of range and small-order tags
+	proofShares := kl.NewEpochProof(keyIdx, key, 1)
+	if err := kl.ValidateProof(proofShares[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	identity, err := group.Encode(group.Identity())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	malformed := []func(proof *ProofShare){
+		func(proof *ProofShare) { proof.ProofShare.ShareU = &algebra.FieldElement{Int: p} },
+		func(proof *ProofShare) { proof.ProofShare.ShareX = &algebra.FieldElement{Int: big.NewInt(-1)} },
+		func(proof *ProofShare) { proof.ProofShare.R = nil },
+		func(proof *ProofShare) { proof.Tag = identity },
+		func(proof *ProofShare) { proof.Tag = proof.Tag[1:] },
+		func(proof *ProofShare) { proof.DPFKey = nil },
+	}
+
+	for i, malform := range malformed {
+		proofShares := kl.NewEpochProof(keyIdx, key, 1)
+		malform(proofShares[0])
+
+		if err := kl.ValidateProof(proofShares[0]); err == nil {
+			t.Fatalf("ValidateProof accepted malformed proof %v", i)
+		}
+
+		if kl.CheckAudit(kl.Audit(proofShares[0]), klB.Audit(proofShares[1])) {
+			t.Fatalf("CheckAudit accepted malformed proof %v", i)
+		}
+	}
+}
+
+func TestParamSets(t *testing.T) {
+
+	for _, id := range ParamSetIDs() {
+		ps, err := LookupParamSet(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if ps.Bits > 3072 && testing.Short() {
+			continue
+		}
+
+		p := ps.P()
+		group := ps.Group()
+		if IdentifyParamSet(group) != id {
+			t.Fatalf("%v: IdentifyParamSet returned %q", id, IdentifyParamSet(group))
+		}
+
+		if ps.IsSubgroup() {
+			// g must have prime order q (of OrderBits bits) dividing p-1
+			q := ps.Order()
+			if p.BitLen() != ps.Bits || !p.ProbablyPrime(20) || !q.ProbablyPrime(20) || q.BitLen() != ps.OrderBits {
+				t.Fatalf("%v: p is not a %v-bit prime with a %v-bit prime subgroup", id, ps.Bits, ps.OrderBits)
+			}
+			if new(big.Int).Mod(group.Field.Pminus1(), q).Sign() != 0 {
+				t.Fatalf("%v: q does not divide p-1", id)
+			}
+			if group.Field.IsMulIdentity(group.G) || !group.Field.IsMulIdentity(group.Field.Exp(group.G, q)) {
+				t.Fatalf("%v: generator does not have order q", id)
+			}
+			continue
+		}
+
+		// p = 2q + 1 must be a safe prime
+		q := new(big.Int).Rsh(p, 1)
+		if p.BitLen() != ps.Bits || !p.ProbablyPrime(20) || !q.ProbablyPrime(20) {
+			t.Fatalf("%v: p is not a %v-bit safe prime", id, ps.Bits)
+		}
+
+		// g must have order 2q (g^q = -1) and be the smallest such element
+		minusOne := new(big.Int).Sub(p, big.NewInt(1))
+		if new(big.Int).Exp(group.G.Int, q, p).Cmp(minusOne) != 0 {
+			t.Fatalf("%v: generator does not have order 2q", id)
+		}
+		for g := int64(2); g < ps.generator; g++ {
+			if new(big.Int).Exp(big.NewInt(g), q, p).Cmp(minusOne) == 0 {
+				t.Fatalf("%v: %v is a smaller generator of order 2q", id, g)
+			}
+		}
+	}
+
+	if _, err := NewGroup("modp1024"); err == nil {
+		t.Fatalf("NewGroup accepted an unknown parameter set")
+	}
+
+	kl := GenerateRandomKeyList(4, 2, DefaultGroup(), Equality, 1)
+	if kl.ParamSet != DefaultParamSet {
+		t.Fatalf("key list has parameter set %q instead of %q", kl.ParamSet, DefaultParamSet)
+	}
+
+	if err := kl.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []string{FFDHE3072, "modp1024"} {
+		bad := kl.CloneKeyList()
+		bad.ParamSet = id
+		if err := bad.Validate(); err == nil {
+			t.Fatalf("Validate accepted the parameter set %q", id)
+		}
+	}
+}
+
+func TestSubgroupMode(t *testing.T) {
+
+	group, err := NewGroup(MODP2048S256)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kl, key, _, keyIdx := GenerateTestingKeyList(
+		TestNumKeys, TestFSSDomain, group, TestPredicate, TestNumSubkeys)
+
+	// the flipped keys of the second verifier are not in
+	// the group, but are valid up to their sign
+	klB := kl.CloneKeyList()
+	klB.FlipSignOfKeys()
+	if err := kl.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := klB.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 10; i++ {
+		proofShares := kl.NewEpochProof(keyIdx, key, uint64(i))
+		if proofShares[0].ProofShare.ShareX.Int.BitLen() > 256 {
+			t.Fatalf("Share of x is not a 256-bit exponent")
+		}
+
+		auditA := kl.Audit(proofShares[0])
+		auditB := klB.Audit(proofShares[1])
+		if !kl.CheckAudit(auditA, auditB) {
+			t.Fatalf("CheckAudit failed")
+		}
+	}
+
+	// a different key is rejected
+	other := kl.ProofPP.ExpField.RandomElement()
+	proofShares := kl.NewProof(keyIdx, other)
+	if kl.CheckAudit(kl.Audit(proofShares[0]), klB.Audit(proofShares[1])) {
+		t.Fatalf("CheckAudit accepted the wrong key")
+	}
+
+	// exponents are 256 bits instead of 3072 bits
+	klDefault, keyDefault, _, keyIdxDefault := GenerateTestingKeyList(
+		TestNumKeys, TestFSSDomain, DefaultGroup(), TestPredicate, TestNumSubkeys)
+	if proofShares[0].Size() >= klDefault.NewProof(keyIdxDefault, keyDefault)[0].Size() {
+		t.Fatalf("Proof over the subgroup is not smaller")
+	}
+}
+
+func TestThresholdAudit(t *testing.T) {
+
+	group, err := NewGroup(MODP2048S256)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kl, key, _, keyIdx := GenerateTestingKeyList(
+		TestNumKeys, TestFSSDomain, group, TestPredicate, TestNumSubkeys)
+
+	// 3 verifiers of which any 2 can audit
+	proofs, err := kl.NewThresholdProof(keyIdx, key, 2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, online := range [][]int{{0, 1}, {0, 2}, {2, 1}, {0, 1, 2}} {
+		if !runThresholdAudit(t, kl, proofs, online) {
+			t.Fatalf("CheckAudit failed for verifiers %v", online)
+		}
+	}
+
+	// 4 verifiers of which any 3 can audit
+	proofs, err = kl.NewThresholdProof(keyIdx, key, 3, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, online := range [][]int{{0, 1, 2}, {0, 1, 3}, {0, 2, 3}, {1, 2, 3}, {3, 1, 0, 2}} {
+		if !runThresholdAudit(t, kl, proofs, online) {
+			t.Fatalf("CheckAudit failed for verifiers %v", online)
+		}
+	}
+
+	// a proof for the wrong key
+	wrong, _ := kl.NewThresholdProof(keyIdx, kl.ProofPP.ExpField.RandomElement(), 3, 4)
+	if runThresholdAudit(t, kl, wrong, []int{1, 2, 3}) {
+		t.Fatalf("CheckAudit accepted the wrong key")
+	}
+
+	// a partial of the wrong verifier
+	online := []int{0, 1, 3}
+	partials := make([]*ThresholdPartial, len(online))
+	for i, v := range online {
+		p, err := kl.ThresholdPartials(proofs[v], online)
+		if err != nil {
+			t.Fatal(err)
+		}
+		partials[i] = p[0]
+	}
+	partials[1], partials[2] = partials[2], partials[1]
+	if _, err := kl.ThresholdAudit(proofs[0], online, partials); err == nil {
+		t.Fatalf("Audited with a partial of the wrong verifier")
+	}
+
+	if _, err := kl.ThresholdAudit(proofs[3], online, partials); err == nil {
+		t.Fatalf("Audited for a verifier outside the pair")
+	}
+
+	if _, err := kl.ThresholdPartials(proofs[0], []int{0, 1}); err == nil {
+		t.Fatalf("Audited with fewer verifiers than the threshold")
+	}
+
+	if _, err := kl.ThresholdPartials(proofs[1], []int{0, 2, 3}); err == nil {
+		t.Fatalf("Audited for an offline verifier")
+	}
+
+	if _, err := kl.NewThresholdProof(keyIdx, key, 1, 3); err == nil {
+		t.Fatalf("Generated a proof for a threshold of 1")
+	}
+
+	// the exponents of the default group are not in a prime field
+	klDefault, keyDefault, _, keyIdxDefault := GenerateTestingKeyList(
+		TestNumKeys, TestFSSDomain, DefaultGroup(), TestPredicate, TestNumSubkeys)
+	if _, err := klDefault.NewThresholdProof(keyIdxDefault, keyDefault, 2, 3); err == nil {
+		t.Fatalf("Generated a threshold proof over a group of composite order")
+	}
+}
+
+func TestEpochTags(t *testing.T) {
+
+	group := DefaultGroup()
+
+	kl, key, _, keyIdx := GenerateTestingKeyList(
+		TestNumKeys, TestFSSDomain, group, TestPredicate, TestNumSubkeys)
+
+	klB := kl.CloneKeyList()
+	klB.FlipSignOfKeys()
+
+	tags := make(map[string]int)
+	for epoch := uint64(0); epoch < 2; epoch++ {
+		for i := 0; i < 5; i++ {
+			proofShares := kl.NewEpochProof(keyIdx, key, epoch)
+
+			auditA := kl.Audit(proofShares[0])
+			auditB := klB.Audit(proofShares[1])
+
+			if !kl.CheckAudit(auditA, auditB) {
+				t.Fatalf("CheckAudit failed")
+			}
+
+			tags[string(auditA.Tag)]++
+		}
+	}
+
+	if len(tags) != 2 {
+		t.Fatalf("Expected one tag per epoch, got %v distinct tags", len(tags))
+	}
+
+	// a tag for the wrong key must be rejected
+	proofShares := kl.NewEpochProof(keyIdx, key, 0)
+	proofShares[0].Tag = kl.EpochTag(kl.ProofPP.ExpField.RandomElement(), 0)
+	proofShares[1].Tag = proofShares[0].Tag
+
+	auditA := kl.Audit(proofShares[0])
+	auditB := klB.Audit(proofShares[1])
+	if kl.CheckAudit(auditA, auditB) {
+		t.Fatalf("CheckAudit accepted an invalid tag")
+	}
+}
+
+func TestEncoding(t *testing.T) {
+
+	kl, key, _, keyIdx := GenerateTestingKeyList(TestNumKeys, TestFSSDomain, DefaultGroup(), Equality, 0)
+	klB := kl.CloneKeyList()
+	klB.FlipSignOfKeys()
+
+	// the same list with 128-bit indices
+	wide := kl.CloneKeyList()
+	wide.FSSDomain = dpf128.MaxDomain
+	wide.KeyIndices = nil
+	wide.WideKeyIndices = make([]dpf128.Index, wide.NumKeys)
+	for i := range wide.WideKeyIndices {
+		wide.WideKeyIndices[i] = dpf128.Index{Lo: kl.KeyIndices[i]}
+	}
+	wideB := wide.CloneKeyList()
+	wideB.FlipSignOfKeys()
+
+	lists := [][2]*KeyList{{kl, klB}, {kl, klB}, {wide, wideB}}
+	proofs := [][]*ProofShare{
+		kl.NewProof(keyIdx, key),
+		kl.NewEpochProof(keyIdx, key, 7),
+		wide.NewWideProof(dpf128.Index{Lo: keyIdx}, key),
+	}
+
+	for i, proofShares := range proofs {
+		var audits [2]*AuditShare
+		for j, proof := range proofShares {
+			list := lists[i][j]
+			b, err := list.EncodeProof(proof)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			decoded, err := list.DecodeProof(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := list.DecodeProof(b[:len(b)-1]); err == nil {
+				t.Fatalf("Decoded a truncated proof share")
+			}
+
+			if _, err := list.DecodeProof(append(b, 0)); err == nil {
+				t.Fatalf("Decoded a proof share with trailing bytes")
+			}
+
+			audit := list.Audit(decoded)
+			if b, err = list.EncodeAudit(audit); err != nil {
+				t.Fatal(err)
+			}
+
+			if audits[j], err = list.DecodeAudit(b); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := list.DecodeAudit(b[:len(b)-1]); err == nil {
+				t.Fatalf("Decoded a truncated audit share")
+			}
+		}
+
+		if !kl.CheckAudit(audits[0], audits[1]) {
+			t.Fatalf("CheckAudit of decoded shares failed (proof %v)", i)
+		}
+	}
+
+	// the audit share of a malformed proof is encoded as well
+	b, err := kl.EncodeAudit(&AuditShare{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	audit, err := kl.DecodeAudit(b)
+	if err != nil || audit.Share != nil || audit.BitSum {
+		t.Fatalf("Decoding of an empty audit share failed: %v", err)
+	}
+}
+
 func BenchmarkBaseline(b *testing.B) {
 	numKeys := uint64(1000)
 	fssDomain := uint(32)
@@ -91,3 +767,145 @@ func BenchmarkPACLMany(b *testing.B) {
 		kl.CheckAudit(audit, audit)
 	}
 }
+
+// returns the key lists of both verifiers over which the per-operation
+// benchmarks run (see benchsweep.Run)
+func benchKeyLists(numKeys uint64, inclusion bool) (*KeyList, *KeyList, *algebra.FieldElement, uint64) {
+	pred := Equality
+	if inclusion {
+		pred = Inclusion
+	}
+
+	kl, key, _, keyIdx := GenerateTestingKeyList(numKeys, TestFSSDomain, DefaultGroup(), pred, TestNumSubkeys)
+	klB := kl.CloneKeyList()
+	klB.FlipSignOfKeys()
+
+	return kl, klB, key, keyIdx
+}
+
+func BenchmarkNewProof(b *testing.B) {
+	benchsweep.Run(b, func(b *testing.B, numKeys uint64, inclusion bool) {
+		kl, _, key, keyIdx := benchKeyLists(numKeys, inclusion)
+
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			kl.NewProof(keyIdx, key)
+		}
+	})
+}
+
+func BenchmarkAudit(b *testing.B) {
+	benchsweep.Run(b, func(b *testing.B, numKeys uint64, inclusion bool) {
+		kl, _, key, keyIdx := benchKeyLists(numKeys, inclusion)
+		shares := kl.NewProof(keyIdx, key)
+
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			kl.Audit(shares[0])
+		}
+	})
+}
+
+func BenchmarkCheckAudit(b *testing.B) {
+	benchsweep.Run(b, func(b *testing.B, numKeys uint64, inclusion bool) {
+		kl, klB, key, keyIdx := benchKeyLists(numKeys, inclusion)
+		shares := kl.NewProof(keyIdx, key)
+		auditA := kl.Audit(shares[0])
+		auditB := klB.Audit(shares[1])
+
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			if !kl.CheckAudit(auditA, auditB) {
+				b.Fatalf("CheckAudit failed")
+			}
+		}
+	})
+}
+
+// records the operations an audit performs on the keys
+// (and forwards them to the accumulator of audits)
+type recordingAccumulator struct {
+	keyAccumulator
+	ops []string
+}
+
+func (acc *recordingAccumulator) condAdd(key *algebra.FieldElement, bit byte) {
+	acc.ops = append(acc.ops, "add")
+	acc.keyAccumulator.condAdd(key, bit)
+}
+
+// DPF output bits with no, every and random bits set
+func testBitPatterns(n uint64) [][]byte {
+	zeros := make([]byte, n)
+	ones := make([]byte, n)
+	random := make([]byte, n)
+	for i := range ones {
+		ones[i] = 1
+		random[i] = byte(rand.Intn(2))
+	}
+
+	return [][]byte{zeros, ones, random}
+}
+
+// checks that the audits performed the same sequence of operations
+func checkSameOps(t *testing.T, traces [][]string) {
+	for _, ops := range traces[1:] {
+		if len(ops) == 0 || !reflect.DeepEqual(ops, traces[0]) {
+			t.Fatalf("Sequence of audit operations depends on the DPF bits")
+		}
+	}
+}
+
+// replaces the indices by distinct random indices in a domain of 2^fssDomain
+func distinctIndices(indices []uint64, fssDomain uint) {
+	for i, idx := range rand.Perm(1 << fssDomain)[:len(indices)] {
+		indices[i] = uint64(idx)
+	}
+}
+
+func writeKeyFile(t *testing.T, path string, write func(io.Writer) error) {
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := write(file); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := file.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// runs a threshold audit with the online verifiers and checks the
+// audit shares of the pair
+func runThresholdAudit(t *testing.T, kl *KeyList, proofs []*ThresholdProofShare, online []int) bool {
+	sorted := append([]int{}, online...)
+	sort.Ints(sorted)
+
+	// the partials every online verifier sends to the pair
+	partials := [2][]*ThresholdPartial{}
+	for _, v := range sorted {
+		p, err := kl.ThresholdPartials(proofs[v], online)
+		if err != nil {
+			t.Fatal(err)
+		}
+		partials[0] = append(partials[0], p[0])
+		partials[1] = append(partials[1], p[1])
+	}
+
+	audits := make([]*AuditShare, 2)
+	for i := range audits {
+		audit, err := kl.ThresholdAudit(proofs[sorted[i]], online, partials[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		audits[i] = audit
+	}
+
+	return kl.CheckAudit(audits...)
+}
@@ -0,0 +1,51 @@
+package paclsposs
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/sachaservan/pacl/algebra"
+	"github.com/sachaservan/pacl/keystore"
+)
+
+// UseKeyStore replaces the public keys of the list by the records of the
+// store (e.g., a file written by WriteKeyFile), which audits then read in
+// place. The second verifier stores its flipped list (see FlipSignOfKeys).
+// Every key of the store is validated (see KeyList.Validate).
+func (kl *KeyList) UseKeyStore(store *keystore.Store) error {
+	if kl.IsWide() {
+		return errors.New("key stores hold 64-bit key indices")
+	}
+
+	size := kl.Field.ByteLen()
+	if store.KeySize != size {
+		return fmt.Errorf("store has %v-byte keys instead of %v", store.KeySize, size)
+	}
+
+	elem := &algebra.GroupElement{Value: &algebra.FieldElement{Int: new(big.Int)}}
+	for i := uint64(0); i < store.NumKeys; i++ {
+		elem.Value.Int.SetBytes(store.Key(i))
+		if err := kl.validateKey(elem); err != nil {
+			return fmt.Errorf("key %v of the store: %v", i, err)
+		}
+	}
+
+	kl.Store = store
+	kl.PublicKeys = nil
+	kl.NumKeys = store.NumKeys
+	kl.KeyIndices = store.Indices()
+
+	return nil
+}
+
+// returns the i-th public key of the list; keys in a store
+// are decoded into elem (which is returned)
+func (kl *KeyList) keyAt(i uint64, elem *algebra.GroupElement) *algebra.GroupElement {
+	if kl.Store == nil {
+		return kl.PublicKeys[i]
+	}
+
+	elem.Value.Int.SetBytes(kl.Store.Key(i))
+	return elem
+}
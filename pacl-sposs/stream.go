@@ -0,0 +1,210 @@
+package paclsposs
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/sachaservan/pacl/algebra"
+	dpf "github.com/sachaservan/vdpf"
+)
+
+// DefaultChunkSize is the number of keys that StreamAudit reads at a time
+const DefaultChunkSize = 1 << 16
+
+// KeySource provides the entries (key index and public key) of a key list
+// in order, so that audits can stream over lists that are not in memory
+type KeySource interface {
+	// ReadEntries reads the entries [start, start+len(indices)) of the list;
+	// the elements in keys may be reused if they are not nil, and only the
+	// indices are read if keys is nil
+	ReadEntries(start uint64, indices []uint64, keys []*algebra.GroupElement) error
+}
+
+// ReadEntries implements KeySource for a key list that is in memory
+func (kl *KeyList) ReadEntries(start uint64, indices []uint64, keys []*algebra.GroupElement) error {
+	if start+uint64(len(indices)) > kl.NumKeys {
+		return io.ErrUnexpectedEOF
+	}
+
+	copy(indices, kl.KeyIndices[start:])
+	if kl.Store == nil {
+		copy(keys, kl.PublicKeys[start:])
+		return nil
+	}
+
+	for i := range keys {
+		if keys[i] == nil {
+			keys[i] = &algebra.GroupElement{Value: &algebra.FieldElement{Int: new(big.Int)}}
+		}
+		kl.keyAt(start+uint64(i), keys[i])
+	}
+
+	return nil
+}
+
+// StreamAudit is the same as Audit but reads the entries of the list from
+// src chunkSize entries at a time, so that the public keys of the list are
+// never all in memory. Only the parameters of kl are used (its keys and
+// indices may be nil).
+//
+// The VDPF is batch evaluated on the indices of the whole list at once (read
+// in a first pass) so that its proof covers every key: the proofs of separate
+// chunks would each allow the key to select a point in its chunk. The memory
+// of the audit therefore grows with the size of the list, by 9 bytes per key.
+func (kl *KeyList) StreamAudit(proof *ProofShare, src KeySource, chunkSize int) (*AuditShare, error) {
+
+	if err := kl.ValidateProof(proof); err != nil {
+		return nil, err
+	}
+
+	if kl.IsWide() {
+		return nil, errors.New("streaming audits require 64-bit key indices")
+	}
+
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+
+	pf := dpf.ServerVDPFInitialize(proof.PrfKey, [2]dpf.HashKey{kl.HKey1, kl.HKey2})
+	eval := func(indices []uint64) ([]byte, []byte) {
+		return pf.BatchVerEval(proof.DPFKey, indices)
+	}
+
+	return kl.streamAudit(kl.newKeyAccumulator(), proof, src, chunkSize, eval)
+}
+
+// StreamAudit with the keys combined by acc and the (V)DPF evaluated by eval
+func (kl *KeyList) streamAudit(
+	acc keyAccumulator,
+	proof *ProofShare,
+	src KeySource,
+	chunkSize int,
+	eval func(indices []uint64) ([]byte, []byte),
+) (*AuditShare, error) {
+
+	// first pass: the indices of the list
+	indices := make([]uint64, kl.NumKeys)
+	for start := uint64(0); start < kl.NumKeys; start += uint64(chunkSize) {
+		end := start + uint64(chunkSize)
+		if end > kl.NumKeys {
+			end = kl.NumKeys
+		}
+
+		if err := src.ReadEntries(start, indices[start:end], nil); err != nil {
+			return nil, err
+		}
+	}
+
+	bits, pi := eval(indices)
+
+	// second pass: the keys selected by the bits
+	chunk := make([]uint64, chunkSize)
+	keys := make([]*algebra.GroupElement, chunkSize)
+
+	for start := uint64(0); start < kl.NumKeys; start += uint64(chunkSize) {
+		n := kl.NumKeys - start
+		if n > uint64(chunkSize) {
+			n = uint64(chunkSize)
+		}
+
+		// the indices are read again: they must not have changed
+		if err := src.ReadEntries(start, chunk[:n], keys[:n]); err != nil {
+			return nil, err
+		}
+
+		for i := uint64(0); i < n; i++ {
+			if chunk[i] != indices[start+i] {
+				return nil, fmt.Errorf("index of key %v changed during the audit", start+i)
+			}
+
+			// add result to running sum (mod q) with a conditional move
+			acc.condAdd(keys[i].Value, bits[start+i])
+		}
+	}
+
+	accumulator, bitSum := acc.sum()
+	spossAudit := kl.ProofPP.Audit(accumulator, proof.ProofShare)
+	audit := &AuditShare{Share: spossAudit, Pi: pi, KeyShare: accumulator, BitSum: bitSum}
+	if proof.Tag != nil {
+		kl.computeEpochTagShare(proof, audit)
+	}
+
+	return audit, nil
+}
+
+// WriteKeyFile writes the entries of the list as fixed-width records
+// (the 8-byte big-endian key index followed by the canonical encoding of
+// the public key) that can be read back with a FileKeySource
+func (kl *KeyList) WriteKeyFile(w io.Writer) error {
+
+	record := make([]byte, 8, 8+kl.Field.ByteLen())
+
+	elem := &algebra.GroupElement{Value: &algebra.FieldElement{Int: new(big.Int)}}
+	for i := uint64(0); i < kl.NumKeys; i++ {
+		key := kl.keyAt(i, elem).Value
+
+		var err error
+		binary.BigEndian.PutUint64(record, kl.KeyIndices[i])
+		record, err = kl.Field.AppendEncoding(record[:8], key)
+		if err != nil || key.Int.Sign() == 0 {
+			return errors.New("public key is not an element of the group")
+		}
+
+		if _, err := w.Write(record); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// FileKeySource reads the entries of a key list from the fixed-width
+// records written by WriteKeyFile (e.g., from an *os.File)
+type FileKeySource struct {
+	r     io.ReaderAt
+	field *algebra.Field
+	buf   []byte
+}
+
+// NewFileKeySource returns a source that reads elements of the field from r
+func NewFileKeySource(r io.ReaderAt, field *algebra.Field) *FileKeySource {
+	return &FileKeySource{r: r, field: field}
+}
+
+// ReadEntries implements KeySource
+func (src *FileKeySource) ReadEntries(start uint64, indices []uint64, keys []*algebra.GroupElement) error {
+
+	recordSize := 8 + src.field.ByteLen()
+	size := recordSize * len(indices)
+	if len(src.buf) < size {
+		src.buf = make([]byte, size)
+	}
+
+	// ReadAt may return io.EOF along with the last records
+	n, err := src.r.ReadAt(src.buf[:size], int64(start)*int64(recordSize))
+	if n < size {
+		return err
+	}
+
+	for i := range indices {
+		record := src.buf[i*recordSize : (i+1)*recordSize]
+		indices[i] = binary.BigEndian.Uint64(record)
+
+		if keys == nil {
+			continue
+		}
+
+		if keys[i] == nil {
+			keys[i] = &algebra.GroupElement{Value: &algebra.FieldElement{Int: new(big.Int)}}
+		}
+
+		if err := src.field.DecodeInto(keys[i].Value, record[8:]); err != nil {
+			return fmt.Errorf("record %v: %v", start+uint64(i), err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,294 @@
+package paclsposs
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/sachaservan/pacl/algebra"
+	dpf "github.com/sachaservan/vdpf"
+)
+
+// Threshold mode: the proof is split among n verifiers (numbered 0, ..., n-1)
+// so that any t >= 2 of them can audit it while the others are offline, as in
+// the threshold mode of pacl-pk (see the privacy guarantees there, which hold
+// here as well). The differences are:
+//
+// The SPoSS proof of a pair is over the additive shares xA and xB of x of the
+// pair's two verifiers (see sposs.GenThresholdProof). The verifiers do not
+// get xA and xB: both are Shamir-shared among all n verifiers with threshold
+// t in the exponent field, which must be of prime order (see ParamSet). Every
+// online verifier sends g^(λ[xA]) to the lower verifier of the pair and
+// g^(λ[xB]) to the higher one (see ThresholdPartials), and the two verifiers
+// of the pair audit with g^xA and g^xB. A colluding pair learns g^x (i.e., the
+// public key of the prover) but not x.
+//
+// Epoch tags are not supported in threshold mode.
+
+// ThresholdProofShare is the share of a threshold proof of one verifier
+type ThresholdProofShare struct {
+	Verifier     int // number of the verifier (0, ..., n-1)
+	NumVerifiers int
+	Threshold    int
+
+	// the VDPF keys and SPoSS proof shares (without shares of x) of every
+	// pair the verifier belongs to (nil for other pairs)
+	Pairs []*ProofShare
+
+	// the Shamir shares of xA and xB of every pair
+	SharesA []*algebra.ShamirShare
+	SharesB []*algebra.ShamirShare
+}
+
+// ThresholdPartial is the part of g^xA (or g^xB) an online verifier sends to
+// the lower (or higher) verifier of the auditing pair
+type ThresholdPartial struct {
+	Verifier int
+	Online   []int // the verifiers taking part in the audit
+	Value    *algebra.FieldElement
+}
+
+// Size returns the number of bytes the prover sends to one verifier
+func (share *ThresholdProofShare) Size() int {
+	size := 0
+	for _, pair := range share.Pairs {
+		if pair != nil {
+			size += pair.Size()
+		}
+	}
+
+	for i := range share.SharesA {
+		size += share.SharesA[i].Y.Size() + share.SharesB[i].Y.Size()
+	}
+
+	return size
+}
+
+// number of verifiers that can be in the auditing pair
+func numPairVerifiers(t, n int) int {
+	return n - t + 2
+}
+
+// number of the pair of verifiers i < j of m in lexicographic order
+func pairNumber(i, j, m int) int {
+	return i*m - i*(i+1)/2 + (j - i - 1)
+}
+
+// NewThresholdProof returns the proof shares of the n verifiers of which any t can audit
+func (kl *KeyListParams) NewThresholdProof(idx uint64, x *algebra.FieldElement, t, n int) ([]*ThresholdProofShare, error) {
+
+	if kl.NumKeys == 0 {
+		panic("list size is set to zero; something is wrong")
+	}
+
+	if kl.IsWide() {
+		return nil, errors.New("threshold proofs require 64-bit key indices")
+	}
+
+	if t < 2 || t > n {
+		return nil, fmt.Errorf("cannot audit with %v of %v verifiers", t, n)
+	}
+
+	expField := kl.ProofPP.ExpField
+	if !expField.P.ProbablyPrime(20) {
+		return nil, errors.New("threshold proofs require a group of prime order")
+	}
+
+	m := numPairVerifiers(t, n)
+	numPairs := m * (m - 1) / 2
+	shares := make([]*ThresholdProofShare, n)
+	for v := range shares {
+		shares[v] = &ThresholdProofShare{
+			Verifier:     v,
+			NumVerifiers: n,
+			Threshold:    t,
+			Pairs:        make([]*ProofShare, numPairs),
+			SharesA:      make([]*algebra.ShamirShare, numPairs),
+			SharesB:      make([]*algebra.ShamirShare, numPairs),
+		}
+	}
+
+	for i := 0; i < m; i++ {
+		for j := i + 1; j < m; j++ {
+			pair := pairNumber(i, j, m)
+
+			prfKey := dpf.GeneratePRFKey()
+			pf := dpf.ClientVDPFInitialize(prfKey, [2]dpf.HashKey{kl.HKey1, kl.HKey2})
+			keyA, keyB := pf.GenVDPFKeys(idx, kl.FSSDomain)
+
+			// the proof is of -g^x when the key is "retrieved" from the
+			// second verifier of the pair (see NewProof)
+			resB := pf.BatchEval(keyB, []uint64{idx})
+
+			xA, xB := kl.ProofPP.ExpLinearShares(expField.NewElement(x.Int))
+			spossProofA, spossProofB := kl.ProofPP.GenThresholdProof(xA, xB, resB[0] == 1)
+
+			sharesA, err := expField.ShamirShares(xA, t, n)
+			if err != nil {
+				return nil, err
+			}
+
+			sharesB, err := expField.ShamirShares(xB, t, n)
+			if err != nil {
+				return nil, err
+			}
+
+			shares[i].Pairs[pair] = &ProofShare{DPFKey: keyA, PrfKey: pf.PrfKey, ShareNumber: 0, ProofShare: spossProofA}
+			shares[j].Pairs[pair] = &ProofShare{DPFKey: keyB, PrfKey: pf.PrfKey, ShareNumber: 1, ProofShare: spossProofB}
+			for v := range shares {
+				shares[v].SharesA[pair], shares[v].SharesB[pair] = sharesA[v], sharesB[v]
+			}
+		}
+	}
+
+	return shares, nil
+}
+
+// ThresholdPartials returns the partials the verifier sends to the lower and
+// the higher verifier of the auditing pair (in this order) when the online
+// verifiers audit the proof; the verifiers of the pair keep their own
+func (kl *KeyList) ThresholdPartials(proof *ThresholdProofShare, online []int) ([]*ThresholdPartial, error) {
+
+	online, pos, err := checkOnline(proof, online)
+	if err != nil {
+		return nil, err
+	}
+
+	m := numPairVerifiers(proof.Threshold, proof.NumVerifiers)
+	pair := pairNumber(online[0], online[1], m)
+	if len(proof.SharesA) != m*(m-1)/2 || len(proof.SharesB) != len(proof.SharesA) ||
+		proof.SharesA[pair] == nil || proof.SharesA[pair].Y == nil ||
+		proof.SharesB[pair] == nil || proof.SharesB[pair].Y == nil {
+		return nil, errors.New("missing shares of x")
+	}
+
+	// the Lagrange coefficient of the verifier (at 0)
+	field := kl.ProofPP.ExpField
+	xs := make([]*algebra.FieldElement, len(online))
+	for i, v := range online {
+		xs[i] = field.NewElement(big.NewInt(int64(v + 1)))
+	}
+
+	lagrange, err := field.LagrangeCoefficients(xs, field.AddIdentity())
+	if err != nil {
+		return nil, err
+	}
+
+	partials := make([]*ThresholdPartial, 2)
+	for i, share := range []*algebra.ShamirShare{proof.SharesA[pair], proof.SharesB[pair]} {
+		exp := field.Mul(lagrange[pos], field.NewElement(share.Y.Int))
+		partials[i] = &ThresholdPartial{
+			Verifier: proof.Verifier,
+			Online:   online,
+			Value:    kl.Group.NewElement(exp.Int).Value,
+		}
+	}
+
+	return partials, nil
+}
+
+// ThresholdAudit returns the audit share of a verifier of the auditing pair
+// given the partials it got from every online verifier (in increasing order
+// of the verifiers, including its own); the audit shares of the pair are
+// checked with CheckAudit (lower verifier first). The key list must not be
+// flipped (the verifier negates the selected key itself when it is the
+// higher verifier of the pair).
+func (kl *KeyList) ThresholdAudit(proof *ThresholdProofShare, online []int, partials []*ThresholdPartial) (*AuditShare, error) {
+
+	online, pos, err := checkOnline(proof, online)
+	if err != nil {
+		return nil, err
+	}
+
+	if pos > 1 {
+		return nil, fmt.Errorf("verifier %v is not in the auditing pair", proof.Verifier)
+	}
+
+	m := numPairVerifiers(proof.Threshold, proof.NumVerifiers)
+	pair := pairNumber(online[0], online[1], m)
+	if len(proof.Pairs) != m*(m-1)/2 || proof.Pairs[pair] == nil || proof.Pairs[pair].ProofShare == nil {
+		return nil, errors.New("missing proof share of the pair")
+	}
+
+	if proof.Pairs[pair].ShareNumber != uint(pos) {
+		return nil, fmt.Errorf("proof share of the pair has share number %v", proof.Pairs[pair].ShareNumber)
+	}
+
+	if len(partials) != len(online) {
+		return nil, fmt.Errorf("%v partials for %v online verifiers", len(partials), len(online))
+	}
+
+	// g^xA (or g^xB) in the exponent from the Shamir shares
+	gx := kl.Group.Identity()
+	for i, partial := range partials {
+		if partial == nil || partial.Value == nil || partial.Verifier != online[i] || !sameVerifiers(partial.Online, online) {
+			return nil, fmt.Errorf("invalid partial of verifier %v", online[i])
+		}
+		gx = kl.Group.Mul(gx, &algebra.GroupElement{Value: partial.Value})
+	}
+
+	spossProof := *proof.Pairs[pair].ProofShare
+	spossProof.GX = gx.Value
+	pairProof := *proof.Pairs[pair]
+	pairProof.ProofShare = &spossProof
+
+	if spossProof.ShareX != nil || kl.ValidateProof(&pairProof) != nil || pairProof.Tag != nil {
+		return &AuditShare{}, nil
+	}
+
+	bits, pi := kl.ExpandVDPF(&pairProof)
+	return kl.computeSignedAudit(kl.newKeyAccumulator(), &pairProof, bits, pi, pos == 1), nil
+}
+
+// returns the sorted online verifiers and the position of the verifier
+// among them after checking that they are distinct verifiers of the proof,
+// that there are at least t of them and that they include the verifier
+func checkOnline(proof *ThresholdProofShare, online []int) ([]int, int, error) {
+
+	if proof == nil {
+		return nil, 0, errors.New("missing proof share")
+	}
+
+	if proof.Threshold < 2 || proof.Threshold > proof.NumVerifiers {
+		return nil, 0, fmt.Errorf("invalid threshold %v of %v verifiers", proof.Threshold, proof.NumVerifiers)
+	}
+
+	if len(online) < proof.Threshold {
+		return nil, 0, fmt.Errorf("%v verifiers online for a threshold of %v", len(online), proof.Threshold)
+	}
+
+	sorted := append([]int{}, online...)
+	sort.Ints(sorted)
+
+	pos := -1
+	for i, v := range sorted {
+		if v < 0 || v >= proof.NumVerifiers || (i > 0 && v == sorted[i-1]) {
+			return nil, 0, fmt.Errorf("invalid online verifiers %v", online)
+		}
+
+		if v == proof.Verifier {
+			pos = i
+		}
+	}
+
+	if pos < 0 {
+		return nil, 0, fmt.Errorf("verifier %v is not online", proof.Verifier)
+	}
+
+	return sorted, pos, nil
+}
+
+func sameVerifiers(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
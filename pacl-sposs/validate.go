@@ -0,0 +1,103 @@
+package paclsposs
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/sachaservan/pacl/algebra"
+)
+
+// Validate returns an error unless the group of the list is its parameter set
+// (if named), every key of the list has an index and every public key is an
+// element of the group (up to its sign) that does not have small order
+// (e.g., for a list received from a client or read from a file)
+func (kl *KeyList) Validate() error {
+
+	if err := kl.checkParamSet(); err != nil {
+		return err
+	}
+
+	numIndices := len(kl.KeyIndices)
+	if kl.IsWide() {
+		numIndices = len(kl.WideKeyIndices)
+	}
+
+	if uint64(numIndices) != kl.NumKeys {
+		return fmt.Errorf("list has %v indices for %v keys", numIndices, kl.NumKeys)
+	}
+
+	if kl.Store == nil && uint64(len(kl.PublicKeys)) != kl.NumKeys {
+		return fmt.Errorf("list has %v public keys instead of %v", len(kl.PublicKeys), kl.NumKeys)
+	}
+
+	elem := &algebra.GroupElement{Value: &algebra.FieldElement{Int: new(big.Int)}}
+	for i := uint64(0); i < kl.NumKeys; i++ {
+		if err := kl.validateKey(kl.keyAt(i, elem)); err != nil {
+			return fmt.Errorf("public key %v: %v", i, err)
+		}
+	}
+
+	return nil
+}
+
+// validates a public key up to its sign: the second verifier holds the
+// negated keys (see FlipSignOfKeys), which are not in groups of prime order
+func (kl *KeyListParams) validateKey(key *algebra.GroupElement) error {
+
+	err := kl.Group.Validate(key)
+	if err == nil || kl.Field.Validate(key.Value) != nil {
+		return err
+	}
+
+	if kl.Group.Validate(&algebra.GroupElement{Value: kl.Field.Negate(key.Value)}) == nil {
+		return nil
+	}
+
+	return err
+}
+
+// ValidateProof returns an error unless the values of the proof share are
+// elements of their fields and its tag (in epoch mode) is a group element
+// that does not have small order; Audit rejects proofs that are not valid
+func (kl *KeyList) ValidateProof(proof *ProofShare) error {
+
+	if proof == nil || (proof.DPFKey == nil && proof.WideDPFKey == nil) {
+		return errors.New("missing DPF key")
+	}
+
+	if kl.IsWide() != (proof.WideDPFKey != nil) {
+		return errors.New("DPF key does not match the index size of the list")
+	}
+
+	if proof.WideDPFKey != nil {
+		if err := proof.WideDPFKey.Validate(); err != nil {
+			return err
+		}
+
+		if proof.WideDPFKey.Domain != kl.FSSDomain {
+			return fmt.Errorf("DPF key has a domain of %v bits instead of %v", proof.WideDPFKey.Domain, kl.FSSDomain)
+		}
+	}
+
+	if proof.ShareNumber > 1 {
+		return fmt.Errorf("invalid share number %v", proof.ShareNumber)
+	}
+
+	if err := kl.ProofPP.ValidateProofShare(proof.ProofShare); err != nil {
+		return err
+	}
+
+	if proof.Tag != nil {
+		tag, err := kl.Group.Decode(proof.Tag)
+		if err == nil {
+			err = kl.Group.Validate(tag)
+		}
+
+		if err != nil {
+			return fmt.Errorf("tag: %v", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package pir
+
+import (
+	"errors"
+
+	"github.com/sachaservan/pacl/dpf128"
+	paclsk "github.com/sachaservan/pacl/pacl-sk"
+	"github.com/sachaservan/pacl/slot"
+	dpf "github.com/sachaservan/vdpf"
+)
+
+// Client retrieves items from a database replicated on two servers
+type Client struct {
+	NumItems uint64
+	params   *paclsk.KeyListParams
+}
+
+func NewClient(numItems uint64) *Client {
+	params := &paclsk.KeyListParams{}
+	params.NumKeys = numItems
+	params.FSSDomain = domainSize(numItems)
+	params.PredicateType = paclsk.Equality
+
+	return &Client{NumItems: numItems, params: params}
+}
+
+// Query returns the queries for both servers (in order) retrieving item i.
+// If key is not nil, the queries carry a PACL proof that the client knows
+// the key of item i; the same DPF keys serve as both the PIR query and the PACL.
+func (c *Client) Query(i uint64, key *slot.Slot) ([]*Query, error) {
+
+	if i >= c.NumItems {
+		return nil, errors.New("item does not exist")
+	}
+
+	prfKey := dpf.GeneratePRFKey()
+	pf := dpf.ClientDPFInitialize(prfKey)
+	keyA, keyB := pf.GenDPFKeys(i, c.params.FSSDomain)
+
+	queries := []*Query{
+		{ShareNumber: 0, DPFKey: keyA, PrfKey: pf.PrfKey},
+		{ShareNumber: 1, DPFKey: keyB, PrfKey: pf.PrfKey},
+	}
+
+	if key != nil {
+		shares := c.params.NewProofWithDPFKeys(pf.PrfKey, keyA, keyB, key)
+		queries[0].KeyShare = shares[0].KeyShare
+		queries[1].KeyShare = shares[1].KeyShare
+	}
+
+	return queries, nil
+}
+
+// QueryKeyword returns the queries for both servers (in order) retrieving
+// the item with the given keyword from a keyword database. If key is not nil,
+// the queries carry a PACL proof that the client knows the key of the keyword.
+// If no item has the keyword, the reconstructed item is all zeros.
+func QueryKeyword(keyword string, key *slot.Slot) []*Query {
+
+	keyA, keyB := dpf128.GenKeys(KeywordIndex(keyword), dpf128.MaxDomain)
+
+	queries := []*Query{
+		{ShareNumber: 0, WideDPFKey: keyA},
+		{ShareNumber: 1, WideDPFKey: keyB},
+	}
+
+	if key != nil {
+		params := &paclsk.KeyListParams{FSSDomain: dpf128.MaxDomain}
+		shares := params.NewWideProofWithDPFKeys(keyA, keyB, key)
+		queries[0].KeyShare = shares[0].KeyShare
+		queries[1].KeyShare = shares[1].KeyShare
+	}
+
+	return queries
+}
+
+// Reconstruct recovers the item from the answers of both servers
+func Reconstruct(answers ...*slot.Slot) *slot.Slot {
+	item := slot.NewEmpty(len(answers[0].Data))
+	for _, answer := range answers {
+		slot.Xor(item, answer)
+	}
+
+	return item
+}
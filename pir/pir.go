@@ -0,0 +1,219 @@
+package pir
+
+import (
+	"errors"
+	"math"
+
+	"github.com/sachaservan/pacl/dpf128"
+	paclsk "github.com/sachaservan/pacl/pacl-sk"
+	"github.com/sachaservan/pacl/slot"
+	dpf "github.com/sachaservan/vdpf"
+)
+
+// Database is a list of equally-sized items replicated on both servers.
+// If KeyList is set, retrieving item i requires knowledge of the i-th key.
+type Database struct {
+	Slots     []*slot.Slot
+	ItemSize  int
+	FSSDomain uint
+	KeyList   *paclsk.KeyList // (optional) secret-key PACL with one key per item
+	Keywords  []dpf128.Index  // hashed keyword of each item (keyword mode only)
+}
+
+// Query is sent by the client to one of the servers
+type Query struct {
+	ShareNumber uint
+	DPFKey      *dpf.DPFKey // DPF key (also used for the PACL proof)
+	PrfKey      dpf.PrfKey  // PRF key used for the PRG in DPF construction
+	WideDPFKey  *dpf128.Key // DPF key over hashed keywords (keyword mode only)
+	KeyShare    *slot.Slot
+}
+
+// PendingAnswer is the answer to a query that is only released once the
+// audit shares of both servers check out (when the database has a PACL)
+type PendingAnswer struct {
+	Answer *slot.Slot
+	Audit  *paclsk.AuditShare // this server's audit share (sent to the other server)
+}
+
+// Server answers queries over its copy of the database
+type Server struct {
+	ServerNumber int
+	DB           *Database
+}
+
+// NewDatabase returns a database over items; items shorter than
+// itemSize are padded with zeros
+func NewDatabase(items [][]byte, itemSize int) (*Database, error) {
+
+	db := &Database{
+		Slots:     make([]*slot.Slot, len(items)),
+		ItemSize:  itemSize,
+		FSSDomain: domainSize(uint64(len(items))),
+	}
+
+	for i, item := range items {
+		if len(item) > itemSize {
+			return nil, errors.New("item is larger than the item size")
+		}
+
+		db.Slots[i] = slot.NewEmpty(itemSize)
+		copy(db.Slots[i].Data, item)
+	}
+
+	return db, nil
+}
+
+// NewKeywordDatabase returns a database where the i-th item is
+// retrieved by its keyword (keywords[i]) rather than by its index
+func NewKeywordDatabase(keywords []string, items [][]byte, itemSize int) (*Database, error) {
+
+	if len(keywords) != len(items) {
+		return nil, errors.New("need exactly one keyword per item")
+	}
+
+	db, err := NewDatabase(items, itemSize)
+	if err != nil {
+		return nil, err
+	}
+
+	// keywords are hashed to 128 bits so that finding
+	// two keywords that map to the same index is infeasible
+	db.FSSDomain = dpf128.MaxDomain
+	db.Keywords = make([]dpf128.Index, len(keywords))
+
+	seen := make(map[dpf128.Index]bool)
+	for i, keyword := range keywords {
+		db.Keywords[i] = KeywordIndex(keyword)
+		if seen[db.Keywords[i]] {
+			return nil, errors.New("duplicate keyword")
+		}
+
+		seen[db.Keywords[i]] = true
+	}
+
+	return db, nil
+}
+
+// KeywordIndex returns the DPF index of the keyword
+func KeywordIndex(keyword string) dpf128.Index {
+	return dpf128.HashToIndex([]byte(keyword))
+}
+
+// SetAccessKeys protects every item of the database with its own key
+func (db *Database) SetAccessKeys(keys []*slot.Slot) error {
+
+	if len(keys) != len(db.Slots) {
+		return errors.New("need exactly one key per item")
+	}
+
+	kl := &paclsk.KeyList{}
+	kl.NumKeys = uint64(len(keys))
+	kl.FSSDomain = db.FSSDomain
+	kl.PredicateType = paclsk.Equality
+	kl.StatSecurity = 128
+	kl.FullDomain = !kl.IsWide() && (1<<kl.FSSDomain == kl.NumKeys) // only applies when domain = #keys
+	kl.Keys = make([]*slot.Slot, len(keys))
+	kl.KeyIndices = make([]uint64, len(keys))
+	kl.WideKeyIndices = db.Keywords
+
+	for i, key := range keys {
+		if len(key.Data) != kl.StatSecurity/8 {
+			return errors.New("access key has the wrong size")
+		}
+
+		kl.KeyIndices[i] = uint64(i)
+		kl.Keys[i] = key
+	}
+
+	db.KeyList = kl
+	return nil
+}
+
+func NewServer(serverNumber int, db *Database) *Server {
+	return &Server{ServerNumber: serverNumber, DB: db}
+}
+
+// Process computes the answer to the query along with the server's audit share.
+// The DPF is expanded over the full domain once and the same expansion is used
+// both to compute the answer and to audit the PACL.
+func (s *Server) Process(q *Query) (*PendingAnswer, error) {
+
+	if s.DB.KeyList != nil && q.KeyShare == nil {
+		return nil, errors.New("query is missing a PACL proof")
+	}
+
+	bits, err := s.expand(q)
+	if err != nil {
+		return nil, err
+	}
+
+	answer := slot.NewEmpty(s.DB.ItemSize)
+	for i := 0; i < len(s.DB.Slots); i++ {
+		if bits[i]%2 == 1 {
+			slot.Xor(answer, s.DB.Slots[i])
+		}
+	}
+
+	pa := &PendingAnswer{Answer: answer}
+	if s.DB.KeyList != nil {
+		proof := &paclsk.ProofShare{
+			DPFKey:      q.DPFKey,
+			PrfKey:      q.PrfKey,
+			WideDPFKey:  q.WideDPFKey,
+			ShareNumber: q.ShareNumber,
+			KeyShare:    q.KeyShare,
+		}
+
+		pa.Audit = s.DB.KeyList.AuditExpanded(proof, bits[:len(s.DB.Slots)])
+	}
+
+	return pa, nil
+}
+
+// expands the DPF key of the query on the index (or keyword) of every item
+func (s *Server) expand(q *Query) ([]byte, error) {
+
+	if s.DB.Keywords != nil {
+		if q.WideDPFKey == nil || q.WideDPFKey.Domain != s.DB.FSSDomain {
+			return nil, errors.New("query is not a keyword query")
+		}
+
+		return dpf128.BatchEval(q.WideDPFKey, s.DB.Keywords)
+	}
+
+	if q.DPFKey == nil {
+		return nil, errors.New("query is not an index query")
+	}
+
+	pf := dpf.ServerDPFInitialize(q.PrfKey)
+	bits := pf.FullDomainEval(q.DPFKey)
+
+	if uint64(len(bits)) < uint64(len(s.DB.Slots)) {
+		return nil, errors.New("query has the wrong domain size")
+	}
+
+	return bits, nil
+}
+
+// Release returns the answer if the audit shares of both servers check out
+// (other is ignored if the database does not have a PACL)
+func (s *Server) Release(pa *PendingAnswer, other *paclsk.AuditShare) (*slot.Slot, error) {
+
+	if s.DB.KeyList != nil {
+		if other == nil || !s.DB.KeyList.CheckAudit(pa.Audit, other) {
+			return nil, errors.New("PACL audit failed")
+		}
+	}
+
+	return pa.Answer, nil
+}
+
+// number of DPF input bits required to address every item
+func domainSize(numItems uint64) uint {
+	if numItems <= 2 {
+		return 1
+	}
+
+	return uint(math.Ceil(math.Log2(float64(numItems))))
+}
@@ -0,0 +1,164 @@
+package pir
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/sachaservan/pacl/slot"
+)
+
+// test configuration parameters
+const TestNumItems = 100
+const TestItemSize = 64
+
+func setupServers(t *testing.T, withPACL bool) ([]*Server, [][]byte, []*slot.Slot) {
+
+	items := make([][]byte, TestNumItems)
+	for i := range items {
+		items[i] = []byte(fmt.Sprintf("item number %v", i))
+	}
+
+	db, err := NewDatabase(items, TestItemSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var keys []*slot.Slot
+	if withPACL {
+		keys = make([]*slot.Slot, TestNumItems)
+		for i := range keys {
+			keys[i] = slot.NewRandom(16)
+		}
+
+		if err := db.SetAccessKeys(keys); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return []*Server{NewServer(0, db), NewServer(1, db)}, items, keys
+}
+
+// runs the two-server PIR protocol and returns the reconstructed item
+func retrieve(servers []*Server, queries []*Query) (*slot.Slot, error) {
+
+	pendingA, err := servers[0].Process(queries[0])
+	if err != nil {
+		return nil, err
+	}
+
+	pendingB, err := servers[1].Process(queries[1])
+	if err != nil {
+		return nil, err
+	}
+
+	// the servers exchange audit shares
+	answerA, err := servers[0].Release(pendingA, pendingB.Audit)
+	if err != nil {
+		return nil, err
+	}
+
+	answerB, err := servers[1].Release(pendingB, pendingA.Audit)
+	if err != nil {
+		return nil, err
+	}
+
+	return Reconstruct(answerA, answerB), nil
+}
+
+func TestPIR(t *testing.T) {
+
+	servers, items, _ := setupServers(t, false)
+	client := NewClient(TestNumItems)
+
+	for _, i := range []uint64{0, 1, 42, TestNumItems - 1} {
+		queries, err := client.Query(i, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		item, err := retrieve(servers, queries)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(bytes.TrimRight(item.Data, "\x00"), items[i]) {
+			t.Fatalf("Retrieved the wrong item. expected: %v, got: %v", items[i], item.Data)
+		}
+	}
+}
+
+func TestPIRWithPACL(t *testing.T) {
+
+	servers, items, keys := setupServers(t, true)
+	client := NewClient(TestNumItems)
+
+	for _, i := range []uint64{0, 7, TestNumItems - 1} {
+		queries, _ := client.Query(i, keys[i])
+		item, err := retrieve(servers, queries)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(bytes.TrimRight(item.Data, "\x00"), items[i]) {
+			t.Fatalf("Retrieved the wrong item. expected: %v, got: %v", items[i], item.Data)
+		}
+	}
+
+	// the key of another item does not grant access
+	queries, _ := client.Query(3, keys[4])
+	if _, err := retrieve(servers, queries); err == nil {
+		t.Fatalf("Retrieved an item without knowing its key")
+	}
+
+	// queries without a PACL proof are rejected
+	queries, _ = client.Query(3, nil)
+	if _, err := retrieve(servers, queries); err == nil {
+		t.Fatalf("Retrieved an item without a PACL proof")
+	}
+}
+
+func TestKeywordPIRWithPACL(t *testing.T) {
+
+	keywords := make([]string, TestNumItems)
+	items := make([][]byte, TestNumItems)
+	keys := make([]*slot.Slot, TestNumItems)
+	for i := range items {
+		keywords[i] = fmt.Sprintf("user%v@example.com", i)
+		items[i] = []byte(fmt.Sprintf("item number %v", i))
+		keys[i] = slot.NewRandom(16)
+	}
+
+	db, err := NewKeywordDatabase(keywords, items, TestItemSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.SetAccessKeys(keys); err != nil {
+		t.Fatal(err)
+	}
+
+	servers := []*Server{NewServer(0, db), NewServer(1, db)}
+
+	for _, i := range []int{0, 7, TestNumItems - 1} {
+		item, err := retrieve(servers, QueryKeyword(keywords[i], keys[i]))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(bytes.TrimRight(item.Data, "\x00"), items[i]) {
+			t.Fatalf("Retrieved the wrong item. expected: %v, got: %v", items[i], item.Data)
+		}
+	}
+
+	// the key of another keyword does not grant access
+	if _, err := retrieve(servers, QueryKeyword(keywords[3], keys[4])); err == nil {
+		t.Fatalf("Retrieved an item without knowing its key")
+	}
+
+	// index queries are rejected by keyword databases
+	queries, _ := NewClient(TestNumItems).Query(3, keys[3])
+	if _, err := retrieve(servers, queries); err == nil {
+		t.Fatalf("Retrieved an item from a keyword database with an index query")
+	}
+}
@@ -0,0 +1,185 @@
+// Package slot implements slots: byte strings of a fixed size that are
+// xor'ed together, e.g., symmetric keys in pacl-sk, PIR records and
+// mailbox contents.
+package slot
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Slot is a set of bytes which can be xor'ed and compared
+type Slot struct {
+	Data []byte
+}
+
+// New returns a slot populated with data (which is not copied)
+func New(b []byte) *Slot {
+	return &Slot{
+		Data: b,
+	}
+}
+
+// NewFromString converts a string to a slot of slotSize bytes
+// (padded with zeros)
+func NewFromString(s string, slotSize int) *Slot {
+	b := []byte(s)
+	for i := 0; i < (slotSize - len(s)); i++ {
+		b = append(b, 0)
+	}
+	return &Slot{
+		Data: b,
+	}
+}
+
+// NewEmpty returns an all-zero slot
+func NewEmpty(numBytes int) *Slot {
+	return &Slot{
+		Data: make([]byte, numBytes),
+	}
+}
+
+// NewRandom returns a slot filled with random bytes
+func NewRandom(numBytes int) *Slot {
+	s := NewEmpty(numBytes)
+	if err := s.Fill(rand.Reader); err != nil {
+		panic(fmt.Sprintf("Generating random bytes failed with %v\n", err))
+	}
+
+	return s
+}
+
+// Fill overwrites the slot with bytes read from r
+// (e.g., crypto/rand.Reader or a seeded PRG)
+func (s *Slot) Fill(r io.Reader) error {
+	_, err := io.ReadFull(r, s.Data)
+	return err
+}
+
+// Copy returns a slot with a copy of the data
+func (s *Slot) Copy() *Slot {
+	return New(append([]byte{}, s.Data...))
+}
+
+// Size returns the number of bytes in the slot
+func (s *Slot) Size() int {
+	if s == nil {
+		return 0
+	}
+
+	return len(s.Data)
+}
+
+// IsZero returns true if every byte of the slot is zero
+// (in time that only depends on the size of the slot)
+func (s *Slot) IsZero() bool {
+	acc := byte(0)
+	for _, b := range s.Data {
+		acc |= b
+	}
+
+	return subtle.ConstantTimeByteEq(acc, 0) == 1
+}
+
+// Equal returns true if slot == other; the time taken only depends
+// on the size of the slots (and not on where they differ)
+func (s *Slot) Equal(other *Slot) bool {
+
+	if s == nil || other == nil {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare(s.Data, other.Data) == 1
+}
+
+// Compare returns the (lexicographic) comparison of the two slots:
+// 0 if slot == other, -1 if slot < other and 1 if slot > other
+func (s *Slot) Compare(other *Slot) int {
+	return bytes.Compare(s.Data, other.Data)
+}
+
+// MarshalBinary returns the bytes of the slot
+func (s *Slot) MarshalBinary() ([]byte, error) {
+	return append([]byte{}, s.Data...), nil
+}
+
+// UnmarshalBinary sets the slot to a copy of data
+func (s *Slot) UnmarshalBinary(data []byte) error {
+	s.Data = append([]byte{}, data...)
+	return nil
+}
+
+// Xor computes the xor of a and b storing the result in a
+// (only the bytes that both slots have are xor'ed)
+func Xor(a, b *Slot) {
+	n := len(a.Data)
+	if len(b.Data) < n {
+		n = len(b.Data)
+	}
+
+	xorBytes(a.Data[:n], b.Data[:n])
+}
+
+// XorMasked xors b into a if bit is 1 and leaves a unchanged if bit is 0;
+// the same memory is read and written either way, so the time taken
+// does not depend on bit (only the least significant bit is used)
+func XorMasked(a, b *Slot, bit byte) {
+	n := len(a.Data)
+	if len(b.Data) < n {
+		n = len(b.Data)
+	}
+
+	xorBytesMasked(a.Data[:n], b.Data[:n], -uint64(bit&1))
+}
+
+// xors src & mask into dst (of the same length) one 64-bit word at a time
+func xorBytesMasked(dst, src []byte, mask uint64) {
+	n := len(dst)
+	i := 0
+
+	// four words per iteration
+	for ; i+32 <= n; i += 32 {
+		d := dst[i : i+32 : i+32]
+		s := src[i : i+32 : i+32]
+		binary.LittleEndian.PutUint64(d[0:], binary.LittleEndian.Uint64(d[0:])^(binary.LittleEndian.Uint64(s[0:])&mask))
+		binary.LittleEndian.PutUint64(d[8:], binary.LittleEndian.Uint64(d[8:])^(binary.LittleEndian.Uint64(s[8:])&mask))
+		binary.LittleEndian.PutUint64(d[16:], binary.LittleEndian.Uint64(d[16:])^(binary.LittleEndian.Uint64(s[16:])&mask))
+		binary.LittleEndian.PutUint64(d[24:], binary.LittleEndian.Uint64(d[24:])^(binary.LittleEndian.Uint64(s[24:])&mask))
+	}
+
+	for ; i+8 <= n; i += 8 {
+		binary.LittleEndian.PutUint64(dst[i:], binary.LittleEndian.Uint64(dst[i:])^(binary.LittleEndian.Uint64(src[i:])&mask))
+	}
+
+	for ; i < n; i++ {
+		dst[i] ^= src[i] & byte(mask)
+	}
+}
+
+// xors src into dst (of the same length) one 64-bit word at a time
+func xorBytes(dst, src []byte) {
+	n := len(dst)
+	i := 0
+
+	// four words per iteration
+	for ; i+32 <= n; i += 32 {
+		d := dst[i : i+32 : i+32]
+		s := src[i : i+32 : i+32]
+		binary.LittleEndian.PutUint64(d[0:], binary.LittleEndian.Uint64(d[0:])^binary.LittleEndian.Uint64(s[0:]))
+		binary.LittleEndian.PutUint64(d[8:], binary.LittleEndian.Uint64(d[8:])^binary.LittleEndian.Uint64(s[8:]))
+		binary.LittleEndian.PutUint64(d[16:], binary.LittleEndian.Uint64(d[16:])^binary.LittleEndian.Uint64(s[16:]))
+		binary.LittleEndian.PutUint64(d[24:], binary.LittleEndian.Uint64(d[24:])^binary.LittleEndian.Uint64(s[24:]))
+	}
+
+	for ; i+8 <= n; i += 8 {
+		binary.LittleEndian.PutUint64(dst[i:], binary.LittleEndian.Uint64(dst[i:])^binary.LittleEndian.Uint64(src[i:]))
+	}
+
+	for ; i < n; i++ {
+		dst[i] ^= src[i]
+	}
+}
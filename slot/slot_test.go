@@ -0,0 +1,263 @@
+package slot
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// the byte-by-byte xor that Xor replaced (used as a reference)
+func xorBytewise(a, b *Slot) {
+	for j := 0; j < len(a.Data) && j < len(b.Data); j++ {
+		a.Data[j] ^= b.Data[j]
+	}
+}
+
+func TestXor(t *testing.T) {
+
+	for _, size := range []int{1, 7, 16, 33, 1000} {
+		a := NewRandom(size)
+		b := NewRandom(size + 3) // only the common prefix is xor'ed
+		expected := a.Copy()
+		xorBytewise(expected, b)
+
+		Xor(a, b)
+		if !a.Equal(expected) {
+			t.Fatalf("Wrong xor of %v-byte slots", size)
+		}
+	}
+
+	a := NewRandom(16)
+	b := a.Copy()
+	Xor(b, a)
+	if !b.IsZero() || a.IsZero() {
+		t.Fatalf("Slot xor'ed with itself is not zero")
+	}
+}
+
+func TestXorMasked(t *testing.T) {
+
+	for _, size := range []int{1, 7, 16, 33, 100} {
+		a := NewRandom(size)
+		b := NewRandom(size)
+
+		unchanged := a.Copy()
+		XorMasked(a, b, 0)
+		if !a.Equal(unchanged) {
+			t.Fatalf("Masked xor with bit 0 changed the slot")
+		}
+
+		expected := a.Copy()
+		Xor(expected, b)
+		XorMasked(a, b, 1)
+		if !a.Equal(expected) {
+			t.Fatalf("Wrong masked xor of %v-byte slots", size)
+		}
+	}
+
+	// 16-byte slots (keys) take a separate path with packed bits
+	for _, size := range []int{16, 33} {
+		v := NewVector(200, size)
+		v.Fill(rand.New(rand.NewSource(1)))
+
+		bits := make([]byte, v.Len())
+		for i := range bits {
+			bits[i] = byte(rand.Intn(2))
+		}
+
+		expected := NewEmpty(size)
+		for i, s := range v.Slots() {
+			if bits[i] == 1 {
+				xorBytewise(expected, s)
+			}
+		}
+
+		acc := NewEmpty(size)
+		v.XorMasked(acc, bits)
+		if !acc.Equal(expected) {
+			t.Fatalf("Wrong masked xor of the selected %v-byte slots", size)
+		}
+
+		acc = NewEmpty(size)
+		v.XorMaskedPacked(acc, PackBits(bits))
+		if !acc.Equal(expected) {
+			t.Fatalf("Wrong masked xor of the %v-byte slots selected by packed bits", size)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+
+	a := NewFromString("alice", 16)
+	b := NewFromString("bob", 16)
+
+	if a.Compare(b) != -1 || b.Compare(a) != 1 || a.Compare(a.Copy()) != 0 {
+		t.Fatalf("Wrong comparison of slots")
+	}
+
+	if !a.Equal(a.Copy()) || a.Equal(b) || a.Equal(nil) {
+		t.Fatalf("Wrong equality of slots")
+	}
+
+	if a.Equal(New(a.Data[:8])) {
+		t.Fatalf("Slots of different sizes are equal")
+	}
+}
+
+func TestFill(t *testing.T) {
+
+	a := NewEmpty(32)
+	b := NewEmpty(32)
+
+	// the same seed fills the same bytes
+	if err := a.Fill(rand.New(rand.NewSource(1))); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.Fill(rand.New(rand.NewSource(1))); err != nil {
+		t.Fatal(err)
+	}
+
+	if a.IsZero() || !a.Equal(b) {
+		t.Fatalf("Fill did not read the bytes of the reader")
+	}
+
+	if err := a.Fill(bytes.NewReader(make([]byte, 8))); err == nil {
+		t.Fatalf("Fill from a short reader did not fail")
+	}
+}
+
+func TestMarshal(t *testing.T) {
+
+	a := NewRandom(16)
+	data, err := a.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := &Slot{}
+	if err := b.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if !a.Equal(b) {
+		t.Fatalf("Slot changed by serialization")
+	}
+
+	// the unmarshaled slot does not share data
+	data[0] ^= 1
+	if !a.Equal(b) {
+		t.Fatalf("Unmarshaled slot shares data")
+	}
+}
+
+func TestVector(t *testing.T) {
+
+	v := NewVector(200, 33)
+	if err := v.Fill(rand.New(rand.NewSource(1))); err != nil {
+		t.Fatal(err)
+	}
+
+	if v.Len() != 200 || len(v.Slots()) != 200 {
+		t.Fatalf("Wrong number of slots in the vector")
+	}
+
+	// slots are views of the vector
+	v.Slot(3).Data[0] ^= 1
+	if v.Data[3*33] != v.Slots()[3].Data[0] {
+		t.Fatalf("Slot does not share data with the vector")
+	}
+
+	// vectors without a slot size
+	if (&Vector{}).Len() != 0 || (&Vector{SlotSize: -1, Data: v.Data}).Len() != 0 {
+		t.Fatalf("Vector without a slot size has slots")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("Created a vector of 0-byte slots")
+		}
+	}()
+	NewVector(200, 0)
+}
+
+// slot sizes of keys and of PIR records
+var BenchmarkSlotSizes = []int{16, 1024, 2048}
+
+func BenchmarkXor(b *testing.B) {
+	impls := map[string]func(a, b *Slot){"bytewise": xorBytewise, "wordwise": Xor}
+
+	for _, size := range BenchmarkSlotSizes {
+		for _, name := range []string{"bytewise", "wordwise"} {
+			b.Run(fmt.Sprintf("bytes=%v/%v", size, name), func(b *testing.B) {
+				xor := impls[name]
+				x := NewRandom(size)
+				y := NewRandom(size)
+
+				b.SetBytes(int64(size))
+				b.ReportAllocs()
+				b.ResetTimer()
+
+				for i := 0; i < b.N; i++ {
+					xor(x, y)
+				}
+			})
+		}
+	}
+}
+
+// compares selecting slots that are separately allocated (branching on
+// every DPF bit and xoring byte by byte) with the constant-time selection
+// from a vector, with one byte per bit or with packed bits (including the
+// packing, as in the audits)
+func BenchmarkXorSelected(b *testing.B) {
+
+	numSlots := uint64(1 << 14)
+
+	for _, size := range BenchmarkSlotSizes {
+		v := NewVector(numSlots, size)
+		v.Fill(rand.New(rand.NewSource(1)))
+
+		slots := make([]*Slot, numSlots)
+		for i := range slots {
+			slots[i] = v.Slot(uint64(i)).Copy()
+		}
+
+		bits := make([]byte, numSlots)
+		for i := range bits {
+			bits[i] = byte(rand.Intn(2))
+		}
+
+		b.Run(fmt.Sprintf("bytes=%v/slots", size), func(b *testing.B) {
+			acc := NewEmpty(size)
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				for j := range slots {
+					if bits[j] == 1 {
+						xorBytewise(acc, slots[j])
+					}
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("bytes=%v/vector", size), func(b *testing.B) {
+			acc := NewEmpty(size)
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				v.XorMasked(acc, bits)
+			}
+		})
+
+		b.Run(fmt.Sprintf("bytes=%v/packed", size), func(b *testing.B) {
+			acc := NewEmpty(size)
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				v.XorMaskedPacked(acc, PackBits(bits))
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package slot
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// Vector stores equally-sized slots contiguously, so that a list of
+// slots (e.g., keys or records) is a single allocation rather than one
+// per slot, and supports batch operations over the slots
+type Vector struct {
+	SlotSize int
+	Data     []byte
+}
+
+// NewVector returns a vector of numSlots all-zero slots
+// (it panics if slotSize is not positive)
+func NewVector(numSlots uint64, slotSize int) *Vector {
+	if slotSize <= 0 {
+		panic("slot size must be positive")
+	}
+
+	return &Vector{
+		SlotSize: slotSize,
+		Data:     make([]byte, numSlots*uint64(slotSize)),
+	}
+}
+
+// Len returns the number of slots in the vector
+// (0 if the slot size is not positive, e.g., for a zero Vector)
+func (v *Vector) Len() uint64 {
+	if v.SlotSize <= 0 {
+		return 0
+	}
+
+	return uint64(len(v.Data) / v.SlotSize)
+}
+
+// Slot returns the i-th slot; the slot shares its data with the vector
+func (v *Vector) Slot(i uint64) *Slot {
+	return &Slot{Data: v.bytes(i)}
+}
+
+// Slots returns every slot of the vector (sharing data with the vector)
+func (v *Vector) Slots() []*Slot {
+	slots := make([]*Slot, v.Len())
+	for i := range slots {
+		slots[i] = v.Slot(uint64(i))
+	}
+
+	return slots
+}
+
+// Fill overwrites every slot with bytes read from r
+func (v *Vector) Fill(r io.Reader) error {
+	_, err := io.ReadFull(r, v.Data)
+	return err
+}
+
+func (v *Vector) bytes(i uint64) []byte {
+	start := i * uint64(v.SlotSize)
+	end := start + uint64(v.SlotSize)
+	return v.Data[start:end:end]
+}
+
+// XorMasked xors into acc every slot whose bit (one byte per bit, as
+// returned by the DPF evaluation) is set; every slot is read and the time
+// taken only depends on the number of slots (and not on which bits are set)
+func (v *Vector) XorMasked(acc *Slot, bits []byte) {
+	n := v.SlotSize
+	if len(acc.Data) < n {
+		n = len(acc.Data)
+	}
+
+	for i := uint64(0); i < v.Len(); i++ {
+		xorBytesMasked(acc.Data[:n], v.bytes(i)[:n], -uint64(bits[i]&1))
+	}
+}
+
+// XorMaskedPacked is XorMasked for selection bits packed into 64-bit words
+// (see PackBits); like XorMasked, every slot is read whatever the bits
+func (v *Vector) XorMaskedPacked(acc *Slot, words []uint64) {
+	n := v.SlotSize
+	if len(acc.Data) < n {
+		n = len(acc.Data)
+	}
+
+	numSlots := v.Len()
+	if n == 16 && v.SlotSize == 16 {
+		v.xorMaskedPacked16(acc.Data[:16], words, numSlots)
+		return
+	}
+
+	// one selection word (64 slots) at a time
+	for w := uint64(0); w*64 < numSlots; w++ {
+		word := words[w]
+		for i := w * 64; i < numSlots && i < (w+1)*64; i++ {
+			xorBytesMasked(acc.Data[:n], v.bytes(i)[:n], -(word & 1))
+			word >>= 1
+		}
+	}
+}
+
+// XorMaskedPacked for 16-byte slots (keys), with acc held in two words
+func (v *Vector) xorMaskedPacked16(acc []byte, words []uint64, numSlots uint64) {
+	a0 := binary.LittleEndian.Uint64(acc[0:])
+	a1 := binary.LittleEndian.Uint64(acc[8:])
+
+	for w := uint64(0); w*64 < numSlots; w++ {
+		word := words[w]
+		for i := w * 64; i < numSlots && i < (w+1)*64; i++ {
+			mask := -(word & 1)
+			s := v.Data[16*i : 16*i+16 : 16*i+16]
+			a0 ^= binary.LittleEndian.Uint64(s[0:]) & mask
+			a1 ^= binary.LittleEndian.Uint64(s[8:]) & mask
+			word >>= 1
+		}
+	}
+
+	binary.LittleEndian.PutUint64(acc[0:], a0)
+	binary.LittleEndian.PutUint64(acc[8:], a1)
+}
+
+// PackBits packs DPF output bits (one byte per bit, as returned
+// by the DPF evaluation) into 64-bit words, least significant first
+func PackBits(b []byte) []uint64 {
+	packed := make([]uint64, (len(b)+63)/64)
+	for i, bit := range b {
+		packed[i/64] |= uint64(bit&1) << (i % 64)
+	}
+
+	return packed
+}
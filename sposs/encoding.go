@@ -0,0 +1,85 @@
+package sposs
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/sachaservan/pacl/algebra"
+)
+
+// Proof shares are encoded canonically as the server number and the sign
+// (one byte each) followed by the fixed-width encodings of the share of x
+// (in the exponent field) and of u, c, d, e, r and the nonce (in the field
+// of the group), so that the encoding of a proof share has a fixed length.
+
+// ProofShareLen returns the number of bytes of an encoded proof share
+func (pp *PublicParams) ProofShareLen() int {
+	return 2 + pp.ExpField.ByteLen() + 6*pp.Group.Field.ByteLen()
+}
+
+// EncodeProofShare returns the canonical encoding of the proof share
+func (pp *PublicParams) EncodeProofShare(share *ProofShare) ([]byte, error) {
+
+	if err := pp.ValidateProofShare(share); err != nil {
+		return nil, err
+	}
+
+	if share.ShareX == nil {
+		return nil, errors.New("threshold proof shares have no encoding")
+	}
+
+	b := make([]byte, 2, pp.ProofShareLen())
+	b[0] = byte(share.ServerNumber)
+	if share.Negate {
+		b[1] = 1
+	}
+
+	b, err := appendEncodings(b, pp.ExpField, share.ShareX)
+	if err != nil {
+		return nil, err
+	}
+
+	return appendEncodings(b, pp.Group.Field, share.proofValues()...)
+}
+
+// DecodeProofShare returns the proof share of the canonical encoding b
+func (pp *PublicParams) DecodeProofShare(b []byte) (*ProofShare, error) {
+
+	if len(b) != pp.ProofShareLen() {
+		return nil, fmt.Errorf("proof share has %v bytes instead of %v", len(b), pp.ProofShareLen())
+	}
+
+	if b[0] > 1 || b[1] > 1 {
+		return nil, errors.New("malformed proof share header")
+	}
+
+	share := &ProofShare{ServerNumber: int(b[0]), Negate: b[1] == 1}
+	share.ShareX = &algebra.FieldElement{Int: new(big.Int)}
+	share.ShareU = &algebra.FieldElement{Int: new(big.Int)}
+	share.ShareC = &algebra.FieldElement{Int: new(big.Int)}
+	share.D = &algebra.FieldElement{Int: new(big.Int)}
+	share.E = &algebra.FieldElement{Int: new(big.Int)}
+	share.R = &algebra.FieldElement{Int: new(big.Int)}
+	share.Nonce = &algebra.FieldElement{Int: new(big.Int)}
+
+	n := 2 + pp.ExpField.ByteLen()
+	if err := pp.ExpField.DecodeInto(share.ShareX, b[2:n]); err != nil {
+		return nil, fmt.Errorf("share of x: %v", err)
+	}
+
+	size := pp.Group.Field.ByteLen()
+	for _, value := range share.proofValues() {
+		if err := pp.Group.Field.DecodeInto(value, b[n:n+size]); err != nil {
+			return nil, err
+		}
+		n += size
+	}
+
+	return share, nil
+}
+
+// values of the proof share in the field of the group (in encoding order)
+func (share *ProofShare) proofValues() []*algebra.FieldElement {
+	return []*algebra.FieldElement{share.ShareU, share.ShareC, share.D, share.E, share.R, share.Nonce}
+}
@@ -1,8 +1,11 @@
 package sposs
 
 import (
-	"bytes"
+	"crypto/rand"
 	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
 	"math/big"
 
 	"github.com/sachaservan/pacl/algebra"
@@ -16,13 +19,15 @@ type PublicParams struct {
 
 type ProofShare struct {
 	ServerNumber int
-	ShareX       *algebra.FieldElement // additive share of x such that g^x =
+	ShareX       *algebra.FieldElement // additive share of x such that g^x = (nil in threshold proofs)
 	ShareU       *algebra.FieldElement // a or b
 	ShareC       *algebra.FieldElement // [c]
 	D            *algebra.FieldElement // beaver mult opening
 	E            *algebra.FieldElement // beaver mult opening
 	R            *algebra.FieldElement // randomness
 	Nonce        *algebra.FieldElement // nonce used in random oracle
+	Negate       bool                  // multiplicative share of the sign of g^x
+	GX           *algebra.FieldElement // g^[x] in threshold proofs, set by the verifier (see GenThresholdProof)
 }
 
 type AuditShare struct {
@@ -31,16 +36,80 @@ type AuditShare struct {
 	HashedData [32]byte
 }
 
+// Size returns the number of bytes of the proof share
+func (share *ProofShare) Size() int {
+	if share == nil {
+		return 0
+	}
+
+	return share.ShareX.Size() + share.ShareU.Size() + share.ShareC.Size() +
+		share.D.Size() + share.E.Size() + share.R.Size() + share.Nonce.Size() + 1 + share.GX.Size()
+}
+
+// Size returns the number of bytes of the audit share
+func (share *AuditShare) Size() int {
+	if share == nil {
+		return 0
+	}
+
+	return len(share.HashedData)
+}
+
+// NewPublicParams returns the parameters of proofs over the group; exponents
+// are reduced mod the order of the group (e.g., a 256-bit q for the subgroup
+// of a Schnorr group rather than P-1)
 func NewPublicParams(g *algebra.Group) *PublicParams {
-	f := algebra.NewField(g.Field.Pminus1())
-	return &PublicParams{g, f, nil}
+	return &PublicParams{g, expField(g), nil}
 }
 
+// field of the exponents of g, with the backend of the field of g when
+// the order is odd (e.g., in Schnorr groups) and math/big otherwise: in a
+// safe-prime group (of order P-1), operations on the secret exponent and
+// its shares are then not constant-time
+func expField(g *algebra.Group) *algebra.Field {
+	f, err := algebra.NewFieldWithBackend(g.Order, g.Field.Backend())
+	if err != nil {
+		return algebra.NewField(g.Order)
+	}
+
+	return f
+}
+
+// GenProof returns the proof shares of g^x = y for y shared between the verifiers
 func (pp *PublicParams) GenProof(x *algebra.FieldElement) (*ProofShare, *ProofShare) {
+	return pp.GenSignedProof(x, false)
+}
+
+// GenSignedProof returns the proof shares of g^x = y, or of -g^x = y if negate
+// is set. The sign is shared multiplicatively: each verifier gets a random
+// sign with which it multiplies its factor g^[x], so that neither verifier
+// learns the sign (which -g^x = g^(x+q) cannot hide in groups of prime order).
+func (pp *PublicParams) GenSignedProof(x *algebra.FieldElement, negate bool) (*ProofShare, *ProofShare) {
 
 	// generate (additive) secret shares of x
 	xA, xB := pp.ExpLinearShares(x)
 
+	return pp.genSignedProof(xA, xB, negate, false)
+}
+
+// GenThresholdProof is the same as GenSignedProof for the additive shares
+// xA and xB of x, but the proof shares do not include them: the verifiers
+// compute g^xA and g^xB themselves (e.g., in the exponent from Shamir shares
+// of xA and xB held by more verifiers) and set GX before the audit. The
+// randomness of the proof is then derived from g^[x] rather than [x].
+func (pp *PublicParams) GenThresholdProof(xA, xB *algebra.FieldElement, negate bool) (*ProofShare, *ProofShare) {
+	return pp.genSignedProof(xA, xB, negate, true)
+}
+
+func (pp *PublicParams) genSignedProof(xA, xB *algebra.FieldElement, negate, threshold bool) (*ProofShare, *ProofShare) {
+
+	// the shares are sent (and hashed) as elements of the exponent field
+	xA, xB = pp.ExpField.NewElement(xA.Int), pp.ExpField.NewElement(xB.Int)
+
+	// multiplicative shares of the sign
+	negA := randomBit()
+	negB := negA != negate
+
 	// a and b of the beaver triple
 	a, b := pp.Group.Field.RandomElement(), pp.Group.Field.RandomElement()
 
@@ -52,32 +121,124 @@ func (pp *PublicParams) GenProof(x *algebra.FieldElement) (*ProofShare, *ProofSh
 	nonceA := pp.Group.Field.RandomElement()
 	nonceB := pp.Group.Field.RandomElement()
 
+	shareA := &ProofShare{ServerNumber: 0, ShareX: xA, ShareU: a, ShareC: cA, Nonce: nonceA, Negate: negA}
+	shareB := &ProofShare{ServerNumber: 1, ShareX: xB, ShareU: b, ShareC: cB, Nonce: nonceB, Negate: negB}
+	if threshold {
+		shareA.ShareX, shareA.GX = nil, pp.Group.NewElement(xA.Int).Value
+		shareB.ShareX, shareB.GX = nil, pp.Group.NewElement(xB.Int).Value
+	}
+
 	// compute randomness by applying Fiat-Shamir
-	rA := pp.RandomOracle(nonceA, xA, a, cA)
-	rB := pp.RandomOracle(nonceB, xB, b, cB)
+	// (every value is reduced so the encodings cannot fail)
+	rA, errA := pp.shareOracle(shareA)
+	rB, errB := pp.shareOracle(shareB)
+	if errA != nil || errB != nil {
+		panic("proof values are not reduced")
+	}
 	r := pp.Group.Field.Add(rA, rB)
 
-	// compute g^[x]
-	gxA := pp.Group.NewElement(xA.Int)
-	gxB := pp.Group.NewElement(xB.Int)
+	// compute ±g^[x]
+	gxA := pp.signedGX(shareA)
+	gxB := pp.signedGX(shareB)
 
 	// d = rg^xA - a
-	d := pp.Group.Field.Mul(r, gxA.Value)
+	d := pp.Group.Field.Mul(r, gxA)
 	d = pp.Group.Field.Sub(d, a)
 
 	// e = g^xB - b
-	e := pp.Group.Field.Sub(gxB.Value, b)
+	e := pp.Group.Field.Sub(gxB, b)
+
+	for _, share := range []*ProofShare{shareA, shareB} {
+		share.D, share.E, share.R = d, e, r
+		share.GX = nil // computed by the verifiers in threshold proofs
+	}
+
+	return shareA, shareB
+}
+
+// returns g^x, negated if negate is set
+func (pp *PublicParams) signedElement(x *algebra.FieldElement, negate bool) *algebra.FieldElement {
+	gx := pp.Group.NewElement(x.Int).Value
+	if negate {
+		return pp.Group.Field.Negate(gx)
+	}
+
+	return gx
+}
+
+// returns ±g^[x] of the proof share (from GX in threshold proofs)
+func (pp *PublicParams) signedGX(share *ProofShare) *algebra.FieldElement {
+	if share.ShareX != nil {
+		return pp.signedElement(share.ShareX, share.Negate)
+	}
+
+	if share.Negate {
+		return pp.Group.Field.Negate(share.GX)
+	}
+
+	return share.GX
+}
 
-	return &ProofShare{0, xA, a, cA, d, e, r, nonceA}, &ProofShare{1, xB, b, cB, d, e, r, nonceB}
+func randomBit() bool {
+	var b [1]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+
+	return b[0]&1 == 1
 }
 
+// ValidateProofShare returns an error unless every value of the proof share
+// is an element of its field (the share of x is in the exponent field)
+func (pp *PublicParams) ValidateProofShare(share *ProofShare) error {
+
+	if share == nil {
+		return errors.New("missing proof share")
+	}
+
+	if share.ServerNumber != 0 && share.ServerNumber != 1 {
+		return fmt.Errorf("invalid server number %v", share.ServerNumber)
+	}
+
+	if (share.ShareX == nil) == (share.GX == nil) {
+		return errors.New("proof share must have exactly one of a share of x and g^[x]")
+	}
+
+	if share.ShareX != nil {
+		if err := pp.ExpField.Validate(share.ShareX); err != nil {
+			return fmt.Errorf("share of x: %v", err)
+		}
+	} else if err := pp.Group.Validate(&algebra.GroupElement{Value: share.GX}); err != nil {
+		return fmt.Errorf("g^[x]: %v", err)
+	}
+
+	values := []*algebra.FieldElement{share.ShareU, share.ShareC, share.D, share.E, share.R, share.Nonce}
+	names := []string{"share of u", "share of c", "d", "e", "r", "nonce"}
+	for i, value := range values {
+		if err := pp.Group.Field.Validate(value); err != nil {
+			return fmt.Errorf("%v: %v", names[i], err)
+		}
+	}
+
+	return nil
+}
+
+// Audit returns the audit share of the proof share for the share of y,
+// or nil if the proof share is malformed (which CheckAudit rejects)
 func (pp *PublicParams) Audit(yShare *algebra.FieldElement, proofShare *ProofShare) *AuditShare {
 
+	if pp.ValidateProofShare(proofShare) != nil || pp.Group.Field.Validate(yShare) != nil {
+		return nil
+	}
+
 	// recompute the randomness
-	r := pp.RandomOracle(proofShare.Nonce, proofShare.ShareX, proofShare.ShareU, proofShare.ShareC)
+	r, err := pp.shareOracle(proofShare)
+	if err != nil {
+		return nil
+	}
 
-	// recompute g^[x]
-	gx := pp.Group.NewElement(proofShare.ShareX.Int)
+	// recompute ±g^[x]
+	gx := &algebra.GroupElement{Value: pp.signedGX(proofShare)}
 
 	// 2^-1 mod p
 	twoInv := pp.Group.Field.MulInv(pp.Group.Field.NewElement(big.NewInt(2)))
@@ -120,26 +281,76 @@ func (pp *PublicParams) Audit(yShare *algebra.FieldElement, proofShare *ProofSha
 		r = pp.Group.Field.Negate(r)
 	}
 
-	data := []byte{}
-	data = append(data, shareW.Int.Bytes()...)
-	data = append(data, r.Int.Bytes()...)
-	data = append(data, u.Int.Bytes()...)
+	// hash the canonical (fixed-width) encodings
+	data, err := appendEncodings(nil, pp.Group.Field, shareW, r, u)
+	if err != nil {
+		return nil
+	}
+
 	return &AuditShare{sha256.Sum256(data)}
 }
 
+// CheckAudit returns true if the audit shares of both verifiers match
+// (audit shares of malformed proof shares are nil and never match)
 func (pp *PublicParams) CheckAudit(auditShareA, auditShareB *AuditShare) bool {
-	return bytes.Equal(auditShareA.HashedData[:], auditShareB.HashedData[:])
+	if auditShareA == nil || auditShareB == nil {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare(auditShareA.HashedData[:], auditShareB.HashedData[:]) == 1
+}
+
+// RandomOracle returns the Fiat-Shamir randomness of the values of a
+// proof share (or an error if they are not reduced)
+func (pp *PublicParams) RandomOracle(nonceShare, xShare, uShare, cShare *algebra.FieldElement, negate bool) (*algebra.FieldElement, error) {
+	// the share of x lives in the exponent field
+	return pp.randomOracle(nonceShare, pp.ExpField, xShare, uShare, cShare, negate)
 }
 
-func (pp *PublicParams) RandomOracle(nonceShare, xShare, uShare, cShare *algebra.FieldElement) *algebra.FieldElement {
+// returns the Fiat-Shamir randomness of the proof share, derived
+// from g^[x] (in the field of the group) in threshold proofs
+func (pp *PublicParams) shareOracle(share *ProofShare) (*algebra.FieldElement, error) {
+	if share.ShareX != nil {
+		return pp.RandomOracle(share.Nonce, share.ShareX, share.ShareU, share.ShareC, share.Negate)
+	}
+
+	return pp.randomOracle(share.Nonce, pp.Group.Field, share.GX, share.ShareU, share.ShareC, share.Negate)
+}
 
-	data := []byte{}
-	data = append(data, nonceShare.Int.Bytes()...)
-	data = append(data, xShare.Int.Bytes()...)
-	data = append(data, uShare.Int.Bytes()...)
-	data = append(data, cShare.Int.Bytes()...)
+// RandomOracle with the value standing for the share of x in xField
+func (pp *PublicParams) randomOracle(nonceShare *algebra.FieldElement, xField *algebra.Field, xShare, uShare, cShare *algebra.FieldElement, negate bool) (*algebra.FieldElement, error) {
+
+	// hash the canonical (fixed-width) encodings
+	data, err := appendEncodings(nil, pp.Group.Field, nonceShare)
+	if err == nil {
+		data, err = appendEncodings(data, xField, xShare)
+	}
+	if err == nil {
+		data, err = appendEncodings(data, pp.Group.Field, uShare, cShare)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if negate {
+		data = append(data, 1)
+	} else {
+		data = append(data, 0)
+	}
 	bytes := sha256.Sum256(data)
-	return pp.Group.Field.NewElement(new(big.Int).SetBytes(bytes[:]))
+	return pp.Group.Field.NewElement(new(big.Int).SetBytes(bytes[:])), nil
+}
+
+// appends the fixed-width encodings of the elements of f to b
+func appendEncodings(b []byte, f *algebra.Field, elems ...*algebra.FieldElement) ([]byte, error) {
+	var err error
+	for _, e := range elems {
+		if b, err = f.AppendEncoding(b, e); err != nil {
+			return nil, err
+		}
+	}
+
+	return b, nil
 }
 
 // Return a pair of linear shares for toShare, s.t. share1 + share2 = toShare
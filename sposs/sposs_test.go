@@ -1,6 +1,8 @@
 package sposs
 
 import (
+	"bytes"
+	crand "crypto/rand"
 	"errors"
 	"fmt"
 	"math/big"
@@ -61,12 +63,162 @@ func TestFullSPoSS(t *testing.T) {
 	}
 }
 
+func TestSignedSPoSS(t *testing.T) {
+
+	// a Schnorr group, where -g^x is not in the group and
+	// exponents (and shares of x) are only 256 bits long
+	group, err := algebra.GenerateSchnorrGroup(crand.Reader, 1024, 256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pp := NewPublicParams(group)
+
+	for i := 0; i < 20; i++ {
+		x := pp.ExpField.RandomElement()
+		negate := i%2 == 1
+
+		// y = g^x or y = -g^x
+		y := pp.Group.NewElement(x.Int).Value
+		if negate {
+			y = pp.Group.Field.Negate(y)
+		}
+		shareA, shareB := pp.LinearShares(y)
+
+		proofA, proofB := pp.GenSignedProof(x, negate)
+		if proofA.ShareX.Int.BitLen() > 256 || proofB.ShareX.Int.BitLen() > 256 {
+			t.Fatalf("Shares of x are not reduced mod q")
+		}
+
+		if !pp.CheckAudit(pp.Audit(shareA, proofA), pp.Audit(shareB, proofB)) {
+			t.Fatalf("Signed SPoSS audit failed (negate = %v)", negate)
+		}
+
+		// the proof does not hold for the other sign
+		proofA, proofB = pp.GenSignedProof(x, !negate)
+		if pp.CheckAudit(pp.Audit(shareA, proofA), pp.Audit(shareB, proofB)) {
+			t.Fatalf("Signed SPoSS audit passed for the wrong sign (negate = %v)", negate)
+		}
+	}
+}
+
+func TestThresholdSPoSS(t *testing.T) {
+
+	group, err := algebra.GenerateSchnorrGroup(crand.Reader, 1024, 256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pp := NewPublicParams(group)
+
+	for i := 0; i < 10; i++ {
+		x := pp.ExpField.RandomElement()
+		xA, xB := pp.ExpLinearShares(x)
+		negate := i%2 == 1
+
+		y := pp.Group.NewElement(x.Int).Value
+		if negate {
+			y = pp.Group.Field.Negate(y)
+		}
+		shareA, shareB := pp.LinearShares(y)
+
+		proofA, proofB := pp.GenThresholdProof(xA, xB, negate)
+		if proofA.ShareX != nil || proofB.ShareX != nil {
+			t.Fatalf("Threshold proof shares include shares of x")
+		}
+
+		// without g^[x] the proof shares are not audited
+		if pp.Audit(shareA, proofA) != nil {
+			t.Fatalf("Audited a threshold proof share without g^[x]")
+		}
+
+		// the verifiers compute g^[x]
+		proofA.GX = pp.Group.NewElement(xA.Int).Value
+		proofB.GX = pp.Group.NewElement(xB.Int).Value
+		if !pp.CheckAudit(pp.Audit(shareA, proofA), pp.Audit(shareB, proofB)) {
+			t.Fatalf("Threshold SPoSS audit failed (negate = %v)", negate)
+		}
+
+		if _, err := pp.EncodeProofShare(proofA); err == nil {
+			t.Fatalf("Encoded a threshold proof share")
+		}
+
+		// g^[x] of another x
+		proofB.GX = pp.Group.NewElement(pp.ExpField.RandomElement().Int).Value
+		if pp.CheckAudit(pp.Audit(shareA, proofA), pp.Audit(shareB, proofB)) {
+			t.Fatalf("Threshold SPoSS audit passed for the wrong g^[x]")
+		}
+
+		// a share of x and g^[x]
+		proofA.ShareX = xA
+		if pp.ValidateProofShare(proofA) == nil {
+			t.Fatalf("Validated a proof share with a share of x and g^[x]")
+		}
+	}
+}
+
+func TestEncodeProofShare(t *testing.T) {
+
+	group, err := algebra.GenerateSchnorrGroup(crand.Reader, 512, 160)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pp := NewPublicParams(group)
+
+	x := pp.ExpField.RandomElement()
+	y := pp.Group.NewElement(x.Int).Value
+	shareA, shareB := pp.LinearShares(y)
+	proofA, proofB := pp.GenSignedProof(x, false)
+
+	var decoded [2]*ProofShare
+	for i, proof := range []*ProofShare{proofA, proofB} {
+		b, err := pp.EncodeProofShare(proof)
+		if err != nil || len(b) != pp.ProofShareLen() {
+			t.Fatalf("Encoding of proof share has %v bytes: %v", len(b), err)
+		}
+
+		if decoded[i], err = pp.DecodeProofShare(b); err != nil {
+			t.Fatal(err)
+		}
+
+		// the header and the values (e.g., a nonce of 2^512 - 1) are checked
+		for _, n := range []int{0, 1, len(b) - group.ByteLen()} {
+			bad := append([]byte{}, b...)
+			bad[n] = 0xff
+			if n > 1 {
+				copy(bad[n:], bytes.Repeat([]byte{0xff}, group.ByteLen()))
+			}
+
+			if _, err := pp.DecodeProofShare(bad); err == nil {
+				t.Fatalf("Decoded a malformed proof share (byte %v)", n)
+			}
+		}
+
+		if _, err := pp.DecodeProofShare(b[1:]); err == nil {
+			t.Fatalf("Decoded a truncated proof share")
+		}
+	}
+
+	if !pp.CheckAudit(pp.Audit(shareA, decoded[0]), pp.Audit(shareB, decoded[1])) {
+		t.Fatalf("Audit of decoded proof shares failed")
+	}
+
+	// values that are not reduced are neither encoded nor audited
+	proofA.R = &algebra.FieldElement{Int: new(big.Int).Add(proofA.R.Int, group.Field.P)}
+	if _, err := pp.EncodeProofShare(proofA); err == nil {
+		t.Fatalf("Encoded a proof share with a value that is not reduced")
+	}
+
+	if pp.Audit(shareA, proofA) != nil || pp.CheckAudit(nil, nil) {
+		t.Fatalf("Audited a proof share with a value that is not reduced")
+	}
+}
+
 func BenchmarkProve(b *testing.B) {
 	group := TestingGroup()
 	pp := NewPublicParams(group)
 
 	x := pp.Group.Field.RandomElement()
 
+	b.ReportAllocs()
 	b.ResetTimer()
 
 	for i := 0; i < b.N; i++ {
@@ -83,6 +235,7 @@ func BenchmarkAudit(b *testing.B) {
 	proofA, _ := pp.GenProof(x)
 	additiveShareA, _ := pp.LinearShares(gX)
 
+	b.ReportAllocs()
 	b.ResetTimer()
 
 	for i := 0; i < b.N; i++ {
@@ -101,6 +254,7 @@ func BenchmarkVerify(b *testing.B) {
 	auditShareA := pp.Audit(additiveShareA, proofA)
 	auditShareB := pp.Audit(additiveShareB, proofB)
 
+	b.ReportAllocs()
 	b.ResetTimer()
 
 	for i := 0; i < b.N; i++ {
@@ -112,6 +266,7 @@ func BenchmarkExp(b *testing.B) {
 	group := TestingGroup()
 
 	_, x := group.RandomElement()
+	b.ReportAllocs()
 	b.ResetTimer()
 
 	for i := 0; i < b.N; i++ {
@@ -0,0 +1,157 @@
+package transport
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// number of messages a pipe buffers in each direction before Send blocks
+// (so that both ends can send before receiving, as verifiers exchanging
+// audit shares do)
+const pipeBuffer = 16
+
+// Pipe returns the two ends of an in-memory connection
+func Pipe() (PeerConn, PeerConn) {
+
+	ab := make(chan []byte, pipeBuffer)
+	ba := make(chan []byte, pipeBuffer)
+	aClosed := make(chan struct{})
+	bClosed := make(chan struct{})
+
+	a := &pipeConn{in: ba, out: ab, closed: aClosed, peerClosed: bClosed}
+	b := &pipeConn{in: ab, out: ba, closed: bClosed, peerClosed: aClosed}
+
+	return a, b
+}
+
+type pipeConn struct {
+	in, out    chan []byte
+	closed     chan struct{} // closed by Close
+	peerClosed chan struct{} // closed by the peer's Close
+	closeOnce  sync.Once
+
+	mu              sync.Mutex
+	sendDeadline    time.Time
+	receiveDeadline time.Time
+}
+
+func (c *pipeConn) Send(msg []byte) error {
+
+	if len(msg) > MaxMessageSize {
+		return fmt.Errorf("message of %v bytes exceeds the maximum of %v", len(msg), MaxMessageSize)
+	}
+
+	c.mu.Lock()
+	deadline := c.sendDeadline
+	c.mu.Unlock()
+
+	select {
+	case <-c.closed:
+		return net.ErrClosed
+	case <-c.peerClosed:
+		return io.ErrClosedPipe
+	default:
+	}
+
+	if passed(deadline) {
+		return os.ErrDeadlineExceeded
+	}
+
+	expired, stop := timeout(deadline)
+	defer stop()
+
+	select {
+	case c.out <- append([]byte{}, msg...):
+		return nil
+	case <-c.closed:
+		return net.ErrClosed
+	case <-c.peerClosed:
+		return io.ErrClosedPipe
+	case <-expired:
+		return os.ErrDeadlineExceeded
+	}
+}
+
+func (c *pipeConn) Receive() ([]byte, error) {
+
+	c.mu.Lock()
+	deadline := c.receiveDeadline
+	c.mu.Unlock()
+
+	select {
+	case <-c.closed:
+		return nil, net.ErrClosed
+	default:
+	}
+
+	if passed(deadline) {
+		return nil, os.ErrDeadlineExceeded
+	}
+
+	expired, stop := timeout(deadline)
+	defer stop()
+
+	select {
+	case msg := <-c.in:
+		return msg, nil
+	case <-c.closed:
+		return nil, net.ErrClosed
+	case <-c.peerClosed:
+		// messages sent before the peer closed are still delivered
+		select {
+		case msg := <-c.in:
+			return msg, nil
+		default:
+			return nil, io.EOF
+		}
+	case <-expired:
+		return nil, os.ErrDeadlineExceeded
+	}
+}
+
+func (c *pipeConn) SetDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sendDeadline, c.receiveDeadline = t, t
+	return nil
+}
+
+func (c *pipeConn) SetSendDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sendDeadline = t
+	return nil
+}
+
+func (c *pipeConn) SetReceiveDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.receiveDeadline = t
+	return nil
+}
+
+func (c *pipeConn) Close() error {
+	c.closeOnce.Do(func() { close(c.closed) })
+	return nil
+}
+
+// returns a channel that fires at the deadline (never for the zero time)
+// and a function releasing its timer. A deadline set while a call is
+// blocked only applies to the following calls.
+func timeout(deadline time.Time) (<-chan time.Time, func()) {
+	if deadline.IsZero() {
+		return nil, func() {}
+	}
+
+	timer := time.NewTimer(time.Until(deadline))
+	return timer.C, func() { timer.Stop() }
+}
+
+// returns true if the deadline is set and has passed
+func passed(deadline time.Time) bool {
+	return !deadline.IsZero() && !time.Now().Before(deadline)
+}
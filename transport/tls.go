@@ -0,0 +1,196 @@
+package transport
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"time"
+)
+
+// Verifiers authenticate each other with certificates issued by a CA that
+// the operators of the verifiers generate locally (see NewCA); a verifier
+// trusts no other CA and only accepts the peer certificate of the name it
+// expects, whether it dials or accepts the connection.
+
+// how long certificates issued by NewCA and Issue are valid
+const certLifetime = 365 * 24 * time.Hour
+
+// how long Dial and Accept wait for the TLS handshake to complete
+const handshakeTimeout = 10 * time.Second
+
+// CA is a locally generated certificate authority
+type CA struct {
+	Certificate *x509.Certificate
+	key         *ecdsa.PrivateKey
+}
+
+// NewCA returns a new self-signed CA with a P256 key
+func NewCA(name string) (*CA, error) {
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	template, err := newTemplate(name)
+	if err != nil {
+		return nil, err
+	}
+	template.IsCA = true
+	template.BasicConstraintsValid = true
+	template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CA{Certificate: cert, key: key}, nil
+}
+
+// Issue returns a certificate (and its key) of the verifier with the
+// given name, valid both to accept and to dial connections
+func (ca *CA) Issue(name string) (tls.Certificate, error) {
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	template, err := newTemplate(name)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	template.DNSNames = []string{name}
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+	template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.Certificate, &key.PublicKey, ca.key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	return tls.Certificate{
+		Certificate: [][]byte{der, ca.Certificate.Raw},
+		PrivateKey:  key,
+		Leaf:        leaf,
+	}, nil
+}
+
+func newTemplate(name string) (*x509.Certificate, error) {
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	return &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    now.Add(-time.Minute),
+		NotAfter:     now.Add(certLifetime),
+	}, nil
+}
+
+// Config returns the TLS configuration of a verifier holding cert that
+// connects to (or accepts connections from) the peer of the given name;
+// both ends must present a certificate issued by ca
+func Config(ca *x509.Certificate, cert tls.Certificate, peer string) *tls.Config {
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      pool,
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ServerName:   peer,
+		MinVersion:   tls.VersionTLS13,
+
+		// the chain is verified by then; the client also checks the server's
+		// name (ServerName) but the server must check the client's itself
+		VerifyConnection: func(state tls.ConnectionState) error {
+			if len(state.PeerCertificates) == 0 {
+				return errors.New("the peer did not present a certificate")
+			}
+			return state.PeerCertificates[0].VerifyHostname(peer)
+		},
+	}
+}
+
+// Dial connects to the verifier listening at addr
+func Dial(addr string, config *tls.Config) (PeerConn, error) {
+
+	dialer := &net.Dialer{Timeout: handshakeTimeout}
+	conn, err := tls.DialWithDialer(dialer, "tcp", addr, config)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewConn(conn), nil
+}
+
+// Listener accepts connections from the other verifier
+type Listener struct {
+	listener net.Listener
+	config   *tls.Config
+}
+
+// Listen listens for connections at addr (e.g., ":7000")
+func Listen(addr string, config *tls.Config) (*Listener, error) {
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Listener{listener: listener, config: config}, nil
+}
+
+// Accept waits for the next connection and returns it once the peer is
+// authenticated
+func (l *Listener) Accept() (PeerConn, error) {
+
+	conn, err := l.listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConn := tls.Server(conn, l.config)
+	tlsConn.SetDeadline(time.Now().Add(handshakeTimeout))
+	if err := tlsConn.Handshake(); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("handshake with %v failed: %v", conn.RemoteAddr(), err)
+	}
+	tlsConn.SetDeadline(time.Time{})
+
+	return NewConn(tlsConn), nil
+}
+
+// Addr returns the address the listener listens at
+func (l *Listener) Addr() net.Addr {
+	return l.listener.Addr()
+}
+
+func (l *Listener) Close() error {
+	return l.listener.Close()
+}
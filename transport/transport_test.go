@@ -0,0 +1,240 @@
+package transport
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestPipe(t *testing.T) {
+
+	a, b := Pipe()
+	defer a.Close()
+	defer b.Close()
+
+	testExchange(t, a, b)
+}
+
+func TestPipeDeadline(t *testing.T) {
+
+	a, b := Pipe()
+	defer a.Close()
+	defer b.Close()
+
+	testDeadline(t, a)
+}
+
+func TestPipeClose(t *testing.T) {
+
+	a, b := Pipe()
+
+	if err := a.Send([]byte("last")); err != nil {
+		t.Fatal(err)
+	}
+	a.Close()
+
+	// messages sent before closing are still delivered
+	msg, err := b.Receive()
+	if err != nil || string(msg) != "last" {
+		t.Fatalf("Expected the last message, got %q (%v)", msg, err)
+	}
+
+	if _, err := b.Receive(); err != io.EOF {
+		t.Fatalf("Expected EOF after the peer closed, got %v", err)
+	}
+
+	if err := b.Send([]byte("late")); err == nil {
+		t.Fatalf("Sent a message to a closed peer")
+	}
+}
+
+func TestMessageTooLarge(t *testing.T) {
+
+	a, b := Pipe()
+	defer a.Close()
+	defer b.Close()
+
+	if err := a.Send(make([]byte, MaxMessageSize+1)); err == nil {
+		t.Fatalf("Sent a message larger than the maximum")
+	}
+}
+
+func TestTLS(t *testing.T) {
+
+	ca, err := NewCA("pacl test CA")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, b := tlsPair(t, ca, ca, "verifier-1")
+	defer a.Close()
+	defer b.Close()
+
+	testExchange(t, a, b)
+	testDeadline(t, a)
+}
+
+func TestTLSRejectsUnknownPeers(t *testing.T) {
+
+	ca, err := NewCA("pacl test CA")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other, err := NewCA("other CA")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a certificate from another CA
+	if !tlsFails(t, ca, other, "verifier-1") {
+		t.Fatalf("Accepted a peer certificate issued by another CA")
+	}
+
+	// a certificate of the CA for another name
+	if !tlsFails(t, ca, ca, "verifier-2") {
+		t.Fatalf("Accepted a peer certificate issued for another name")
+	}
+}
+
+// returns the ends of a TLS connection between verifier-0 (listening, with
+// a certificate of ca) and a client named dialerName with a certificate of
+// dialerCA
+func tlsPair(t *testing.T, ca, dialerCA *CA, dialerName string) (PeerConn, PeerConn) {
+	t.Helper()
+
+	listener, accepted, err := tlsListen(t, ca)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	cert, err := dialerCA.Issue(dialerName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := Dial(listener.Addr().String(), Config(dialerCA.Certificate, cert, "verifier-0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := <-accepted
+	if res.err != nil {
+		t.Fatal(res.err)
+	}
+
+	return a, res.conn
+}
+
+// returns true if the connection is refused by either end
+func tlsFails(t *testing.T, ca, dialerCA *CA, dialerName string) bool {
+	t.Helper()
+
+	listener, accepted, err := tlsListen(t, ca)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	cert, err := dialerCA.Issue(dialerName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := Dial(listener.Addr().String(), Config(dialerCA.Certificate, cert, "verifier-0"))
+	if err != nil {
+		return true
+	}
+	defer a.Close()
+
+	res := <-accepted
+	if res.err != nil {
+		// with TLS 1.3 the client only learns about it on its first read
+		a.SetReceiveDeadline(time.Now().Add(time.Second))
+		if _, err := a.Receive(); err == nil {
+			t.Fatalf("Received a message on a refused connection")
+		}
+		return true
+	}
+
+	res.conn.Close()
+	return false
+}
+
+type acceptResult struct {
+	conn PeerConn
+	err  error
+}
+
+// listens as verifier-0 (expecting verifier-1) and accepts one connection
+func tlsListen(t *testing.T, ca *CA) (*Listener, chan acceptResult, error) {
+	t.Helper()
+
+	cert, err := ca.Issue("verifier-0")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	listener, err := Listen("127.0.0.1:0", Config(ca.Certificate, cert, "verifier-1"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	accepted := make(chan acceptResult, 1)
+	go func() {
+		conn, err := listener.Accept()
+		accepted <- acceptResult{conn, err}
+	}()
+
+	return listener, accepted, nil
+}
+
+// both ends send before receiving, as verifiers exchanging audit shares do
+func testExchange(t *testing.T, a, b PeerConn) {
+	t.Helper()
+
+	msgs := [][]byte{[]byte("audit share"), {}, bytes.Repeat([]byte{0xab}, 100000)}
+	for _, msg := range msgs {
+		if err := a.Send(msg); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := b.Send(append([]byte("re: "), msg...)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, msg := range msgs {
+		got, err := b.Receive()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(got, msg) {
+			t.Fatalf("Received a message of %v bytes instead of %v", len(got), len(msg))
+		}
+
+		got, err = a.Receive()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(got, append([]byte("re: "), msg...)) {
+			t.Fatalf("Received a reply of %v bytes instead of %v", len(got), len(msg)+4)
+		}
+	}
+}
+
+// nothing is sent to conn: receiving must time out
+func testDeadline(t *testing.T, conn PeerConn) {
+	t.Helper()
+
+	conn.SetReceiveDeadline(time.Now().Add(50 * time.Millisecond))
+	if _, err := conn.Receive(); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("Expected the deadline to expire, got %v", err)
+	}
+}